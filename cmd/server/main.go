@@ -1,112 +1,367 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/dvoryadkinadv/expense-tracker/internal/config"
 	"github.com/dvoryadkinadv/expense-tracker/internal/database"
 	"github.com/dvoryadkinadv/expense-tracker/internal/handlers"
+	"github.com/dvoryadkinadv/expense-tracker/internal/logging"
+	"github.com/dvoryadkinadv/expense-tracker/internal/metrics"
+	"github.com/dvoryadkinadv/expense-tracker/internal/migrate"
+	"github.com/dvoryadkinadv/expense-tracker/internal/ratelimit"
 	"github.com/dvoryadkinadv/expense-tracker/internal/service"
+	"github.com/dvoryadkinadv/expense-tracker/internal/tracing"
+	"github.com/dvoryadkinadv/expense-tracker/internal/worker"
+	"github.com/dvoryadkinadv/expense-tracker/migrations"
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	// Читаем настройки из переменных окружения
-	// В продакшене лучше использовать что-то типа viper,
-	// но для простоты пока так
-	dbConfig := database.Config{
-		Host:     getEnv("DB_HOST", "localhost"),
-		Port:     getEnv("DB_PORT", "5432"),
-		User:     getEnv("DB_USER", "postgres"),
-		Password: getEnv("DB_PASSWORD", "postgres"),
-		DBName:   getEnv("DB_NAME", "expense_tracker"),
-		SSLMode:  getEnv("DB_SSLMODE", "disable"),
-	}
-
-	// Подключаемся к базе
-	db, err := database.NewConnection(dbConfig)
+	// Вся работа в run: os.Exit завершает процесс сразу, и отложенные
+	// закрытия (БД, фоновые задачи) бы не выполнились
+	if err := run(); err != nil {
+		slog.Error("Сервер завершился с ошибкой", logging.Err(err))
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	// Настройки: умолчания, файл (--config), окружение и флаги - см. пакет config
+	cfg, opts, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка конфигурации: %w", err)
+	}
+
+	// Логи в JSON (или text) с уровнем из logging.level; log.Printf библиотек тоже идёт сюда
+	logger, err := logging.New(os.Stderr, cfg.Logging.Level, cfg.Logging.Format)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+
+	if opts.PrintConfig {
+		out, err := cfg.YAML()
+		if err != nil {
+			return fmt.Errorf("не удалось вывести конфигурацию: %w", err)
+		}
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+
+	// SIGINT/SIGTERM отменяют ctx: прерывается ожидание БД при старте,
+	// а работающий сервер начинает плавную остановку
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Трассировка: спаны запросов, методов сервиса и SQL уходят в tracing.exporter
+	// Закрываем последней (defer выполняются в обратном порядке), чтобы дописать спаны остановки
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing.Setup())
+	if err != nil {
+		return fmt.Errorf("не удалось настроить трассировку: %w", err)
+	}
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			slog.Error("Не удалось отправить спаны", logging.Err(err))
+		}
+	}()
+	if cfg.Tracing.Exporter != tracing.ExporterNone {
+		slog.Info("Трассировка включена", slog.String("exporter", cfg.Tracing.Exporter))
+	}
+
+	// Подключаемся к базе; если она ещё не поднялась - ждём database.connect_timeout
+	db, err := database.Connect(ctx, cfg.Database.Connection())
 	if err != nil {
-		log.Fatalf("Не удалось подключиться к БД: %v", err)
+		return fmt.Errorf("не удалось подключиться к БД: %w", err)
 	}
 	defer db.Close()
-	log.Println("Подключение к БД установлено")
+	slog.Info("Подключение к БД установлено")
+
+	// Миграции встроены в бинарник
+	migrator, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		return fmt.Errorf("не удалось загрузить миграции: %w", err)
+	}
+
+	// expense-tracker migrate ... - только миграции, без запуска сервера
+	if len(opts.Args) > 0 && opts.Args[0] == "migrate" {
+		if err := runMigrate(ctx, migrator, opts.Args[1:]); err != nil {
+			return fmt.Errorf("ошибка миграции: %w", err)
+		}
+		return nil
+	}
+
+	// По умолчанию схема обновляется при старте; с MIGRATE_ON_START=false
+	// миграции запускают отдельно (migrate up), а сервер только проверяет версию
+	if cfg.Migrations.OnStart {
+		if err := runMigrate(ctx, migrator, []string{"up"}); err != nil {
+			return fmt.Errorf("ошибка миграции: %w", err)
+		}
+	} else if version, err := migrator.Version(ctx); err != nil {
+		return fmt.Errorf("не удалось проверить версию схемы: %w", err)
+	} else if version != migrator.Latest() {
+		slog.Warn("Схема БД отстаёт, запустите migrate up",
+			slog.Int("version", version), slog.Int("expected", migrator.Latest()))
+	}
+
+	// Метрики считаются всегда, metrics.enabled решает только, отдавать ли их
+	appMetrics := metrics.New()
+	appMetrics.RegisterDB(db.DB, "postgres")
 
 	// Создаём слои приложения
-	repo := database.NewExpenseRepository(db)
-	expenseService := service.NewExpenseService(repo)
-	expenseHandler := handlers.NewExpenseHandler(expenseService)
+	repo := database.NewExpenseRepository(db, database.WithQueryObserver(appMetrics))
+	ruleRepo := database.NewRuleRepository(db)
+	viewRepo := database.NewViewRepository(db)
 
-	// Настраиваем роутер
-	router := setupRouter(expenseHandler)
+	anomalyService := service.NewAnomalyService(repo, service.DefaultAnomalyConfig())
+	ruleService := service.NewRuleService(ruleRepo)
+	viewService := service.NewViewService(viewRepo)
+
+	// Изменения и записи о них в журнале сохраняются одной транзакцией
+	transactor := database.NewTransactor(db)
+	auditService := service.NewAuditService(database.NewAuditRepository(db), transactor)
+
+	// Классификатор учится на всей истории при старте, дальше - на лету
+	classifier := service.NewCategoryClassifier()
+	if err := classifier.Train(ctx, repo); err != nil {
+		slog.Warn("Не удалось обучить классификатор категорий", logging.Err(err))
+	}
+
+	expenseService := service.NewExpenseService(repo,
+		service.WithAnomalyDetector(anomalyService),
+		service.WithRules(ruleService),
+		service.WithClassifier(classifier),
+		service.WithViews(viewService),
+		service.WithTransactions(transactor),
+		service.WithAudit(auditService),
+		service.WithBatchLimit(cfg.Expenses.BatchMaxSize),
+		service.WithMetrics(appMetrics),
+	)
+
+	// Корзина: удалённые расходы хранятся trash_retention_days дней (0 - всегда)
+	retention := time.Duration(cfg.Expenses.TrashRetentionDays) * 24 * time.Hour
+	trashService := service.NewTrashService(repo, retention, classifier, auditService)
+
+	trashPurger := worker.NewPeriodic("trash-purge", time.Hour, func(ctx context.Context) error {
+		purged, err := trashService.PurgeExpired(ctx)
+		if purged > 0 {
+			slog.InfoContext(ctx, "Корзина очищена", slog.Int64("purged", purged))
+		}
+		return err
+	})
+
+	// Ответы на запросы с Idempotency-Key хранятся idempotency_ttl_hours часов
+	idempotencyTTL := time.Duration(cfg.Expenses.IdempotencyTTLHours) * time.Hour
+	idempotencyService := service.NewIdempotencyService(database.NewIdempotencyRepository(db), idempotencyTTL)
 
-	// Запускаем сервер
-	port := getEnv("PORT", "8080")
-	log.Printf("Сервер запускается на порту %s", port)
+	idempotencyPurger := worker.NewPeriodic("idempotency-purge", time.Hour, func(ctx context.Context) error {
+		_, err := idempotencyService.PurgeExpired(ctx)
+		return err
+	})
 
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("Ошибка запуска сервера: %v", err)
+	// Ограничение частоты: счётчики в памяти или общие в БД (rate_limit.store)
+	var limitStore ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateLimit.Store == "postgres" {
+		limitStore = database.NewRateLimitStore(db)
 	}
+	limitPurger := worker.NewPeriodic("rate-limit-purge", 10*time.Minute, func(ctx context.Context) error {
+		_, err := limitStore.Purge(ctx)
+		return err
+	})
+
+	// Фоновые задачи не зависят от сигнала: их останавливаем сами,
+	// уже после HTTP-сервера и до закрытия БД (defer выше)
+	workers := startWorkers(context.Background(), trashPurger, idempotencyPurger, limitPurger)
+	defer workers.Stop()
+
+	// Готовность: БД, версия схемы и фоновые задачи
+	healthService := service.NewHealthService(db, migrator, healthConfig(cfg.Health), trashPurger, idempotencyPurger, limitPurger)
+
+	h := &routes{
+		expenses:  handlers.NewExpenseHandler(expenseService),
+		anomalies: handlers.NewAnomalyHandler(anomalyService),
+		rules:     handlers.NewRuleHandler(ruleService, expenseService),
+		suggest:   handlers.NewAutocompleteHandler(service.NewAutocompleteService(repo)),
+		views:     handlers.NewViewHandler(viewService),
+		trash:     handlers.NewTrashHandler(trashService),
+		audit:     handlers.NewAuditHandler(auditService),
+		health:    handlers.NewHealthHandler(healthService),
+
+		idempotent: handlers.Idempotency(idempotencyService),
+		metrics:    handlers.RequestMetrics(appMetrics),
+		cors:       handlers.CORS(corsPolicy(cfg.CORS)),
+	}
+
+	apiLimit, batchLimit := cfg.RateLimit.Limits()
+	h.limitAPI = handlers.RateLimit(limitStore, "api", apiLimit)
+	h.limitBatch = handlers.RateLimit(limitStore, "batch", batchLimit)
+
+	// Метрики - на порту API или на отдельном адресе (metrics.addr),
+	// чтобы не открывать их вместе с API наружу
+	var servers []*http.Server
+	if cfg.Metrics.Enabled {
+		if cfg.Metrics.Addr == "" {
+			h.scrape, h.scrapePath = appMetrics.Handler(), cfg.Metrics.Path
+		} else {
+			servers = append(servers, newMetricsServer(cfg, appMetrics.Handler()))
+			slog.Info("Метрики на отдельном адресе", slog.String("addr", cfg.Metrics.Addr))
+		}
+	}
+
+	// Настраиваем роутер
+	router, err := setupRouter(h, cfg.Server, logger)
+	if err != nil {
+		return err
+	}
+
+	// Запускаем сервер и ждём сигнала остановки
+	servers = append(servers, newServer(cfg.Server, router))
+	slog.Info("Сервер запускается", slog.Int("port", cfg.Server.Port))
+
+	return serve(ctx, cfg.Server.ShutdownTimeout.Duration, servers...)
+}
+
+// routes - все хэндлеры приложения
+// Собрала в структуру, чтобы setupRouter не обрастал параметрами
+type routes struct {
+	expenses  *handlers.ExpenseHandler
+	anomalies *handlers.AnomalyHandler
+	rules     *handlers.RuleHandler
+	suggest   *handlers.AutocompleteHandler
+	views     *handlers.ViewHandler
+	trash     *handlers.TrashHandler
+	audit     *handlers.AuditHandler
+	health    *handlers.HealthHandler
+
+	// idempotent - повтор ответов по Idempotency-Key для создания расходов
+	idempotent gin.HandlerFunc
+
+	// cors - политика CORS для всех маршрутов
+	cors gin.HandlerFunc
+
+	// limitAPI - лимит на всю группу /api, limitBatch - ещё и на тяжёлые операции
+	limitAPI   gin.HandlerFunc
+	limitBatch gin.HandlerFunc
+
+	// metrics - учёт запросов; scrape - отдача метрик на scrapePath
+	// (nil, если метрики выключены или слушают отдельный адрес)
+	metrics    gin.HandlerFunc
+	scrape     http.Handler
+	scrapePath string
 }
 
 // setupRouter настраивает все маршруты
-func setupRouter(h *handlers.ExpenseHandler) *gin.Engine {
-	// В продакшене можно использовать gin.ReleaseMode
-	if os.Getenv("GIN_MODE") == "" {
-		gin.SetMode(gin.DebugMode)
+func setupRouter(h *routes, server config.ServerConfig, logger *slog.Logger) (*gin.Engine, error) {
+	// В продакшене - release (server.gin_mode / GIN_MODE)
+	gin.SetMode(server.GinMode)
+
+	// Вместо gin.Default: свои логи запросов и паник через slog
+	router := gin.New()
+
+	// По умолчанию gin верит X-Forwarded-For от кого угодно, а по IP клиента
+	// считаются лимиты запросов - верим только прокси из server.trusted_proxies
+	if err := router.SetTrustedProxies(server.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("server.trusted_proxies: %w", err)
 	}
+	router.Use(handlers.RequestID())
+	router.Use(handlers.Tracing())
+	router.Use(h.metrics)
+	router.Use(handlers.AccessLog(logger))
+	router.Use(handlers.Recovery(logger))
 
-	router := gin.Default()
+	// CORS: каким сайтам браузер разрешит обращаться к API (cors.allowed_origins)
+	router.Use(h.cors)
 
-	// Middleware для CORS (если будет фронтенд)
-	router.Use(corsMiddleware())
+	// Пользователь из X-User-ID - владелец сохранённых представлений
+	router.Use(handlers.Identity())
 
-	// Health check - для мониторинга
+	// Пробы: /livez - процесс жив, /readyz - готов принимать запросы
+	// /health оставлен для старых проверок, он ничего не проверяет
 	router.GET("/health", handlers.HealthCheck)
+	router.GET("/livez", h.health.Live)
+	router.GET("/readyz", h.health.Ready)
+
+	// Метрики Prometheus
+	if h.scrape != nil {
+		router.GET(h.scrapePath, gin.WrapH(h.scrape))
+	}
 
 	// API routes
-	api := router.Group("/api")
+	api := router.Group("/api", h.limitAPI)
 	{
 		// Расходы
 		expenses := api.Group("/expenses")
 		{
-			expenses.POST("", h.CreateExpense)
-			expenses.GET("", h.GetExpenses)
-			expenses.GET("/:id", h.GetExpense)
-			expenses.PUT("/:id", h.UpdateExpense)
-			expenses.DELETE("/:id", h.DeleteExpense)
+			expenses.POST("", h.idempotent, h.expenses.CreateExpense)
+			expenses.GET("", h.expenses.GetExpenses)
+			expenses.POST("/batch", h.limitBatch, h.idempotent, h.expenses.BatchExpenses)
+			expenses.POST("/import", h.limitBatch, h.idempotent, h.expenses.ImportExpenses)
+			expenses.GET("/:id", h.expenses.GetExpense)
+			expenses.PUT("/:id", h.expenses.ReplaceExpense)
+			expenses.PATCH("/:id", h.expenses.PatchExpense)
+			expenses.DELETE("/:id", h.expenses.DeleteExpense)
+			expenses.GET("/:id/history", h.audit.GetHistory)
 		}
 
 		// Статистика
-		api.GET("/stats", h.GetStats)
+		api.GET("/stats", h.expenses.GetStats)
 
 		// Категории
-		api.GET("/categories", h.GetCategories)
-	}
+		api.GET("/categories", h.expenses.GetCategories)
+		api.GET("/categories/suggest", h.expenses.SuggestCategories)
 
-	return router
-}
+		// Автодополнение для формы ввода
+		api.GET("/suggest", h.suggest.Suggest)
+
+		// Необычные расходы
+		api.GET("/anomalies", h.anomalies.GetAnomalies)
 
-// corsMiddleware добавляет заголовки CORS
-// Без этого браузер не даст фронтенду делать запросы
-func corsMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
+		// Правила автокатегоризации
+		rules := api.Group("/rules")
+		{
+			rules.POST("", h.rules.CreateRule)
+			rules.GET("", h.rules.GetRules)
+			rules.POST("/apply", h.limitBatch, h.rules.ApplyRules)
+			rules.GET("/:id", h.rules.GetRule)
+			rules.PUT("/:id", h.rules.UpdateRule)
+			rules.DELETE("/:id", h.rules.DeleteRule)
 		}
 
-		c.Next()
-	}
-}
+		// Журнал изменений
+		api.GET("/audit", h.audit.GetAudit)
 
-// getEnv возвращает значение переменной окружения или дефолт
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+		// Корзина
+		trash := api.Group("/trash")
+		{
+			trash.GET("", h.trash.GetTrash)
+			trash.POST("/:id/restore", h.trash.RestoreExpense)
+			trash.DELETE("/:id", h.trash.PurgeExpense)
+		}
+
+		// Сохранённые представления; запуск - GET /api/expenses?view=ID
+		views := api.Group("/views")
+		{
+			views.POST("", h.views.CreateView)
+			views.GET("", h.views.GetViews)
+			views.GET("/:id", h.views.GetView)
+			views.PUT("/:id", h.views.UpdateView)
+			views.DELETE("/:id", h.views.DeleteView)
+		}
 	}
-	return defaultValue
+
+	return router, nil
 }
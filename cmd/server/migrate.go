@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/migrate"
+)
+
+// runMigrate - подкоманда migrate:
+//
+//	expense-tracker migrate [up]      - применить все новые миграции
+//	expense-tracker migrate down [N]  - откатить N последних (по умолчанию 1)
+//	expense-tracker migrate status    - что применено, а что нет
+func runMigrate(ctx context.Context, runner *migrate.Runner, args []string) error {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		applied, err := runner.Up(ctx)
+		for _, m := range applied {
+			slog.Info("Применена миграция", slog.Int("version", m.Version), slog.String("name", m.Name))
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			slog.Info("Схема актуальна", slog.Int("version", runner.Latest()))
+		}
+		return nil
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("migrate down: ожидается число миграций, получили %q", args[1])
+			}
+			steps = n
+		}
+		reverted, err := runner.Down(ctx, steps)
+		for _, m := range reverted {
+			slog.Info("Откачена миграция", slog.Int("version", m.Version), slog.String("name", m.Name))
+		}
+		return err
+
+	case "status":
+		statuses, err := runner.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "не применена"
+			if s.Applied {
+				state = "применена " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if s.Modified {
+				state += ", ФАЙЛ ИЗМЕНЁН"
+			}
+			fmt.Fprintf(os.Stdout, "%03d_%-35s %s\n", s.Version, s.Name, state)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("неизвестная команда migrate %q: up, down [N] или status", command)
+	}
+}
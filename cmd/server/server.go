@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/config"
+	"github.com/dvoryadkinadv/expense-tracker/internal/handlers"
+	"github.com/dvoryadkinadv/expense-tracker/internal/service"
+	"github.com/dvoryadkinadv/expense-tracker/internal/worker"
+)
+
+// newServer - http.Server с таймаутами из конфигурации
+// router.Run таймаутов не ставит, и медленный клиент мог держать соединение вечно
+func newServer(cfg config.ServerConfig, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.Port),
+		Handler:      handler,
+		ReadTimeout:  cfg.ReadTimeout.Duration,
+		WriteTimeout: cfg.WriteTimeout.Duration,
+		IdleTimeout:  cfg.IdleTimeout.Duration,
+	}
+}
+
+// newMetricsServer - отдельный сервер только для метрик (metrics.addr)
+func newMetricsServer(cfg config.Config, handler http.Handler) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle(cfg.Metrics.Path, handler)
+
+	return &http.Server{
+		Addr:         cfg.Metrics.Addr,
+		Handler:      mux,
+		ReadTimeout:  cfg.Server.ReadTimeout.Duration,
+		WriteTimeout: cfg.Server.WriteTimeout.Duration,
+		IdleTimeout:  cfg.Server.IdleTimeout.Duration,
+	}
+}
+
+// healthConfig - настройки для service.NewHealthService
+func healthConfig(cfg config.HealthConfig) service.HealthConfig {
+	return service.HealthConfig{
+		Timeout:  cfg.CheckTimeout.Duration,
+		CacheTTL: cfg.CacheTTL.Duration,
+	}
+}
+
+// corsPolicy - настройки для handlers.CORS
+// Источники config уже проверил при загрузке
+func corsPolicy(cfg config.CORSConfig) handlers.CORSPolicy {
+	return handlers.CORSPolicy{
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowCredentials: cfg.AllowCredentials,
+		AllowedMethods:   cfg.AllowedMethods,
+		AllowedHeaders:   cfg.AllowedHeaders,
+		ExposedHeaders:   cfg.ExposedHeaders,
+		MaxAge:           cfg.MaxAge.Duration,
+	}
+}
+
+// serve запускает серверы и работает, пока не отменят ctx или один из них не упадёт
+// После отмены новые соединения не принимаются, а начатые запросы дорабатывают
+// не дольше shutdownTimeout; кто не успел - обрывается
+func serve(ctx context.Context, shutdownTimeout time.Duration, servers ...*http.Server) error {
+	errCh := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func() {
+			errCh <- srv.ListenAndServe()
+		}()
+	}
+
+	var serveErr error
+	select {
+	case err := <-errCh:
+		// Один не запустился (например, порт занят) - останавливаем остальные
+		serveErr = fmt.Errorf("ошибка запуска сервера: %w", err)
+	case <-ctx.Done():
+		slog.Info("Останавливаем сервер, ждём текущие запросы", slog.String("timeout", shutdownTimeout.String()))
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	var shutdownErrs []error
+	for _, srv := range servers {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			srv.Close()
+			shutdownErrs = append(shutdownErrs, fmt.Errorf("%s: не все запросы завершились за %s: %w", srv.Addr, shutdownTimeout, err))
+		}
+	}
+	if serveErr != nil || len(shutdownErrs) > 0 {
+		return errors.Join(append([]error{serveErr}, shutdownErrs...)...)
+	}
+
+	slog.Info("Сервер остановлен")
+	return nil
+}
+
+// workers - запущенные фоновые задачи
+type workers []*worker.Periodic
+
+// startWorkers запускает задачи в переданном порядке
+func startWorkers(ctx context.Context, tasks ...*worker.Periodic) workers {
+	for _, task := range tasks {
+		task.Start(ctx)
+	}
+	return tasks
+}
+
+// Stop останавливает задачи в обратном порядке, дожидаясь текущих прогонов
+func (w workers) Stop() {
+	for i := len(w) - 1; i >= 0; i-- {
+		w[i].Stop()
+		slog.Info("Фоновая задача остановлена", slog.String("worker", w[i].Name()))
+	}
+}
@@ -0,0 +1,230 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func env(values map[string]string) func(string) string {
+	return func(key string) string {
+		return values[key]
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, opts, err := Load(nil, env(nil))
+	if err != nil {
+		t.Fatalf("Умолчания должны проходить проверку: %v", err)
+	}
+	if !reflect.DeepEqual(cfg, Default()) {
+		t.Errorf("Без источников ожидали Default(), получили %+v", cfg)
+	}
+	if opts.File != "" || opts.PrintConfig || len(opts.Args) != 0 {
+		t.Errorf("Неожиданные опции: %+v", opts)
+	}
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeFile(t, "app.yaml", `
+server:
+  port: 9000
+database:
+  host: file-host
+  name: file-db
+  conn_max_lifetime: 1h
+`)
+
+	cfg, opts, err := Load(
+		[]string{"--config", path, "--db-host", "flag-host", "migrate", "status"},
+		env(map[string]string{"DB_HOST": "env-host", "DB_NAME": "env-db"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Server.Port != 9000 {
+		t.Errorf("Порт из файла: ожидали 9000, получили %d", cfg.Server.Port)
+	}
+	if cfg.Database.Name != "env-db" {
+		t.Errorf("Окружение сильнее файла: ожидали env-db, получили %q", cfg.Database.Name)
+	}
+	if cfg.Database.Host != "flag-host" {
+		t.Errorf("Флаг сильнее окружения: ожидали flag-host, получили %q", cfg.Database.Host)
+	}
+	if cfg.Database.ConnMaxLifetime.Duration != time.Hour {
+		t.Errorf("Длительность из файла: ожидали 1h, получили %s", cfg.Database.ConnMaxLifetime)
+	}
+	if cfg.Database.User != "postgres" || cfg.Database.MaxOpenConns != 25 {
+		t.Errorf("Чего нет в файле, должно остаться по умолчанию: %+v", cfg.Database)
+	}
+	if strings.Join(opts.Args, " ") != "migrate status" {
+		t.Errorf("Аргументы после флагов: ожидали migrate status, получили %v", opts.Args)
+	}
+}
+
+func TestLoad_TOMLFromEnv(t *testing.T) {
+	path := writeFile(t, "app.toml", `
+[expenses]
+batch_max_size = 10
+
+[migrations]
+on_start = false
+`)
+
+	cfg, opts, err := Load([]string{"--migrate-on-start"}, env(map[string]string{"CONFIG_FILE": path}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.File != path {
+		t.Errorf("Файл из CONFIG_FILE: ожидали %s, получили %q", path, opts.File)
+	}
+	if cfg.Expenses.BatchMaxSize != 10 {
+		t.Errorf("Ожидали batch_max_size 10, получили %d", cfg.Expenses.BatchMaxSize)
+	}
+	if !cfg.Migrations.OnStart {
+		t.Error("Булев флаг без значения должен включать on_start")
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		want string
+	}{
+		{"неизвестный ключ", []string{"--config", writeFile(t, "a.yaml", "server:\n  prot: 1\n")}, nil, "prot"},
+		{"неизвестный ключ в TOML", []string{"--config", writeFile(t, "a.toml", "[server]\nprot = 1\n")}, nil, "prot"},
+		{"неизвестное расширение", []string{"--config", writeFile(t, "a.json", "{}")}, nil, ".toml"},
+		{"нет файла", []string{"--config", "/nonexistent/app.yaml"}, nil, "не удалось прочитать"},
+		{"не число в окружении", nil, map[string]string{"DB_PORT": "abc"}, "DB_PORT"},
+		{"не длительность", nil, map[string]string{"DB_CONN_MAX_LIFETIME": "10"}, "DB_CONN_MAX_LIFETIME"},
+		{"не число во флаге", []string{"--port", "x"}, nil, "port"},
+		{"неверный порт", []string{"--port", "70000"}, nil, "server.port"},
+		{"* с credentials", nil, map[string]string{"CORS_ALLOWED_ORIGINS": "*", "CORS_ALLOW_CREDENTIALS": "true"}, "cors.allowed_origins"},
+		{"источник с путём", nil, map[string]string{"CORS_ALLOWED_ORIGINS": "https://app.example.com/login"}, "cors.allowed_origins"},
+	}
+
+	for _, tt := range tests {
+		_, _, err := Load(tt.args, env(tt.env))
+		if err == nil {
+			t.Errorf("%s: ожидали ошибку", tt.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: ошибка должна упоминать %q, получили %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestValidate_AllErrors(t *testing.T) {
+	cfg := Default()
+	cfg.Server.GinMode = "prod"
+	cfg.Database.MaxOpenConns = 5
+	cfg.Database.MaxIdleConns = 10
+	cfg.Expenses.BatchMaxSize = 0
+	cfg.Server.ShutdownTimeout = Duration{}
+	cfg.Metrics.Addr = ":8080"
+	cfg.Tracing.Exporter = "file"
+	cfg.Tracing.SampleRatio = 1.5
+	cfg.Server.TrustedProxies = []string{"10.0.0.0/8", "proxy.local"}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Ожидали ошибку")
+	}
+	for _, key := range []string{"server.gin_mode", "database.max_idle_conns", "expenses.batch_max_size", "server.shutdown_timeout", "metrics.addr", "tracing.file", "tracing.sample_ratio", "server.trusted_proxies"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("В ошибке нет %s: %v", key, err)
+		}
+	}
+}
+
+func TestValidate_DatabaseURL(t *testing.T) {
+	cfg := Default()
+	cfg.Database.URL = "postgres://app:secret@db:5432/expenses?sslmode=require"
+	cfg.Database.Host = ""
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("С DATABASE_URL отдельные параметры не нужны: %v", err)
+	}
+
+	cfg.Database.URL = "mysql://db/expenses"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Ожидали ошибку для не-postgres URL")
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Password = "secret"
+	cfg.Database.URL = "postgres://app:hunter2@db:5432/expenses?password=hunter3&sslmode=disable"
+
+	out, err := cfg.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"secret", "hunter2", "hunter3"} {
+		if strings.Contains(string(out), secret) {
+			t.Errorf("Секрет %q попал в вывод:\n%s", secret, out)
+		}
+	}
+	if !strings.Contains(string(out), "app:xxxxx@db:5432") {
+		t.Errorf("Пользователь и хост должны остаться видны:\n%s", out)
+	}
+
+	if cfg.Database.Password != "secret" {
+		t.Error("Redacted не должен менять исходную конфигурацию")
+	}
+}
+
+func TestLoad_Lists(t *testing.T) {
+	path := writeFile(t, "app.yaml", `
+cors:
+  allowed_origins:
+    - https://app.example.com
+  allowed_methods: [GET]
+`)
+
+	cfg, _, err := Load([]string{"--config", path}, env(map[string]string{
+		"CORS_ALLOWED_HEADERS": " Content-Type, X-User-ID ,",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(cfg.CORS.AllowedOrigins, []string{"https://app.example.com"}) {
+		t.Errorf("allowed_origins из файла: получили %v", cfg.CORS.AllowedOrigins)
+	}
+	if !reflect.DeepEqual(cfg.CORS.AllowedMethods, []string{"GET"}) {
+		t.Errorf("Список из файла должен заменить умолчание, получили %v", cfg.CORS.AllowedMethods)
+	}
+	if !reflect.DeepEqual(cfg.CORS.AllowedHeaders, []string{"Content-Type", "X-User-ID"}) {
+		t.Errorf("Список из окружения через запятую: получили %v", cfg.CORS.AllowedHeaders)
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	for _, ok := range []string{"*", "https://app.example.com", "http://localhost:3000", "https://*.example.com"} {
+		if err := checkOrigin(ok); err != nil {
+			t.Errorf("%q: неожиданная ошибка %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"app.example.com", "https://app.example.com/path", "https://*.*.example.com", "https://app.*.com", "https://user@example.com"} {
+		if err := checkOrigin(bad); err == nil {
+			t.Errorf("%q: ожидали ошибку", bad)
+		}
+	}
+}
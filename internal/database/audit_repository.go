@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// AuditRepository - журнал изменений расходов
+// Методов изменения и удаления нет намеренно, в БД их ещё и запрещает триггер
+type AuditRepository struct {
+	db *sqlx.DB
+}
+
+// NewAuditRepository создаёт новый репозиторий журнала
+func NewAuditRepository(db *sqlx.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+const auditColumns = `id, expense_id, action, actor, changes, created_at`
+
+// AddAudit дописывает запись в журнал
+// Если в контексте есть транзакция, запись попадает в неё вместе с самим изменением
+func (r *AuditRepository) AddAudit(ctx context.Context, entry *models.AuditEntry) error {
+	query := `
+		INSERT INTO audit_log (expense_id, action, actor, changes)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx, query,
+		entry.ExpenseID, entry.Action, entry.Actor, entry.Changes,
+	).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка записи в журнал изменений: %w", err)
+	}
+
+	return nil
+}
+
+// ListAudit возвращает записи журнала по фильтру
+func (r *AuditRepository) ListAudit(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	var args queryArgs
+	var conditions []string
+
+	if filter.ExpenseID != 0 {
+		conditions = append(conditions, "expense_id = "+args.add(filter.ExpenseID))
+	}
+	if filter.Actor != "" {
+		conditions = append(conditions, "actor = "+args.add(filter.Actor))
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, "action = "+args.add(filter.Action))
+	}
+	if filter.From != "" {
+		conditions = append(conditions, "created_at >= "+args.add(filter.From)+"::timestamptz")
+	}
+	if filter.To != "" {
+		conditions = append(conditions, "created_at < "+args.add(filter.To)+"::timestamptz")
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	order := "created_at DESC, id DESC"
+	if filter.Ascending {
+		order = "created_at, id"
+	}
+
+	query := `SELECT ` + auditColumns + ` FROM audit_log` + where +
+		` ORDER BY ` + order +
+		` LIMIT ` + args.add(filter.Limit) + ` OFFSET ` + args.add(filter.Offset)
+
+	var entries []models.AuditEntry
+	if err := conn(ctx, r.db).SelectContext(ctx, &entries, query, args.values...); err != nil {
+		return nil, fmt.Errorf("ошибка чтения журнала изменений: %w", err)
+	}
+
+	if entries == nil {
+		entries = []models.AuditEntry{}
+	}
+
+	return entries, nil
+}
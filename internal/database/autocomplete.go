@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/filterql"
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+)
+
+// suggestCandidates - сколько самых свежих совпадений группируем
+// Ограничение держит время ответа стабильным даже на большой таблице:
+// для подсказок важнее недавнее, чем полнота
+const suggestCandidates = 2000
+
+// Оценка подсказки складывается из трёх частей:
+// похожесть текста (триграммы, плюс бонус за совпадение префикса),
+// частота (логарифм, чтобы частые не забивали всё) и свежесть
+// (экспонента с характерным временем 30 дней)
+const suggestScore = `
+	(max(sim) + CASE WHEN bool_or(is_prefix) THEN 0.5 ELSE 0 END)::float8
+	+ 0.3 * ln(1 + count(*))::float8
+	+ 0.5 * exp(-(CURRENT_DATE - max(date))::float8 / 30)`
+
+// Описания группируем без учёта регистра, а показываем самый свежий вариант
+const suggestDescriptionsQuery = `
+	WITH matches AS (
+		SELECT lower(description) AS key, description, category, amount, merchant, date,
+		       similarity(lower(description), $1) AS sim,
+		       lower(description) LIKE $2 AS is_prefix
+		FROM expenses
+		WHERE deleted_at IS NULL AND (lower(description) % $1 OR lower(description) LIKE $2)
+		ORDER BY date DESC
+		LIMIT $4
+	)
+	SELECT (array_agg(description ORDER BY date DESC))[1] AS value,
+	       mode() WITHIN GROUP (ORDER BY category) AS category,
+	       mode() WITHIN GROUP (ORDER BY amount) AS amount,
+	       mode() WITHIN GROUP (ORDER BY merchant) AS merchant,
+	       count(*) AS uses,
+	       max(date) AS last_used,
+	       ` + suggestScore + ` AS score
+	FROM matches
+	GROUP BY key
+	ORDER BY score DESC, uses DESC
+	LIMIT $3`
+
+// Для продавцов подсказываем ещё и обычную категорию
+const suggestMerchantsQuery = `
+	WITH matches AS (
+		SELECT merchant, category, date,
+		       similarity(lower(merchant), $1) AS sim,
+		       lower(merchant) LIKE $2 AS is_prefix
+		FROM expenses
+		WHERE deleted_at IS NULL AND merchant <> '' AND (lower(merchant) % $1 OR lower(merchant) LIKE $2)
+		ORDER BY date DESC
+		LIMIT $4
+	)
+	SELECT merchant AS value,
+	       mode() WITHIN GROUP (ORDER BY category) AS category,
+	       count(*) AS uses,
+	       max(date) AS last_used,
+	       ` + suggestScore + ` AS score
+	FROM matches
+	GROUP BY merchant
+	ORDER BY score DESC, uses DESC
+	LIMIT $3`
+
+const suggestCategoriesQuery = `
+	WITH matches AS (
+		SELECT category, date,
+		       similarity(lower(category), $1) AS sim,
+		       lower(category) LIKE $2 AS is_prefix
+		FROM expenses
+		WHERE deleted_at IS NULL AND (lower(category) % $1 OR lower(category) LIKE $2)
+		ORDER BY date DESC
+		LIMIT $4
+	)
+	SELECT category AS value,
+	       count(*) AS uses,
+	       max(date) AS last_used,
+	       ` + suggestScore + ` AS score
+	FROM matches
+	GROUP BY category
+	ORDER BY score DESC, uses DESC
+	LIMIT $3`
+
+// Suggest возвращает варианты автодополнения для поля
+// Похожие с опечатками находятся через оператор % из pg_trgm
+func (r *ExpenseRepository) Suggest(ctx context.Context, field models.SuggestField, prefix string, limit int) ([]models.Suggestion, error) {
+	defer r.observe("Suggest", time.Now())
+
+	var query string
+	switch field {
+	case models.SuggestDescription:
+		query = suggestDescriptionsQuery
+	case models.SuggestMerchant:
+		query = suggestMerchantsQuery
+	case models.SuggestCategory:
+		query = suggestCategoriesQuery
+	default:
+		return nil, fmt.Errorf("неизвестное поле для подсказок: %s", field)
+	}
+
+	prefix = strings.ToLower(prefix)
+
+	var suggestions []models.Suggestion
+	err := conn(ctx, r.db).SelectContext(ctx, &suggestions, query, prefix, filterql.EscapeLike(prefix)+"%", limit, suggestCandidates)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения подсказок: %w", err)
+	}
+
+	if suggestions == nil {
+		suggestions = []models.Suggestion{}
+	}
+
+	return suggestions, nil
+}
@@ -0,0 +1,165 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/filterql"
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+	"github.com/lib/pq"
+)
+
+// queryArgs накапливает аргументы запроса и выдаёт плейсхолдеры $1, $2, ...
+// Так не нужно руками следить за номерами, как раньше с argNum
+type queryArgs struct {
+	values []interface{}
+}
+
+func (a *queryArgs) add(v interface{}) string {
+	a.values = append(a.values, v)
+	return fmt.Sprintf("$%d", len(a.values))
+}
+
+// expenseQuery - условия выборки расходов по фильтру
+// Общая часть для списка и для подсчёта total_count
+type expenseQuery struct {
+	args       queryArgs
+	conditions []string
+	tsQuery    string // выражение tsquery, если есть полнотекстовый поиск
+}
+
+func newExpenseQuery(filter models.ExpenseFilter) (*expenseQuery, error) {
+	q := &expenseQuery{}
+
+	// Расходы из корзины не видны ни в списках, ни в статистике
+	q.where("deleted_at IS NULL")
+
+	// Полнотекстовый поиск: плейсхолдер запроса переиспользуется
+	// и в условии, и в ранжировании с подсветкой
+	if tsQuery := prefixTSQuery(filter.Query); tsQuery != "" {
+		q.tsQuery = tsQueryExpr(q.args.add(tsQuery))
+		q.where("search_vector @@ " + q.tsQuery)
+	}
+
+	if filter.Category != "" {
+		q.where("category = " + q.args.add(filter.Category))
+	}
+
+	if len(filter.Categories) > 0 {
+		q.where("category = ANY(" + q.args.add(pq.Array(filter.Categories)) + ")")
+	}
+
+	if len(filter.ExcludeCategories) > 0 {
+		q.where("category <> ALL(" + q.args.add(pq.Array(filter.ExcludeCategories)) + ")")
+	}
+
+	if filter.AmountMin != nil {
+		q.where("amount >= " + q.args.add(*filter.AmountMin))
+	}
+
+	if filter.AmountMax != nil {
+		q.where("amount <= " + q.args.add(*filter.AmountMax))
+	}
+
+	if filter.CreatedAfter != "" {
+		q.where("created_at >= " + q.args.add(filter.CreatedAfter) + "::timestamptz")
+	}
+
+	if filter.DescriptionContains != "" {
+		pattern := "%" + filterql.EscapeLike(strings.ToLower(filter.DescriptionContains)) + "%"
+		q.where("lower(description) LIKE " + q.args.add(pattern))
+	}
+
+	if filter.DateFrom != "" {
+		q.where("date >= " + q.args.add(filter.DateFrom))
+	}
+
+	if filter.DateTo != "" {
+		q.where("date <= " + q.args.add(filter.DateTo))
+	}
+
+	// Выражение из filter= компилируется в условие с плейсхолдерами
+	// из того же набора аргументов, что и остальные фильтры
+	if filter.Expression != "" {
+		node, err := filterql.Parse(filter.Expression)
+		if err != nil {
+			return nil, err
+		}
+		cond, err := filterql.Compile(node, q.args.add)
+		if err != nil {
+			return nil, err
+		}
+		q.where(cond)
+	}
+
+	return q, nil
+}
+
+func (q *expenseQuery) where(condition string) {
+	q.conditions = append(q.conditions, condition)
+}
+
+// whereClause возвращает " WHERE ..." или пустую строку
+func (q *expenseQuery) whereClause() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(q.conditions, " AND ")
+}
+
+// sortColumn - колонка для сортировки и тип, к которому приводим значение из курсора
+type sortColumn struct {
+	column string
+	cast   string
+}
+
+// sortColumns - белый список сортировок
+// Имя колонки попадает прямо в SQL, поэтому только отсюда
+var sortColumns = map[string]sortColumn{
+	"date":       {column: "date", cast: "date"},
+	"amount":     {column: "amount", cast: "numeric"},
+	"created_at": {column: "created_at", cast: "timestamptz"},
+	"category":   {column: "category", cast: "text"},
+}
+
+// orderSpec - разобранная сортировка: колонка и направление
+type orderSpec struct {
+	sortColumn
+	desc bool
+}
+
+// parseSort разбирает "-amount" в колонку и направление
+func parseSort(sort string) (orderSpec, error) {
+	if sort == "" {
+		sort = models.DefaultSort
+	}
+
+	desc := strings.HasPrefix(sort, "-")
+	col, ok := sortColumns[strings.TrimPrefix(sort, "-")]
+	if !ok {
+		return orderSpec{}, fmt.Errorf("неизвестное поле сортировки: %s", sort)
+	}
+
+	return orderSpec{sortColumn: col, desc: desc}, nil
+}
+
+// orderBy - ORDER BY по колонке с id для однозначности
+// reverse переворачивает направление (для страницы назад)
+func (o orderSpec) orderBy(reverse bool) string {
+	dir := "ASC"
+	if o.desc != reverse {
+		dir = "DESC"
+	}
+	return fmt.Sprintf("%s %s, id %s", o.column, dir, dir)
+}
+
+// keysetCondition - условие "после курсора" в порядке сортировки
+// Для DESC вперёд значит "меньше", для ASC - "больше"; назад - наоборот
+func (o orderSpec) keysetCondition(q *expenseQuery, ks *models.Keyset) string {
+	op := ">"
+	if o.desc != ks.Backward {
+		op = "<"
+	}
+	return fmt.Sprintf("(%s, id) %s (%s::%s, %s)",
+		o.column, op, q.args.add(ks.Value), o.cast, q.args.add(ks.ID))
+}
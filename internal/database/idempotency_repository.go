@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// IdempotencyRepository - хранилище ключей идемпотентности
+type IdempotencyRepository struct {
+	db *sqlx.DB
+}
+
+// NewIdempotencyRepository создаёт новый репозиторий ключей
+func NewIdempotencyRepository(db *sqlx.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+const idempotencyColumns = `owner, key, fingerprint, status_code, content_type, body, created_at, expires_at`
+
+// ReserveKey занимает ключ под новый запрос
+// Возвращает false, если ключ уже занят и ещё не истёк.
+// Просроченный ключ перезаписывается, как будто его не было; так же -
+// ключ, занятый раньше staleBefore и так и не получивший ответа
+func (r *IdempotencyRepository) ReserveKey(ctx context.Context, record *models.IdempotencyRecord, staleBefore time.Time) (bool, error) {
+	query := `
+		INSERT INTO idempotency_keys (owner, key, fingerprint, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (owner, key) DO UPDATE
+		SET fingerprint = EXCLUDED.fingerprint, status_code = 0, content_type = '', body = NULL,
+			created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= EXCLUDED.created_at
+			OR (idempotency_keys.status_code = 0 AND idempotency_keys.created_at < $6)
+	`
+
+	result, err := conn(ctx, r.db).ExecContext(
+		ctx, query,
+		record.Owner, record.Key, record.Fingerprint, record.CreatedAt, record.ExpiresAt, staleBefore,
+	)
+	if err != nil {
+		return false, fmt.Errorf("ошибка сохранения ключа идемпотентности: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ошибка сохранения ключа идемпотентности: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// GetKey возвращает ключ (nil, если его нет)
+func (r *IdempotencyRepository) GetKey(ctx context.Context, owner, key string) (*models.IdempotencyRecord, error) {
+	var record models.IdempotencyRecord
+
+	err := conn(ctx, r.db).GetContext(ctx, &record,
+		`SELECT `+idempotencyColumns+` FROM idempotency_keys WHERE owner = $1 AND key = $2`,
+		owner, key,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка получения ключа идемпотентности: %w", err)
+	}
+
+	return &record, nil
+}
+
+// CompleteKey сохраняет ответ на запрос
+// Только для ключа "в работе": сохранённый ответ не перезаписываем
+func (r *IdempotencyRepository) CompleteKey(ctx context.Context, record *models.IdempotencyRecord) error {
+	query := `
+		UPDATE idempotency_keys
+		SET status_code = $3, content_type = $4, body = $5
+		WHERE owner = $1 AND key = $2 AND status_code = 0
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx, query,
+		record.Owner, record.Key, record.StatusCode, record.ContentType, record.Body,
+	)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения ответа по ключу идемпотентности: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseKey освобождает ключ "в работе", чтобы запрос можно было повторить
+func (r *IdempotencyRepository) ReleaseKey(ctx context.Context, owner, key string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx,
+		`DELETE FROM idempotency_keys WHERE owner = $1 AND key = $2 AND status_code = 0`, owner, key)
+	if err != nil {
+		return fmt.Errorf("ошибка освобождения ключа идемпотентности: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpiredKeys удаляет ключи, истёкшие к моменту before
+func (r *IdempotencyRepository) DeleteExpiredKeys(ctx context.Context, before time.Time) (int64, error) {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка очистки ключей идемпотентности: %w", err)
+	}
+
+	return result.RowsAffected()
+}
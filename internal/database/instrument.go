@@ -0,0 +1,167 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"reflect"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/logging"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxLoggedQuery - сколько символов SQL оставляем в логе и в спане
+const maxLoggedQuery = 300
+
+// tracer - спаны SQL-запросов
+var tracer = otel.Tracer("github.com/dvoryadkinadv/expense-tracker/internal/database")
+
+// instrumentedQuerier оборачивает каждый запрос спаном и пишет ошибки в лог
+// request_id и trace_id берутся из контекста, так что ошибку БД можно связать
+// с HTTP-запросом. Параметры запроса никуда не попадают - в них бывают личные данные
+type instrumentedQuerier struct {
+	q querier
+}
+
+func (i instrumentedQuerier) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	ctx, q := startQuery(ctx, "get", query)
+	err := i.q.GetContext(ctx, dest, query, args...)
+	q.finish(err, returnedRows(dest, err))
+	return err
+}
+
+func (i instrumentedQuerier) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	ctx, q := startQuery(ctx, "select", query)
+	err := i.q.SelectContext(ctx, dest, query, args...)
+	q.finish(err, returnedRows(dest, err))
+	return err
+}
+
+func (i instrumentedQuerier) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, q := startQuery(ctx, "exec", query)
+	result, err := i.q.ExecContext(ctx, query, args...)
+	rows := int64(-1)
+	if err == nil {
+		if n, affectedErr := result.RowsAffected(); affectedErr == nil {
+			rows = n
+		}
+	}
+	q.finish(err, rows)
+	return result, err
+}
+
+func (i instrumentedQuerier) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, q := startQuery(ctx, "query", query)
+	rows, err := i.q.QueryContext(ctx, query, args...)
+	q.finish(err, -1)
+	return rows, err
+}
+
+func (i instrumentedQuerier) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, q := startQuery(ctx, "query_row", query)
+	row := i.q.QueryRowContext(ctx, query, args...)
+	q.finish(row.Err(), -1)
+	return row
+}
+
+// runningQuery - начатый запрос: спан и всё, что нужно для лога
+type runningQuery struct {
+	ctx   context.Context
+	span  trace.Span
+	op    string
+	query string
+	start time.Time
+}
+
+func startQuery(ctx context.Context, op, query string) (context.Context, *runningQuery) {
+	query = compactQuery(query)
+	verb := sqlVerb(query)
+
+	ctx, span := tracer.Start(ctx, verb,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "postgresql"),
+			attribute.String("db.operation.name", verb),
+			attribute.String("db.query.text", query),
+		),
+	)
+
+	return ctx, &runningQuery{ctx: ctx, span: span, op: op, query: query, start: time.Now()}
+}
+
+// finish закрывает спан; rows < 0 - число строк неизвестно
+// "Не найдено" ошибкой не считается, а отменённый клиентом запрос идёт в лог предупреждением
+func (q *runningQuery) finish(err error, rows int64) {
+	defer q.span.End()
+
+	if rows >= 0 {
+		q.span.SetAttributes(attribute.Int64("db.response.returned_rows", rows))
+	}
+	if err == nil || errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+
+	q.span.RecordError(err)
+	q.span.SetStatus(codes.Error, err.Error())
+
+	level := slog.LevelError
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		level = slog.LevelWarn
+	}
+
+	attrs := []slog.Attr{
+		slog.String("op", q.op),
+		slog.String("query", q.query),
+		slog.Float64("duration_ms", float64(time.Since(q.start).Microseconds())/1000),
+		logging.Err(err),
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		attrs = append(attrs, slog.String("pg_code", string(pqErr.Code)))
+		q.span.SetAttributes(attribute.String("db.response.status_code", string(pqErr.Code)))
+	}
+
+	slog.Default().LogAttrs(q.ctx, level, "Ошибка запроса к БД", attrs...)
+}
+
+// returnedRows - сколько строк прочитали в dest: длина среза для Select, 1 для Get
+func returnedRows(dest interface{}, err error) int64 {
+	if err != nil {
+		return -1
+	}
+	v := reflect.ValueOf(dest)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice {
+		return int64(v.Len())
+	}
+	return 1
+}
+
+// compactQuery - SQL в одну строку и не длиннее maxLoggedQuery
+func compactQuery(query string) string {
+	query = strings.Join(strings.Fields(query), " ")
+	if len(query) > maxLoggedQuery {
+		cut := maxLoggedQuery
+		for cut > 0 && !utf8.RuneStart(query[cut]) {
+			cut--
+		}
+		query = query[:cut] + "..."
+	}
+	return query
+}
+
+// sqlVerb - первое слово запроса (SELECT, INSERT, WITH...) для имени спана
+func sqlVerb(query string) string {
+	verb, _, _ := strings.Cut(query, " ")
+	return strings.ToUpper(verb)
+}
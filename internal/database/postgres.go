@@ -1,46 +1,130 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"time"
 
+	"github.com/dvoryadkinadv/expense-tracker/internal/logging"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // Config - настройки подключения к БД
 // Вынесла в отдельную структуру, чтобы было проще тестировать
 type Config struct {
+	// URL - строка подключения postgres://...; если задана, остальные
+	// параметры подключения не используются
+	URL      string
 	Host     string
 	Port     string
 	User     string
 	Password string
 	DBName   string
 	SSLMode  string
+
+	// Пул соединений; нули - значения по умолчанию database/sql
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// ConnectTimeout - сколько Connect пытается подключиться; 0 - одна попытка
+	ConnectTimeout time.Duration
+}
+
+// DSN - строка подключения для драйвера
+func (cfg Config) DSN() string {
+	if cfg.URL != "" {
+		return cfg.URL
+	}
+	return fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode,
+	)
 }
 
 // NewConnection создаёт подключение к PostgreSQL
 // Возвращаю sqlx.DB, потому что он удобнее стандартного sql.DB
 // (есть Get, Select и прочие плюшки)
 func NewConnection(cfg Config) (*sqlx.DB, error) {
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode,
-	)
+	return connect(context.Background(), cfg)
+}
 
-	db, err := sqlx.Connect("postgres", dsn)
+// Паузы между попытками подключения в Connect: 0.5s, 1s, 2s, 4s, 5s, 5s...
+const (
+	connectInitialDelay = 500 * time.Millisecond
+	connectMaxDelay     = 5 * time.Second
+)
+
+// Connect - NewConnection с повторами: при старте через docker-compose или
+// в Kubernetes база часто поднимается позже приложения
+// Пытается подключиться, пока не пройдёт cfg.ConnectTimeout или не отменят ctx.
+// Неверный логин или пароль не повторяется - ожидание тут не поможет
+func Connect(ctx context.Context, cfg Config) (*sqlx.DB, error) {
+	if cfg.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.ConnectTimeout)
+		defer cancel()
+	}
+
+	delay := connectInitialDelay
+	for attempt := 1; ; attempt++ {
+		db, err := connect(ctx, cfg)
+		if err == nil {
+			return db, nil
+		}
+		if cfg.ConnectTimeout <= 0 || !retryable(err) {
+			return nil, err
+		}
+
+		slog.WarnContext(ctx, "БД недоступна, повторим подключение",
+			slog.Int("attempt", attempt), slog.String("retry_in", delay.String()), logging.Err(err))
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return nil, fmt.Errorf("%w (подключение прервано)", err)
+			}
+			return nil, fmt.Errorf("%w (сдались после %d попыток)", err, attempt)
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, connectMaxDelay)
+	}
+}
+
+func connect(ctx context.Context, cfg Config) (*sqlx.DB, error) {
+	db, err := sqlx.Open("postgres", cfg.DSN())
 	if err != nil {
 		return nil, fmt.Errorf("не удалось подключиться к БД: %w", err)
 	}
 
 	// Проверяем, что соединение реально работает
-	if err := db.Ping(); err != nil {
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("БД не отвечает: %w", err)
 	}
 
 	// Настройки пула соединений
-	// Для простого приложения
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
+	if cfg.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
 	return db, nil
 }
+
+// retryable - имеет ли смысл повторять подключение
+// Ошибки авторизации (класс 28) сами не пройдут, остальное - скорее всего, база ещё стартует
+func retryable(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code.Class() != "28"
+	}
+	return !errors.Is(err, context.Canceled)
+}
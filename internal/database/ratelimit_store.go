@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/ratelimit"
+	"github.com/jmoiron/sqlx"
+)
+
+// RateLimitStore - корзины ограничения частоты в PostgreSQL, общие для всех экземпляров
+// Время берётся из БД (now()), а не из часов экземпляра, чтобы расхождение
+// часов между машинами не влияло на пополнение
+type RateLimitStore struct {
+	db *sqlx.DB
+}
+
+// NewRateLimitStore создаёт хранилище корзин
+func NewRateLimitStore(db *sqlx.DB) *RateLimitStore {
+	return &RateLimitStore{db: db}
+}
+
+// refilled - токены в корзине с учётом пополнения с прошлого запроса
+// $2 - размер корзины, $3 - скорость пополнения в секунду.
+// В UPDATE ... SET справа от = видна старая строка, поэтому выражение можно повторять
+const refilled = `LEAST($2::float8, rate_limits.tokens + EXTRACT(EPOCH FROM (now() - rate_limits.updated_at))::float8 * $3::float8)`
+
+// Take забирает токен одним запросом: строка блокируется на время UPDATE,
+// и одновременные запросы разных экземпляров не заберут один токен дважды
+func (s *RateLimitStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	query := `
+		INSERT INTO rate_limits (key, tokens, allowed, rate, burst, updated_at)
+		VALUES ($1, $2::float8 - 1, TRUE, $3, $4, now())
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = CASE WHEN ` + refilled + ` >= 1 THEN ` + refilled + ` - 1 ELSE ` + refilled + ` END,
+			allowed = ` + refilled + ` >= 1,
+			rate = EXCLUDED.rate, burst = EXCLUDED.burst, updated_at = now()
+		RETURNING tokens, allowed
+	`
+
+	var state struct {
+		Tokens  float64 `db:"tokens"`
+		Allowed bool    `db:"allowed"`
+	}
+	if err := conn(ctx, s.db).GetContext(ctx, &state, query, key, float64(limit.Burst), limit.Rate(), limit.Burst); err != nil {
+		return ratelimit.Result{}, fmt.Errorf("ошибка ограничения частоты запросов: %w", err)
+	}
+
+	return ratelimit.NewResult(limit, state.Tokens, state.Allowed), nil
+}
+
+// Purge удаляет корзины, которые уже успели наполниться
+func (s *RateLimitStore) Purge(ctx context.Context) (int64, error) {
+	result, err := conn(ctx, s.db).ExecContext(ctx, `
+		DELETE FROM rate_limits
+		WHERE tokens + EXTRACT(EPOCH FROM (now() - updated_at))::float8 * rate >= burst
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка очистки корзин ограничения частоты: %w", err)
+	}
+
+	return result.RowsAffected()
+}
@@ -15,29 +15,66 @@ import (
 // Использую паттерн Repository, чтобы отделить логику работы с БД
 // от бизнес-логики и HTTP-обработчиков
 type ExpenseRepository struct {
-	db *sqlx.DB
+	db       *sqlx.DB
+	observer QueryObserver
 }
 
+// QueryObserver получает время выполнения методов репозитория (для метрик)
+type QueryObserver interface {
+	ObserveQuery(method string, duration time.Duration)
+}
+
+// RepositoryOption - необязательная настройка репозитория
+type RepositoryOption func(*ExpenseRepository)
+
+// WithQueryObserver передаёт время каждого метода в observer
+func WithQueryObserver(o QueryObserver) RepositoryOption {
+	return func(r *ExpenseRepository) {
+		r.observer = o
+	}
+}
+
+// expenseColumns - колонки расхода, которые читаем во всех запросах
+// Держу в одном месте, чтобы при добавлении поля не забыть какой-нибудь SELECT
+const expenseColumns = `id, description, amount, category, merchant, tags, date, created_at, version, updated_at, deleted_at`
+
 // NewExpenseRepository создаёт новый репозиторий
-func NewExpenseRepository(db *sqlx.DB) *ExpenseRepository {
-	return &ExpenseRepository{db: db}
+func NewExpenseRepository(db *sqlx.DB, opts ...RepositoryOption) *ExpenseRepository {
+	r := &ExpenseRepository{db: db}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// observe отмечает время метода: defer r.observe("GetAll", time.Now())
+func (r *ExpenseRepository) observe(method string, start time.Time) {
+	if r.observer != nil {
+		r.observer.ObserveQuery(method, time.Since(start))
+	}
 }
 
 // Create добавляет новый расход в БД
 func (r *ExpenseRepository) Create(ctx context.Context, expense *models.Expense) error {
+	defer r.observe("Create", time.Now())
+
 	query := `
-		INSERT INTO expenses (description, amount, category, date, created_at)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id
+		INSERT INTO expenses (description, amount, category, merchant, tags, date, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		RETURNING id, version
 	`
 
 	expense.CreatedAt = time.Now()
+	expense.UpdatedAt = expense.CreatedAt
+	if expense.Tags == nil {
+		expense.Tags = models.Tags{}
+	}
 
-	err := r.db.QueryRowContext(
+	err := conn(ctx, r.db).QueryRowContext(
 		ctx, query,
 		expense.Description, expense.Amount, expense.Category,
-		expense.Date, expense.CreatedAt,
-	).Scan(&expense.ID)
+		expense.Merchant, expense.Tags, expense.Date, expense.CreatedAt,
+	).Scan(&expense.ID, &expense.Version)
 
 	if err != nil {
 		return fmt.Errorf("ошибка создания расхода: %w", err)
@@ -48,11 +85,13 @@ func (r *ExpenseRepository) Create(ctx context.Context, expense *models.Expense)
 
 // GetByID возвращает расход по ID
 func (r *ExpenseRepository) GetByID(ctx context.Context, id int64) (*models.Expense, error) {
+	defer r.observe("GetByID", time.Now())
+
 	var expense models.Expense
 
-	query := `SELECT id, description, amount, category, date, created_at FROM expenses WHERE id = $1`
+	query := `SELECT ` + expenseColumns + ` FROM expenses WHERE id = $1 AND deleted_at IS NULL`
 
-	err := r.db.GetContext(ctx, &expense, query, id)
+	err := conn(ctx, r.db).GetContext(ctx, &expense, query, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // расход не найден - это нормально, не ошибка
@@ -64,55 +103,54 @@ func (r *ExpenseRepository) GetByID(ctx context.Context, id int64) (*models.Expe
 }
 
 // GetAll возвращает список расходов с фильтрацией
-// Тут немного магии со строками, но зато гибко!
+// Постраничный вывод двумя способами: старый LIMIT/OFFSET и keyset
+// по (поле сортировки, id) - он не замедляется на дальних страницах
+// и не пропускает строки, когда данные меняются между запросами
 func (r *ExpenseRepository) GetAll(ctx context.Context, filter models.ExpenseFilter) ([]models.Expense, error) {
-	var expenses []models.Expense
-	var args []interface{}
-	var conditions []string
+	defer r.observe("GetAll", time.Now())
 
-	query := `SELECT id, description, amount, category, date, created_at FROM expenses`
-	argNum := 1
+	var expenses []models.Expense
 
-	// Собираем условия фильтрации
-	if filter.Category != "" {
-		conditions = append(conditions, fmt.Sprintf("category = $%d", argNum))
-		args = append(args, filter.Category)
-		argNum++
+	order, err := parseSort(filter.Sort)
+	if err != nil {
+		return nil, err
 	}
 
-	if filter.DateFrom != "" {
-		conditions = append(conditions, fmt.Sprintf("date >= $%d", argNum))
-		args = append(args, filter.DateFrom)
-		argNum++
+	q, err := newExpenseQuery(filter)
+	if err != nil {
+		return nil, err
 	}
-
-	if filter.DateTo != "" {
-		conditions = append(conditions, fmt.Sprintf("date <= $%d", argNum))
-		args = append(args, filter.DateTo)
-		argNum++
+	columns := expenseColumns
+
+	// Keyset: продолжаем с места, где остановилась предыдущая страница.
+	// Назад идём в обратном порядке, а потом разворачиваем результат
+	backward := filter.Keyset != nil && filter.Keyset.Backward
+	orderBy := order.orderBy(backward)
+	if filter.Keyset != nil {
+		q.where(order.keysetCondition(q, filter.Keyset))
 	}
 
-	// Добавляем WHERE если есть условия
-	if len(conditions) > 0 {
-		query += " WHERE " + strings.Join(conditions, " AND ")
+	// Для поиска - релевантность и сниппет с подсветкой.
+	// Без явной сортировки сначала самые релевантные
+	if q.tsQuery != "" {
+		columns += searchColumns(q.tsQuery)
+		if filter.Sort == "" {
+			orderBy = "rank DESC, " + orderBy
+		}
 	}
 
-	// Сортировка по дате (новые сверху)
-	query += " ORDER BY date DESC, id DESC"
+	query := `SELECT ` + columns + ` FROM expenses` + q.whereClause() + " ORDER BY " + orderBy
 
-	// Пагинация
 	if filter.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT $%d", argNum)
-		args = append(args, filter.Limit)
-		argNum++
+		query += " LIMIT " + q.args.add(filter.Limit)
 	}
 
-	if filter.Offset > 0 {
-		query += fmt.Sprintf(" OFFSET $%d", argNum)
-		args = append(args, filter.Offset)
+	// С курсором смещение не нужно
+	if filter.Offset > 0 && filter.Keyset == nil {
+		query += " OFFSET " + q.args.add(filter.Offset)
 	}
 
-	err := r.db.SelectContext(ctx, &expenses, query, args...)
+	err = conn(ctx, r.db).SelectContext(ctx, &expenses, query, q.args.values...)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка получения списка расходов: %w", err)
 	}
@@ -122,11 +160,62 @@ func (r *ExpenseRepository) GetAll(ctx context.Context, filter models.ExpenseFil
 		expenses = []models.Expense{}
 	}
 
+	if q.tsQuery != "" {
+		for i := range expenses {
+			expenses[i].Highlight = highlightHTML(expenses[i].Highlight)
+		}
+	}
+
+	if backward {
+		for i, j := 0, len(expenses)-1; i < j; i, j = i+1, j-1 {
+			expenses[i], expenses[j] = expenses[j], expenses[i]
+		}
+	}
+
+	return expenses, nil
+}
+
+// Count возвращает, сколько всего расходов подходит под фильтр
+// Пагинация (limit, offset, курсор) не учитывается
+func (r *ExpenseRepository) Count(ctx context.Context, filter models.ExpenseFilter) (int, error) {
+	defer r.observe("Count", time.Now())
+
+	q, err := newExpenseQuery(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = conn(ctx, r.db).GetContext(ctx, &count, `SELECT COUNT(*) FROM expenses`+q.whereClause(), q.args.values...)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка подсчёта расходов: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetSince возвращает все расходы начиная с указанной даты
+// Нужен для анализа истории (поиск аномалий), поэтому без лимита -
+// объём ограничивается периодом
+func (r *ExpenseRepository) GetSince(ctx context.Context, since time.Time) ([]models.Expense, error) {
+	defer r.observe("GetSince", time.Now())
+
+	var expenses []models.Expense
+
+	query := `SELECT ` + expenseColumns + `
+		FROM expenses WHERE date >= $1 AND deleted_at IS NULL ORDER BY date, id`
+
+	if err := conn(ctx, r.db).SelectContext(ctx, &expenses, query, since); err != nil {
+		return nil, fmt.Errorf("ошибка получения истории расходов: %w", err)
+	}
+
 	return expenses, nil
 }
 
 // Update обновляет расход
 func (r *ExpenseRepository) Update(ctx context.Context, id int64, req models.UpdateExpenseRequest) (*models.Expense, error) {
+	defer r.observe("Update", time.Now())
+
 	var sets []string
 	var args []interface{}
 	argNum := 1
@@ -150,6 +239,18 @@ func (r *ExpenseRepository) Update(ctx context.Context, id int64, req models.Upd
 		argNum++
 	}
 
+	if req.Merchant != nil {
+		sets = append(sets, fmt.Sprintf("merchant = $%d", argNum))
+		args = append(args, *req.Merchant)
+		argNum++
+	}
+
+	if req.Tags != nil {
+		sets = append(sets, fmt.Sprintf("tags = $%d", argNum))
+		args = append(args, models.Tags(*req.Tags))
+		argNum++
+	}
+
 	if req.Date != nil {
 		parsedDate, err := time.Parse("2006-01-02", *req.Date)
 		if err != nil {
@@ -165,18 +266,26 @@ func (r *ExpenseRepository) Update(ctx context.Context, id int64, req models.Upd
 		return r.GetByID(ctx, id)
 	}
 
+	sets = append(sets, "version = version + 1", "updated_at = CURRENT_TIMESTAMP")
+
+	where := fmt.Sprintf("id = $%d AND deleted_at IS NULL", argNum)
+	args = append(args, id)
+	argNum++
+
+	// С ожидаемой версией обновление проходит, только если расход
+	// никто не успел поменять: иначе строк не найдётся
+	if req.Version != nil {
+		where += fmt.Sprintf(" AND version = $%d", argNum)
+		args = append(args, *req.Version)
+	}
+
 	query := fmt.Sprintf(
-		`UPDATE expenses SET %s WHERE id = $%d
-		 RETURNING id, description, amount, category, date, created_at`,
-		strings.Join(sets, ", "), argNum,
+		`UPDATE expenses SET %s WHERE %s RETURNING %s`,
+		strings.Join(sets, ", "), where, expenseColumns,
 	)
-	args = append(args, id)
 
 	var expense models.Expense
-	err := r.db.QueryRowContext(ctx, query, args...).Scan(
-		&expense.ID, &expense.Description, &expense.Amount,
-		&expense.Category, &expense.Date, &expense.CreatedAt,
-	)
+	err := conn(ctx, r.db).GetContext(ctx, &expense, query, args...)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -188,45 +297,61 @@ func (r *ExpenseRepository) Update(ctx context.Context, id int64, req models.Upd
 	return &expense, nil
 }
 
-// Delete удаляет расход по ID
-func (r *ExpenseRepository) Delete(ctx context.Context, id int64) error {
-	query := `DELETE FROM expenses WHERE id = $1`
+// Delete перемещает расход в корзину (мягкое удаление)
+// Запись остаётся в таблице с deleted_at и пропадает из всех выборок;
+// насовсем её удаляет Purge или очистка корзины по сроку.
+// version > 0 - удалять, только если версия совпадает
+func (r *ExpenseRepository) Delete(ctx context.Context, id int64, version int) error {
+	defer r.observe("Delete", time.Now())
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	query := `
+		UPDATE expenses
+		SET deleted_at = CURRENT_TIMESTAMP, version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR version = $2)
+	`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id, version)
 	if err != nil {
 		return fmt.Errorf("ошибка удаления расхода: %w", err)
 	}
 
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
-		return fmt.Errorf("расход с id=%d не найден", id)
+		return fmt.Errorf("расход с id=%d не найден: %w", id, sql.ErrNoRows)
 	}
 
 	return nil
 }
 
-// GetStats возвращает статистику по расходам
-func (r *ExpenseRepository) GetStats(ctx context.Context) (*models.ExpenseStats, error) {
+// GetStats возвращает статистику по расходам, подходящим под фильтр
+// Условия те же, что и у списка; пагинация и сортировка не учитываются
+func (r *ExpenseRepository) GetStats(ctx context.Context, filter models.ExpenseFilter) (*models.ExpenseStats, error) {
+	defer r.observe("GetStats", time.Now())
+
 	stats := &models.ExpenseStats{
 		ByCategory: make(map[string]float64),
 	}
 
+	q, err := newExpenseQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	where := q.whereClause()
+
 	// Общая статистика
-	err := r.db.QueryRowContext(ctx, `
+	err = conn(ctx, r.db).QueryRowContext(ctx, `
 		SELECT COALESCE(SUM(amount), 0), COUNT(*), COALESCE(AVG(amount), 0)
-		FROM expenses
-	`).Scan(&stats.TotalAmount, &stats.ExpenseCount, &stats.AverageAmount)
+		FROM expenses`+where, q.args.values...).Scan(&stats.TotalAmount, &stats.ExpenseCount, &stats.AverageAmount)
 
 	if err != nil {
 		return nil, fmt.Errorf("ошибка получения общей статистики: %w", err)
 	}
 
 	// Статистика по категориям
-	rows, err := r.db.QueryContext(ctx, `
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
 		SELECT category, COALESCE(SUM(amount), 0)
-		FROM expenses
-		GROUP BY category
-	`)
+		FROM expenses`+where+`
+		GROUP BY category`, q.args.values...)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка получения статистики по категориям: %w", err)
 	}
@@ -246,10 +371,12 @@ func (r *ExpenseRepository) GetStats(ctx context.Context) (*models.ExpenseStats,
 
 // GetCategories возвращает список уникальных категорий
 func (r *ExpenseRepository) GetCategories(ctx context.Context) ([]string, error) {
+	defer r.observe("GetCategories", time.Now())
+
 	var categories []string
 
-	err := r.db.SelectContext(ctx, &categories, `
-		SELECT DISTINCT category FROM expenses ORDER BY category
+	err := conn(ctx, r.db).SelectContext(ctx, &categories, `
+		SELECT DISTINCT category FROM expenses WHERE deleted_at IS NULL ORDER BY category
 	`)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка получения категорий: %w", err)
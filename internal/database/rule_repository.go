@@ -0,0 +1,110 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// RuleRepository - хранилище правил автокатегоризации
+type RuleRepository struct {
+	db *sqlx.DB
+}
+
+// NewRuleRepository создаёт новый репозиторий правил
+func NewRuleRepository(db *sqlx.DB) *RuleRepository {
+	return &RuleRepository{db: db}
+}
+
+const ruleColumns = `id, name, priority, enabled, stop, conditions, actions, created_at`
+
+// CreateRule добавляет правило
+func (r *RuleRepository) CreateRule(ctx context.Context, rule *models.Rule) error {
+	query := `
+		INSERT INTO rules (name, priority, enabled, stop, conditions, actions)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx, query,
+		rule.Name, rule.Priority, rule.Enabled, rule.Stop, rule.Conditions, rule.Actions,
+	).Scan(&rule.ID, &rule.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка создания правила: %w", err)
+	}
+
+	return nil
+}
+
+// GetRule возвращает правило по ID (nil, если не найдено)
+func (r *RuleRepository) GetRule(ctx context.Context, id int64) (*models.Rule, error) {
+	var rule models.Rule
+
+	err := conn(ctx, r.db).GetContext(ctx, &rule, `SELECT `+ruleColumns+` FROM rules WHERE id = $1`, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка получения правила: %w", err)
+	}
+
+	return &rule, nil
+}
+
+// ListRules возвращает все правила в порядке применения
+func (r *RuleRepository) ListRules(ctx context.Context) ([]models.Rule, error) {
+	var rules []models.Rule
+
+	err := conn(ctx, r.db).SelectContext(ctx, &rules, `SELECT `+ruleColumns+` FROM rules ORDER BY priority DESC, id`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения правил: %w", err)
+	}
+
+	if rules == nil {
+		rules = []models.Rule{}
+	}
+
+	return rules, nil
+}
+
+// UpdateRule целиком заменяет правило
+func (r *RuleRepository) UpdateRule(ctx context.Context, rule *models.Rule) error {
+	query := `
+		UPDATE rules
+		SET name = $1, priority = $2, enabled = $3, stop = $4, conditions = $5, actions = $6
+		WHERE id = $7
+		RETURNING created_at
+	`
+
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx, query,
+		rule.Name, rule.Priority, rule.Enabled, rule.Stop, rule.Conditions, rule.Actions, rule.ID,
+	).Scan(&rule.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("правило с id=%d не найдено: %w", rule.ID, err)
+		}
+		return fmt.Errorf("ошибка обновления правила: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteRule удаляет правило
+func (r *RuleRepository) DeleteRule(ctx context.Context, id int64) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM rules WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления правила: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("правило с id=%d не найдено: %w", id, sql.ErrNoRows)
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// prefixTSQuery превращает пользовательский запрос в текст для to_tsquery
+// "кофе стар" -> "кофе:* & стар:*"
+// Всё, кроме букв и цифр, выкидываем: синтаксис tsquery пользователю
+// не нужен, а кривой ввод ломал бы запрос
+func prefixTSQuery(q string) string {
+	words := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, w := range words {
+		terms = append(terms, w+":*")
+	}
+
+	return strings.Join(terms, " & ")
+}
+
+// tsQueryExpr - выражение tsquery по обеим конфигурациям
+// Плейсхолдер используется дважды, поэтому аргумент передаём один раз
+func tsQueryExpr(placeholder string) string {
+	return "(to_tsquery('russian', " + placeholder + ") || to_tsquery('english', " + placeholder + "))"
+}
+
+// Границы совпадений в сниппете из БД - символы из области частного
+// использования, в описаниях их не бывает. Сразу <mark> ставить нельзя:
+// описание пришло от пользователя и может содержать свою разметку
+const (
+	markStart = "\ue000"
+	markStop  = "\ue001"
+)
+
+// headlineOptions - как подсвечивать совпадения в сниппете
+const headlineOptions = "StartSel=" + markStart + ", StopSel=" + markStop + ", MaxWords=20, MinWords=5, MaxFragments=2"
+
+// highlightHTML экранирует сниппет и только потом ставит <mark> вокруг совпадений
+func highlightHTML(headline string) string {
+	escaped := html.EscapeString(headline)
+	return strings.NewReplacer(markStart, "<mark>", markStop, "</mark>").Replace(escaped)
+}
+
+// searchColumns - колонки релевантности и сниппета для выражения tsQuery
+func searchColumns(tsQuery string) string {
+	return ", ts_rank_cd(search_vector, " + tsQuery + ") AS rank" +
+		", ts_headline('russian', description, " + tsQuery + ", '" + headlineOptions + "') AS highlight"
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+)
+
+func TestPrefixTSQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		q    string
+		want string
+	}{
+		{"одно слово", "кофе", "кофе:*"},
+		{"несколько слов", "Кофе  Стар", "кофе:* & стар:*"},
+		{"цифры", "такси 24", "такси:* & 24:*"},
+		{"латиница", "Starbucks coffee", "starbucks:* & coffee:*"},
+		{"операторы tsquery", "кофе & !чай | (сок) <-> вода:*", "кофе:* & чай:* & сок:* & вода:*"},
+		{"кавычки и слэши", `'кофе' "чай" \сок`, "кофе:* & чай:* & сок:*"},
+		{"дефис разделяет слова", "кофе-машина", "кофе:* & машина:*"},
+		{"пустой запрос", "", ""},
+		{"только пробелы", "   \t ", ""},
+		{"только знаки", "&|!():*'", ""},
+	}
+
+	for _, tt := range tests {
+		if got := prefixTSQuery(tt.q); got != tt.want {
+			t.Errorf("%s: prefixTSQuery(%q) = %q, ожидали %q", tt.name, tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestNewExpenseQuery_Search(t *testing.T) {
+	q, err := newExpenseQuery(models.ExpenseFilter{Query: "кофе стар"})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	// Запрос передаётся одним аргументом, а плейсхолдер - в обе конфигурации
+	if len(q.args.values) != 1 || q.args.values[0] != "кофе:* & стар:*" {
+		t.Errorf("Ожидали один аргумент tsquery, получили %v", q.args.values)
+	}
+	want := "(to_tsquery('russian', $1) || to_tsquery('english', $1))"
+	if q.tsQuery != want {
+		t.Errorf("tsQuery: ожидали %s, получили %s", want, q.tsQuery)
+	}
+
+	// Запрос без слов - без поиска вообще, а не с пустым to_tsquery
+	q, err = newExpenseQuery(models.ExpenseFilter{Query: "&&"})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if q.tsQuery != "" || len(q.args.values) != 0 {
+		t.Errorf("Ожидали запрос без поиска, получили %q %v", q.tsQuery, q.args.values)
+	}
+}
+
+func TestSearchColumns(t *testing.T) {
+	columns := searchColumns("$1")
+
+	for _, want := range []string{
+		"ts_rank_cd(search_vector, $1) AS rank",
+		"ts_headline('russian', description, $1, 'StartSel=\ue000, StopSel=\ue001, MaxWords=20, MinWords=5, MaxFragments=2') AS highlight",
+	} {
+		if !strings.Contains(columns, want) {
+			t.Errorf("Ожидали %s в %s", want, columns)
+		}
+	}
+
+	// Настройки подсветки подставляются в строковый литерал SQL
+	if strings.ContainsAny(headlineOptions, `'\`) {
+		t.Errorf("headlineOptions не должны содержать кавычек: %s", headlineOptions)
+	}
+}
+
+func TestHighlightHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		headline string
+		want     string
+	}{
+		{"совпадение", "Утренний " + markStart + "кофе" + markStop + " с собой", "Утренний <mark>кофе</mark> с собой"},
+		{"разметка в описании", `<img src=x onerror=alert(1)> ` + markStart + "кофе" + markStop,
+			"&lt;img src=x onerror=alert(1)&gt; <mark>кофе</mark>"},
+		{"чужой <mark>", "<mark>" + markStart + "чай" + markStop + "</mark>", "&lt;mark&gt;<mark>чай</mark>&lt;/mark&gt;"},
+		{"кавычки и амперсанд", `"A&B" ` + markStart + "cafe" + markStop, "&#34;A&amp;B&#34; <mark>cafe</mark>"},
+	}
+
+	for _, tt := range tests {
+		if got := highlightHTML(tt.headline); got != tt.want {
+			t.Errorf("%s: highlightHTML(%q) = %q, ожидали %q", tt.name, tt.headline, got, tt.want)
+		}
+	}
+}
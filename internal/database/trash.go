@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+)
+
+// ListDeleted возвращает расходы из корзины, недавно удалённые сверху
+func (r *ExpenseRepository) ListDeleted(ctx context.Context, limit, offset int) ([]models.Expense, error) {
+	defer r.observe("ListDeleted", time.Now())
+
+	var expenses []models.Expense
+
+	query := `SELECT ` + expenseColumns + ` FROM expenses
+		WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id DESC
+		LIMIT $1 OFFSET $2`
+
+	err := conn(ctx, r.db).SelectContext(ctx, &expenses, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения корзины: %w", err)
+	}
+
+	if expenses == nil {
+		expenses = []models.Expense{}
+	}
+
+	return expenses, nil
+}
+
+// Restore возвращает расход из корзины (nil, если его там нет)
+func (r *ExpenseRepository) Restore(ctx context.Context, id int64) (*models.Expense, error) {
+	defer r.observe("Restore", time.Now())
+
+	var expense models.Expense
+
+	query := `UPDATE expenses
+		SET deleted_at = NULL, version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NOT NULL
+		RETURNING ` + expenseColumns
+
+	err := conn(ctx, r.db).GetContext(ctx, &expense, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка восстановления расхода: %w", err)
+	}
+
+	return &expense, nil
+}
+
+// Purge удаляет расход из корзины насовсем и возвращает его
+// (nil, если в корзине такого расхода нет)
+func (r *ExpenseRepository) Purge(ctx context.Context, id int64) (*models.Expense, error) {
+	defer r.observe("Purge", time.Now())
+
+	var expense models.Expense
+
+	query := `DELETE FROM expenses WHERE id = $1 AND deleted_at IS NOT NULL RETURNING ` + expenseColumns
+
+	err := conn(ctx, r.db).GetContext(ctx, &expense, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка очистки расхода: %w", err)
+	}
+
+	return &expense, nil
+}
+
+// PurgeDeletedBefore удаляет насовсем до limit расходов, лежащих в корзине
+// с before и раньше, и возвращает удалённые - для журнала
+func (r *ExpenseRepository) PurgeDeletedBefore(ctx context.Context, before time.Time, limit int) ([]models.Expense, error) {
+	defer r.observe("PurgeDeletedBefore", time.Now())
+
+	var expenses []models.Expense
+
+	query := `DELETE FROM expenses WHERE id IN (
+			SELECT id FROM expenses
+			WHERE deleted_at IS NOT NULL AND deleted_at < $1
+			ORDER BY deleted_at, id
+			LIMIT $2
+		)
+		RETURNING ` + expenseColumns
+
+	err := conn(ctx, r.db).SelectContext(ctx, &expenses, query, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка очистки корзины: %w", err)
+	}
+
+	return expenses, nil
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// querier - общее у *sqlx.DB и *sqlx.Tx
+// Репозиторий не знает, работает он в транзакции или нет
+type querier interface {
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type txKey struct{}
+
+// conn возвращает транзакцию из контекста, если она есть, иначе само подключение
+// Запросы через него попадают в трассировку, а ошибки - ещё и в лог с request_id
+func conn(ctx context.Context, db *sqlx.DB) querier {
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return instrumentedQuerier{tx}
+	}
+	return instrumentedQuerier{db}
+}
+
+// Transactor выполняет несколько операций репозиториев в одной транзакции
+type Transactor struct {
+	db *sqlx.DB
+}
+
+// NewTransactor создаёт новый Transactor
+func NewTransactor(db *sqlx.DB) *Transactor {
+	return &Transactor{db: db}
+}
+
+// WithinTx запускает fn в транзакции: всё, что репозитории делают с переданным
+// контекстом, попадает в неё. Ошибка или паника в fn - откат, иначе коммит.
+// Вложенный вызов переиспользует внешнюю транзакцию
+func (t *Transactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := t.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// ViewRepository - хранилище сохранённых представлений
+type ViewRepository struct {
+	db *sqlx.DB
+}
+
+// NewViewRepository создаёт новый репозиторий представлений
+func NewViewRepository(db *sqlx.DB) *ViewRepository {
+	return &ViewRepository{db: db}
+}
+
+const viewColumns = `id, name, owner, shared, filter, sort, columns, created_at, updated_at`
+
+// CreateView добавляет представление
+func (r *ViewRepository) CreateView(ctx context.Context, view *models.SavedView) error {
+	query := `
+		INSERT INTO saved_views (name, owner, shared, filter, sort, columns)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx, query,
+		view.Name, view.Owner, view.Shared, view.Filter, view.Sort, view.Columns,
+	).Scan(&view.ID, &view.CreatedAt, &view.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка создания представления: %w", err)
+	}
+
+	return nil
+}
+
+// GetView возвращает представление по ID (nil, если не найдено)
+func (r *ViewRepository) GetView(ctx context.Context, id int64) (*models.SavedView, error) {
+	var view models.SavedView
+
+	err := conn(ctx, r.db).GetContext(ctx, &view, `SELECT `+viewColumns+` FROM saved_views WHERE id = $1`, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка получения представления: %w", err)
+	}
+
+	return &view, nil
+}
+
+// ListViews возвращает представления владельца и общие
+func (r *ViewRepository) ListViews(ctx context.Context, owner string) ([]models.SavedView, error) {
+	var views []models.SavedView
+
+	err := conn(ctx, r.db).SelectContext(ctx, &views, `
+		SELECT `+viewColumns+` FROM saved_views
+		WHERE owner = $1 OR shared
+		ORDER BY name, id`, owner)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения представлений: %w", err)
+	}
+
+	if views == nil {
+		views = []models.SavedView{}
+	}
+
+	return views, nil
+}
+
+// UpdateView заменяет представление (владелец не меняется)
+func (r *ViewRepository) UpdateView(ctx context.Context, view *models.SavedView) error {
+	query := `
+		UPDATE saved_views
+		SET name = $1, shared = $2, filter = $3, sort = $4, columns = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $6
+		RETURNING owner, created_at, updated_at
+	`
+
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx, query,
+		view.Name, view.Shared, view.Filter, view.Sort, view.Columns, view.ID,
+	).Scan(&view.Owner, &view.CreatedAt, &view.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("представление с id=%d не найдено: %w", view.ID, err)
+		}
+		return fmt.Errorf("ошибка обновления представления: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteView удаляет представление
+func (r *ViewRepository) DeleteView(ctx context.Context, id int64) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM saved_views WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления представления: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("представление с id=%d не найдено: %w", id, sql.ErrNoRows)
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package filterql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// field - поле, доступное в запросе
+// Колонки берутся только отсюда, значения всегда идут через плейсхолдеры
+type field struct {
+	kind valueKind
+	ops  string // допустимые операторы через пробел
+	// sql строит условие; arg выдаёт плейсхолдер для значения
+	sql func(op string, arg func(interface{}) string, value interface{}) string
+}
+
+const (
+	opsEquality = ": = !="
+	opsOrdered  = ": = != > >= < <="
+)
+
+var fields = map[string]field{
+	"category":    {kind: kindText, ops: opsEquality, sql: equalFold("category")},
+	"description": {kind: kindText, ops: opsEquality, sql: contains("description")},
+	"merchant":    {kind: kindText, ops: opsEquality, sql: contains("merchant")},
+	"tag":         {kind: kindText, ops: opsEquality, sql: tagCondition},
+	"amount":      {kind: kindNumber, ops: opsOrdered, sql: ordered("amount", "")},
+	"date":        {kind: kindDate, ops: opsOrdered, sql: ordered("date", "::date")},
+	"created_at":  {kind: kindTimestamp, ops: opsOrdered, sql: ordered("created_at", "::timestamptz")},
+}
+
+func (f field) allows(op string) bool {
+	for _, allowed := range strings.Fields(f.ops) {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (f field) parseValue(raw string) (interface{}, error) {
+	return f.kind.parse(raw)
+}
+
+func fieldNames() string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// Compile превращает дерево в SQL-условие для таблицы expenses
+// arg добавляет значение в аргументы запроса и возвращает плейсхолдер ($1, $2, ...),
+// так условие встраивается в запрос вместе с остальными фильтрами
+func Compile(node Node, arg func(interface{}) string) (string, error) {
+	switch n := node.(type) {
+	case *Binary:
+		left, err := Compile(n.Left, arg)
+		if err != nil {
+			return "", err
+		}
+		right, err := Compile(n.Right, arg)
+		if err != nil {
+			return "", err
+		}
+		return "(" + left + " " + n.Op + " " + right + ")", nil
+
+	case *Not:
+		expr, err := Compile(n.Expr, arg)
+		if err != nil {
+			return "", err
+		}
+		return "NOT " + expr, nil
+
+	case *Comparison:
+		f, ok := fields[n.Field]
+		if !ok {
+			return "", errorAt(n.Pos, "неизвестное поле %q", n.Field)
+		}
+		return "(" + f.sql(n.Op, arg, n.Value) + ")", nil
+	}
+
+	return "", fmt.Errorf("неизвестный узел фильтра: %T", node)
+}
+
+// equalFold - сравнение без учёта регистра: category:еда найдёт "Еда"
+func equalFold(column string) func(string, func(interface{}) string, interface{}) string {
+	return func(op string, arg func(interface{}) string, value interface{}) string {
+		cond := fmt.Sprintf("lower(%s) = lower(%s)", column, arg(value))
+		if op == "!=" {
+			return "NOT " + cond
+		}
+		return cond
+	}
+}
+
+// contains - для ":" ищем подстроку, для "=" и "!=" сравниваем целиком
+func contains(column string) func(string, func(interface{}) string, interface{}) string {
+	return func(op string, arg func(interface{}) string, value interface{}) string {
+		if op == ":" {
+			pattern := "%" + EscapeLike(strings.ToLower(value.(string))) + "%"
+			return fmt.Sprintf("lower(%s) LIKE %s", column, arg(pattern))
+		}
+		return equalFold(column)(op, arg, value)
+	}
+}
+
+func tagCondition(op string, arg func(interface{}) string, value interface{}) string {
+	cond := fmt.Sprintf("lower(%s) = ANY(SELECT lower(t) FROM unnest(tags) t)", arg(value))
+	if op == "!=" {
+		return "NOT " + cond
+	}
+	return cond
+}
+
+// ordered - числа и даты; ":" значит равенство
+func ordered(column, cast string) func(string, func(interface{}) string, interface{}) string {
+	return func(op string, arg func(interface{}) string, value interface{}) string {
+		if op == ":" {
+			op = "="
+		}
+		if op == "!=" {
+			op = "<>"
+		}
+		return fmt.Sprintf("%s %s %s%s", column, op, arg(value), cast)
+	}
+}
+
+// EscapeLike экранирует спецсимволы LIKE, чтобы "100%" искалось буквально
+// Общая для языка фильтров и для простых параметров списка в database
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
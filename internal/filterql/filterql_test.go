@@ -0,0 +1,118 @@
+package filterql
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// compile - разбор и компиляция с плейсхолдерами $1, $2, ...
+func compile(t *testing.T, input string) (string, []interface{}) {
+	t.Helper()
+
+	node, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", input, err)
+	}
+
+	var args []interface{}
+	sql, err := Compile(node, func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	})
+	if err != nil {
+		t.Fatalf("Compile(%q): %v", input, err)
+	}
+
+	return sql, args
+}
+
+func TestCompile_Precedence(t *testing.T) {
+	sql, args := compile(t, "category:food AND amount>500 AND date>=2026-01-01 OR tag:trip")
+
+	want := "((((lower(category) = lower($1)) AND (amount > $2)) AND (date >= $3::date)) OR " +
+		"(lower($4) = ANY(SELECT lower(t) FROM unnest(tags) t)))"
+	if sql != want {
+		t.Errorf("SQL:\n%s\nожидали:\n%s", sql, want)
+	}
+
+	if len(args) != 4 || args[0] != "food" || args[1] != 500.0 || args[3] != "trip" {
+		t.Errorf("Неожиданные аргументы: %v", args)
+	}
+}
+
+func TestCompile_ParensNotAndImplicitAnd(t *testing.T) {
+	sql, _ := compile(t, `NOT (merchant:"Пятёрочка" or tag:work) amount<=100`)
+
+	want := "(NOT ((lower(merchant) LIKE $1) OR (lower($2) = ANY(SELECT lower(t) FROM unnest(tags) t))) AND (amount <= $3))"
+	if sql != want {
+		t.Errorf("SQL:\n%s\nожидали:\n%s", sql, want)
+	}
+}
+
+func TestCompile_ValuesNeverInSQL(t *testing.T) {
+	sql, args := compile(t, `description:"'; DROP TABLE expenses; --"`)
+
+	if strings.Contains(sql, "DROP") {
+		t.Errorf("Значение попало в SQL: %s", sql)
+	}
+	if args[0] != "%'; drop table expenses; --%" {
+		t.Errorf("Неожиданный шаблон: %v", args[0])
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	if got := EscapeLike(`100%_a\b`); got != `100\%\_a\\b` {
+		t.Errorf("EscapeLike: получили %s", got)
+	}
+
+	_, args := compile(t, `description:"100%"`)
+	if args[0] != `%100\%%` {
+		t.Errorf("Неожиданный шаблон: %v", args[0])
+	}
+}
+
+func TestParse_ErrorPositions(t *testing.T) {
+	tests := []struct {
+		input string
+		pos   int
+	}{
+		{"", 1},
+		{"colour:red", 1},
+		{"amount>много", 8},
+		{"category>food", 9},
+		{"date>=2026-13-01", 7},
+		{"category:food AND", 18},
+		{"(amount>1 OR amount<0", 1},
+		{"amount>1)", 9},
+		{`описание:"хлеб`, 1},            // неизвестное поле на кириллице
+		{`category:"без кавычки`, 10},    // незакрытая кавычка
+		{"категория = еда OR amount", 1}, // позиция в символах, а не байтах
+		{"tag:trip amount", 16},
+		{"amount ! 5", 8},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.input)
+		var perr *Error
+		if !errors.As(err, &perr) {
+			t.Errorf("%q: ожидали *Error, получили %v", tt.input, err)
+			continue
+		}
+		if perr.Pos != tt.pos {
+			t.Errorf("%q: позиция %d, ожидали %d (%v)", tt.input, perr.Pos, tt.pos, perr)
+		}
+	}
+}
+
+func TestParse_Limits(t *testing.T) {
+	if _, err := Parse(strings.Repeat("a", MaxLength+1)); err == nil {
+		t.Error("Слишком длинный фильтр должен отклоняться")
+	}
+
+	deep := strings.Repeat("(", maxDepth+1) + "amount>1" + strings.Repeat(")", maxDepth+1)
+	if _, err := Parse(deep); err == nil {
+		t.Error("Слишком глубокая вложенность должна отклоняться")
+	}
+}
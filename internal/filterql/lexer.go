@@ -0,0 +1,176 @@
+package filterql
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenKind - вид токена
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString // значение в кавычках
+	tokOp     // : = != > >= < <=
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokEOF:
+		return "конец запроса"
+	case tokWord:
+		return "слово"
+	case tokString:
+		return "строка"
+	case tokOp:
+		return "оператор"
+	case tokLParen:
+		return "\"(\""
+	case tokRParen:
+		return "\")\""
+	case tokAnd:
+		return "AND"
+	case tokOr:
+		return "OR"
+	case tokNot:
+		return "NOT"
+	default:
+		return "?"
+	}
+}
+
+// token - лексема с позицией (номер символа с 1, а не байта)
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// lexer разбивает запрос на токены
+// Позиции считаем в символах: в запросе бывает кириллица,
+// и номер байта пользователю ничего бы не сказал
+type lexer struct {
+	input  string
+	offset int // в байтах
+	pos    int // в символах, с 1
+}
+
+func newLexer(input string) *lexer {
+	return &lexer{input: input, pos: 1}
+}
+
+func (l *lexer) next() (token, error) {
+	l.skipSpaces()
+
+	if l.offset >= len(l.input) {
+		return token{kind: tokEOF, pos: l.pos}, nil
+	}
+
+	start := l.pos
+	r, _ := utf8.DecodeRuneInString(l.input[l.offset:])
+
+	switch {
+	case r == '(':
+		l.advance()
+		return token{kind: tokLParen, text: "(", pos: start}, nil
+	case r == ')':
+		l.advance()
+		return token{kind: tokRParen, text: ")", pos: start}, nil
+	case r == '"':
+		return l.readString()
+	case strings.ContainsRune(":=!<>", r):
+		return l.readOperator()
+	default:
+		return l.readWord(), nil
+	}
+}
+
+func (l *lexer) readString() (token, error) {
+	start := l.pos
+	l.advance() // открывающая кавычка
+
+	var sb strings.Builder
+	for l.offset < len(l.input) {
+		r := l.advance()
+		switch r {
+		case '"':
+			return token{kind: tokString, text: sb.String(), pos: start}, nil
+		case '\\':
+			if l.offset < len(l.input) {
+				sb.WriteRune(l.advance())
+			}
+		default:
+			sb.WriteRune(r)
+		}
+	}
+
+	return token{}, errorAt(start, "незакрытая кавычка")
+}
+
+func (l *lexer) readOperator() (token, error) {
+	start := l.pos
+	first := l.advance()
+
+	// Двухсимвольные операторы: != >= <=
+	if l.offset < len(l.input) && l.input[l.offset] == '=' && first != ':' && first != '=' {
+		l.advance()
+		return token{kind: tokOp, text: string(first) + "=", pos: start}, nil
+	}
+
+	if first == '!' {
+		return token{}, errorAt(start, "ожидали \"!=\"")
+	}
+
+	return token{kind: tokOp, text: string(first), pos: start}, nil
+}
+
+// readWord читает слово до пробела, скобки или оператора
+// Ключевые слова AND, OR, NOT - без учёта регистра
+func (l *lexer) readWord() token {
+	start := l.pos
+	begin := l.offset
+
+	for l.offset < len(l.input) {
+		r, _ := utf8.DecodeRuneInString(l.input[l.offset:])
+		if unicode.IsSpace(r) || strings.ContainsRune("():=!<>\"", r) {
+			break
+		}
+		l.advance()
+	}
+
+	text := l.input[begin:l.offset]
+	switch strings.ToUpper(text) {
+	case "AND":
+		return token{kind: tokAnd, text: text, pos: start}
+	case "OR":
+		return token{kind: tokOr, text: text, pos: start}
+	case "NOT":
+		return token{kind: tokNot, text: text, pos: start}
+	}
+
+	return token{kind: tokWord, text: text, pos: start}
+}
+
+func (l *lexer) skipSpaces() {
+	for l.offset < len(l.input) {
+		r, _ := utf8.DecodeRuneInString(l.input[l.offset:])
+		if !unicode.IsSpace(r) {
+			return
+		}
+		l.advance()
+	}
+}
+
+func (l *lexer) advance() rune {
+	r, size := utf8.DecodeRuneInString(l.input[l.offset:])
+	l.offset += size
+	l.pos++
+	return r
+}
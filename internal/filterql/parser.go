@@ -0,0 +1,286 @@
+// Package filterql - маленький язык запросов для фильтрации расходов:
+//
+//	category:food AND amount>500 AND date>=2026-01-01 OR tag:trip
+//
+// AND связывает сильнее OR, NOT отрицает следующее условие, скобки
+// группируют. Между условиями без оператора подразумевается AND
+package filterql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ограничения, чтобы один запрос не съел парсер
+const (
+	MaxLength = 1000
+	maxDepth  = 32
+)
+
+// Error - ошибка разбора с номером символа, где что-то не так
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("ошибка в фильтре, позиция %d: %s", e.Pos, e.Msg)
+}
+
+func errorAt(pos int, format string, args ...interface{}) *Error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Node - узел дерева запроса
+type Node interface {
+	node()
+}
+
+// Binary - AND или OR
+type Binary struct {
+	Op          string
+	Left, Right Node
+}
+
+// Not - отрицание условия
+type Not struct {
+	Expr Node
+}
+
+// Comparison - одно условие "поле оператор значение"
+// Value уже проверено и приведено к типу поля (float64, time.Time или string)
+type Comparison struct {
+	Field string
+	Op    string
+	Value interface{}
+	Pos   int
+}
+
+func (*Binary) node()     {}
+func (*Not) node()        {}
+func (*Comparison) node() {}
+
+// Parse разбирает запрос в дерево
+// Поля и значения проверяются сразу, чтобы ошибка указывала на место в запросе
+func Parse(input string) (Node, error) {
+	if len([]rune(input)) > MaxLength {
+		return nil, errorAt(MaxLength+1, "слишком длинный фильтр (максимум %d символов)", MaxLength)
+	}
+
+	p := &parser{lex: newLexer(input)}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind == tokEOF {
+		return nil, errorAt(p.tok.pos, "пустой фильтр")
+	}
+
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind != tokEOF {
+		return nil, p.unexpected("AND, OR или конец запроса")
+	}
+
+	return node, nil
+}
+
+type parser struct {
+	lex   *lexer
+	tok   token
+	depth int
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) parseOr() (Node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.tok.kind == tokOr {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &Binary{Op: "OR", Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch p.tok.kind {
+		case tokAnd:
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		case tokWord, tokNot, tokLParen:
+			// "category:food amount>500" - то же, что с AND
+		default:
+			return left, nil
+		}
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &Binary{Op: "AND", Left: left, Right: right}
+	}
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+
+	if p.depth > maxDepth {
+		return nil, errorAt(p.tok.pos, "слишком глубокая вложенность (максимум %d)", maxDepth)
+	}
+
+	switch p.tok.kind {
+	case tokNot:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Expr: expr}, nil
+
+	case tokLParen:
+		open := p.tok.pos
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			if p.tok.kind == tokEOF {
+				return nil, errorAt(open, "скобка не закрыта")
+			}
+			return nil, p.unexpected("\")\"")
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		return expr, nil
+
+	case tokWord:
+		return p.parseComparison()
+	}
+
+	return nil, p.unexpected("условие")
+}
+
+func (p *parser) parseComparison() (Node, error) {
+	fieldTok := p.tok
+	name := strings.ToLower(fieldTok.text)
+	f, ok := fields[name]
+	if !ok {
+		return nil, errorAt(fieldTok.pos, "неизвестное поле %q, допустимо: %s", fieldTok.text, fieldNames())
+	}
+
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokOp {
+		return nil, p.unexpected("оператор после " + name)
+	}
+	opTok := p.tok
+	if !f.allows(opTok.text) {
+		return nil, errorAt(opTok.pos, "оператор %s не подходит для поля %s", opTok.text, name)
+	}
+
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokWord && p.tok.kind != tokString {
+		return nil, p.unexpected("значение для " + name)
+	}
+	valTok := p.tok
+
+	value, err := f.parseValue(valTok.text)
+	if err != nil {
+		return nil, errorAt(valTok.pos, "%s: %v", name, err)
+	}
+
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	return &Comparison{Field: name, Op: opTok.text, Value: value, Pos: fieldTok.pos}, nil
+}
+
+// unexpected - ошибка "ожидали X, а встретили Y" в позиции текущего токена
+func (p *parser) unexpected(want string) error {
+	if p.tok.kind == tokEOF {
+		return errorAt(p.tok.pos, "ожидали %s, а запрос закончился", want)
+	}
+	return errorAt(p.tok.pos, "ожидали %s, а встретили %q", want, p.tok.text)
+}
+
+// valueKind - тип значения поля
+type valueKind int
+
+const (
+	kindText valueKind = iota
+	kindNumber
+	kindDate
+	kindTimestamp
+)
+
+func (k valueKind) parse(raw string) (interface{}, error) {
+	switch k {
+	case kindNumber:
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q - не число", raw)
+		}
+		return v, nil
+	case kindDate:
+		v, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q - не дата, нужен формат YYYY-MM-DD", raw)
+		}
+		return v, nil
+	case kindTimestamp:
+		if v, err := time.Parse(time.RFC3339, raw); err == nil {
+			return v, nil
+		}
+		v, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q - не дата, нужен формат YYYY-MM-DD или RFC3339", raw)
+		}
+		return v, nil
+	default:
+		if raw == "" {
+			return nil, fmt.Errorf("пустое значение")
+		}
+		return raw, nil
+	}
+}
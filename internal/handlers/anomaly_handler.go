@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+	"github.com/dvoryadkinadv/expense-tracker/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// AnomalyHandler отдаёт список необычных расходов
+type AnomalyHandler struct {
+	service *service.AnomalyService
+}
+
+// NewAnomalyHandler создаёт новый хэндлер
+func NewAnomalyHandler(s *service.AnomalyService) *AnomalyHandler {
+	return &AnomalyHandler{service: s}
+}
+
+// GetAnomalies возвращает необычные расходы за период
+func (h *AnomalyHandler) GetAnomalies(c *gin.Context) {
+	filter := models.AnomalyFilter{
+		Category: c.Query("category"),
+		DateFrom: c.Query("date_from"),
+		DateTo:   c.Query("date_to"),
+	}
+
+	if thresholdStr := c.Query("threshold"); thresholdStr != "" {
+		threshold, err := strconv.ParseFloat(thresholdStr, 64)
+		if err != nil || threshold <= 0 {
+			respondError(c, service.Invalid("threshold", "нужно положительное число"))
+			return
+		}
+		filter.Threshold = threshold
+	}
+
+	anomalies, err := h.service.List(c.Request.Context(), filter)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    anomalies,
+	})
+}
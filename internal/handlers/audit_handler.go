@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+	"github.com/dvoryadkinadv/expense-tracker/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditHandler отдаёт журнал изменений расходов
+type AuditHandler struct {
+	service *service.AuditService
+}
+
+// NewAuditHandler создаёт новый хэндлер
+func NewAuditHandler(s *service.AuditService) *AuditHandler {
+	return &AuditHandler{service: s}
+}
+
+// GetHistory возвращает историю одного расхода, от старых изменений к новым
+// Работает и для удалённых насовсем: журнал их переживает
+func (h *AuditHandler) GetHistory(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondInvalidID(c)
+		return
+	}
+
+	entries, err := h.service.History(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    entries,
+	})
+}
+
+// GetAudit возвращает ленту изменений, новые сверху
+//
+//	?actor=anna&action=update&expense_id=5&from=2024-01-01&to=2024-02-01&limit=50&offset=0
+func (h *AuditHandler) GetAudit(c *gin.Context) {
+	filter := models.AuditFilter{
+		Actor:  c.Query("actor"),
+		Action: models.AuditAction(c.Query("action")),
+		From:   c.Query("from"),
+		To:     c.Query("to"),
+	}
+
+	if idStr := c.Query("expense_id"); idStr != "" {
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			respondError(c, service.Invalid("expense_id", "нужен ID расхода"))
+			return
+		}
+		filter.ExpenseID = id
+	}
+
+	filter.Limit, _ = strconv.Atoi(c.Query("limit"))
+	filter.Offset, _ = strconv.Atoi(c.Query("offset"))
+
+	entries, err := h.service.List(c.Request.Context(), filter)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    entries,
+	})
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+	"github.com/dvoryadkinadv/expense-tracker/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// AutocompleteHandler отдаёт подсказки для формы ввода
+type AutocompleteHandler struct {
+	service *service.AutocompleteService
+}
+
+// NewAutocompleteHandler создаёт новый хэндлер
+func NewAutocompleteHandler(s *service.AutocompleteService) *AutocompleteHandler {
+	return &AutocompleteHandler{service: s}
+}
+
+// Suggest возвращает подсказки по началу ввода
+func (h *AutocompleteHandler) Suggest(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	field := models.SuggestField(c.Query("field"))
+
+	suggestions, err := h.service.Suggest(c.Request.Context(), field, c.Query("q"), limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    suggestions,
+	})
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORSPolicy - каким сайтам и с чем браузер может обращаться к API
+type CORSPolicy struct {
+	// AllowedOrigins - точные источники (https://app.example.com),
+	// поддомены (https://*.example.com) или "*" - любой сайт, но без credentials
+	AllowedOrigins []string
+	// AllowCredentials - разрешить cookies и заголовок Authorization из браузера
+	AllowCredentials bool
+	AllowedMethods   []string
+	// AllowedHeaders - заголовки запроса; "*" - любые, которые спросит браузер
+	AllowedHeaders []string
+	// ExposedHeaders - заголовки ответа, которые увидит JavaScript
+	ExposedHeaders []string
+	// MaxAge - сколько браузер может не повторять preflight
+	MaxAge time.Duration
+}
+
+// originMatcher - разобранный список AllowedOrigins
+type originMatcher struct {
+	any      bool
+	exact    map[string]bool
+	suffixes [][2]string // схема:// и .домен[:порт] для https://*.домен[:порт]
+}
+
+func newOriginMatcher(origins []string) originMatcher {
+	m := originMatcher{exact: make(map[string]bool)}
+	for _, origin := range origins {
+		origin = strings.TrimSuffix(strings.ToLower(origin), "/")
+		switch {
+		case origin == "*":
+			m.any = true
+		case strings.Contains(origin, "://*."):
+			scheme, domain, _ := strings.Cut(origin, "*")
+			m.suffixes = append(m.suffixes, [2]string{scheme, domain})
+		default:
+			m.exact[origin] = true
+		}
+	}
+	return m
+}
+
+// allowed - можно ли источнику обращаться к API
+// Поддомен должен быть непустым: https://*.example.com не пускает сам https://example.com
+func (m originMatcher) allowed(origin string) bool {
+	origin = strings.ToLower(origin)
+	if m.any || m.exact[origin] {
+		return true
+	}
+	for _, s := range m.suffixes {
+		scheme, domain := s[0], s[1]
+		if !strings.HasPrefix(origin, scheme) || !strings.HasSuffix(origin, domain) {
+			continue
+		}
+		sub := origin[len(scheme) : len(origin)-len(domain)]
+		if sub != "" && strings.Trim(sub, "abcdefghijklmnopqrstuvwxyz0123456789-.") == "" &&
+			!strings.HasPrefix(sub, ".") && !strings.HasSuffix(sub, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// CORS отвечает на preflight и добавляет заголовки CORS к ответам разрешённым источникам
+// Запросы без Origin (не из браузера или с того же сайта) проходят как обычно.
+// Чужим источникам заголовки не ставятся, и браузер не отдаст им ответ
+func CORS(policy CORSPolicy) gin.HandlerFunc {
+	origins := newOriginMatcher(policy.AllowedOrigins)
+	methods := strings.Join(policy.AllowedMethods, ", ")
+	headers := strings.Join(policy.AllowedHeaders, ", ")
+	exposed := strings.Join(policy.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(policy.MaxAge.Seconds()))
+
+	anyHeader := false
+	for _, h := range policy.AllowedHeaders {
+		anyHeader = anyHeader || h == "*"
+	}
+
+	// С "*" и без credentials ответ для всех источников одинаковый,
+	// иначе Access-Control-Allow-Origin зависит от Origin, и кешам нужен Vary
+	varies := !origins.any || policy.AllowCredentials
+
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		if varies {
+			header.Add("Vary", "Origin")
+		}
+
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if preflight {
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		if !origins.allowed(origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if origins.any && !policy.AllowCredentials {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if policy.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if exposed != "" {
+				header.Set("Access-Control-Expose-Headers", exposed)
+			}
+			c.Next()
+			return
+		}
+
+		header.Set("Access-Control-Allow-Methods", methods)
+		if anyHeader {
+			if requested := c.GetHeader("Access-Control-Request-Headers"); requested != "" {
+				header.Set("Access-Control-Allow-Headers", requested)
+			}
+		} else if headers != "" {
+			header.Set("Access-Control-Allow-Headers", headers)
+		}
+		if policy.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", maxAge)
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupCORSRouter(policy CORSPolicy) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(CORS(policy))
+	router.GET("/api/things", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.PATCH("/api/things", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func corsRequest(router *gin.Engine, method, origin string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/api/things", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func preflight(router *gin.Engine, origin string) *httptest.ResponseRecorder {
+	return corsRequest(router, http.MethodOptions, origin, map[string]string{
+		"Access-Control-Request-Method":  http.MethodPatch,
+		"Access-Control-Request-Headers": "content-type, if-match",
+	})
+}
+
+var testPolicy = CORSPolicy{
+	AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+	AllowCredentials: true,
+	AllowedMethods:   []string{"GET", "POST", "PATCH"},
+	AllowedHeaders:   []string{"Content-Type", "If-Match"},
+	ExposedHeaders:   []string{"ETag", "X-Request-ID"},
+	MaxAge:           10 * time.Minute,
+}
+
+func TestCORS_Origins(t *testing.T) {
+	router := setupCORSRouter(testPolicy)
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://app.example.com", true},
+		{"https://APP.example.com", true},
+		{"http://app.example.com", false},
+		{"https://evil.example.com", false},
+		{"https://shop.example.org", true},
+		{"https://a.b.example.org", true},
+		{"https://example.org", false},
+		{"https://evilexample.org", false},
+		{"https://example.org.evil.com", false},
+		{"null", false},
+	}
+
+	for _, tt := range tests {
+		w := corsRequest(router, http.MethodGet, tt.origin, nil)
+		got := w.Header().Get("Access-Control-Allow-Origin")
+		if tt.allowed && got != tt.origin {
+			t.Errorf("%s: ожидали Access-Control-Allow-Origin с этим источником, получили %q", tt.origin, got)
+		}
+		if !tt.allowed && got != "" {
+			t.Errorf("%s: чужому источнику заголовок не нужен, получили %q", tt.origin, got)
+		}
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: обычный запрос всё равно обрабатывается, получили %d", tt.origin, w.Code)
+		}
+	}
+}
+
+func TestCORS_ActualRequest(t *testing.T) {
+	router := setupCORSRouter(testPolicy)
+
+	w := corsRequest(router, http.MethodGet, "https://app.example.com", nil)
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("Access-Control-Allow-Credentials: ожидали true, получили %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Expose-Headers"); got != "ETag, X-Request-ID" {
+		t.Errorf("Access-Control-Expose-Headers: получили %q", got)
+	}
+	if !slices.Contains(w.Header().Values("Vary"), "Origin") {
+		t.Errorf("Ответ зависит от Origin, нужен Vary: Origin, получили %v", w.Header().Values("Vary"))
+	}
+
+	// Без Origin - не CORS, но Vary всё равно нужен: кеш не должен отдать этот ответ сайту
+	w = corsRequest(router, http.MethodGet, "", nil)
+	if w.Header().Get("Access-Control-Allow-Origin") != "" || !slices.Contains(w.Header().Values("Vary"), "Origin") {
+		t.Errorf("Запрос без Origin: неожиданные заголовки %v", w.Header())
+	}
+}
+
+func TestCORS_Preflight(t *testing.T) {
+	router := setupCORSRouter(testPolicy)
+
+	w := preflight(router, "https://shop.example.org")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Preflight: ожидали 204, получили %d", w.Code)
+	}
+	want := map[string]string{
+		"Access-Control-Allow-Origin":      "https://shop.example.org",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Methods":     "GET, POST, PATCH",
+		"Access-Control-Allow-Headers":     "Content-Type, If-Match",
+		"Access-Control-Max-Age":           "600",
+	}
+	for key, value := range want {
+		if got := w.Header().Get(key); got != value {
+			t.Errorf("%s: ожидали %q, получили %q", key, value, got)
+		}
+	}
+	for _, vary := range []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"} {
+		if !slices.Contains(w.Header().Values("Vary"), vary) {
+			t.Errorf("Нет Vary: %s, получили %v", vary, w.Header().Values("Vary"))
+		}
+	}
+
+	if w := preflight(router, "https://evil.com"); w.Code != http.StatusForbidden || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Preflight чужого источника: ожидали 403 без заголовков, получили %d %v", w.Code, w.Header())
+	}
+}
+
+func TestCORS_Wildcard(t *testing.T) {
+	router := setupCORSRouter(CORSPolicy{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{"GET"},
+		AllowedHeaders: []string{"*"},
+	})
+
+	w := preflight(router, "https://anything.test")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Ожидали *, получили %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Headers"); got != "content-type, if-match" {
+		t.Errorf("С * в заголовках разрешаются запрошенные, получили %q", got)
+	}
+	if w.Header().Get("Access-Control-Allow-Credentials") != "" || w.Header().Get("Access-Control-Max-Age") != "" {
+		t.Errorf("Без credentials и max_age лишние заголовки: %v", w.Header())
+	}
+
+	w = corsRequest(router, http.MethodGet, "https://anything.test", nil)
+	if slices.Contains(w.Header().Values("Vary"), "Origin") {
+		t.Error("Ответ с * одинаков для всех источников, Vary: Origin не нужен")
+	}
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/logging"
+	"github.com/dvoryadkinadv/expense-tracker/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// ProblemContentType - тип ответа с ошибкой (RFC 7807)
+const ProblemContentType = "application/problem+json"
+
+// internalDetail - что видит клиент вместо текста внутренней ошибки
+// Сам текст (там бывают SQL и адреса) уходит только в лог
+const internalDetail = "Внутренняя ошибка сервера"
+
+// Problem - ответ с ошибкой в формате RFC 7807 (problem+json)
+// Errors - разбор по полям для ошибок проверки, Result - итог пакетной операции
+type Problem struct {
+	Type      string               `json:"type"`
+	Title     string               `json:"title"`
+	Status    int                  `json:"status"`
+	Detail    string               `json:"detail,omitempty"`
+	Instance  string               `json:"instance,omitempty"`
+	RequestID string               `json:"request_id,omitempty"`
+	Errors    []service.FieldError `json:"errors,omitempty"`
+	Result    interface{}          `json:"result,omitempty"`
+}
+
+// errorStatus - код ответа для ошибки сервиса
+// Единственное место, где виды ошибок превращаются в коды HTTP
+func errorStatus(err error) int {
+	switch {
+	// Частные случаи - раньше общих видов, к которым они относятся
+	case errors.Is(err, service.ErrVersionConflict):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, service.ErrIdempotencyKeyReused):
+		return http.StatusUnprocessableEntity
+
+	case errors.Is(err, service.ErrInternal):
+		return http.StatusInternalServerError
+	case errors.Is(err, service.ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrConflict):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// respondError отвечает problem+json с кодом по виду ошибки
+func respondError(c *gin.Context, err error) {
+	writeProblem(c, errorProblem(c, err))
+}
+
+// errorProblem собирает ответ для ошибки сервиса
+// Текст внутренних ошибок клиенту не показываем: он попадает в журнал запроса через c.Error
+func errorProblem(c *gin.Context, err error) Problem {
+	problem := newProblem(c, errorStatus(err), err.Error())
+
+	if problem.Status >= http.StatusInternalServerError {
+		c.Error(err)
+		problem.Detail = internalDetail
+	}
+
+	var invalid *service.ValidationError
+	if errors.As(err, &invalid) {
+		problem.Errors = invalid.Fields
+	}
+
+	return problem
+}
+
+// respondProblem отвечает problem+json с заданным кодом и текстом
+// Для ошибок, которые находит сам хендлер: неверный ID, не тот Content-Type и т.п.
+func respondProblem(c *gin.Context, status int, detail string) {
+	writeProblem(c, newProblem(c, status, detail))
+}
+
+func newProblem(c *gin.Context, status int, detail string) Problem {
+	return Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  c.Request.URL.Path,
+		RequestID: logging.RequestID(c.Request.Context()),
+	}
+}
+
+// writeProblem пишет ответ и прерывает цепочку, чтобы годилось и для middleware
+func writeProblem(c *gin.Context, problem Problem) {
+	// Gin не перезаписывает Content-Type, если он уже выставлен
+	c.Header("Content-Type", ProblemContentType)
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
+
+// respondInvalidID - в пути вместо ID не число
+func respondInvalidID(c *gin.Context) {
+	respondError(c, service.Invalid("id", "Неверный ID"))
+}
+
+// bindError переводит ошибку ShouldBindJSON в ValidationError с разбором по полям
+// Имена полей берутся из тегов json у obj - те же, что клиент прислал в теле
+func bindError(err error, obj interface{}) error {
+	var (
+		invalid   validator.ValidationErrors
+		typeErr   *json.UnmarshalTypeError
+		syntaxErr *json.SyntaxError
+	)
+
+	switch {
+	case errors.As(err, &invalid):
+		fields := make([]service.FieldError, 0, len(invalid))
+		for _, fe := range invalid {
+			fields = append(fields, service.FieldError{
+				Field:   jsonPath(reflect.TypeOf(obj), fe.StructNamespace()),
+				Message: validationMessage(fe),
+			})
+		}
+		return &service.ValidationError{Fields: fields}
+
+	case errors.As(err, &typeErr):
+		return service.Invalid(typeErr.Field, "ожидается "+jsonTypeName(typeErr.Type))
+
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return service.Invalid("body", "тело запроса - не JSON")
+
+	case errors.Is(err, io.EOF):
+		return service.Invalid("body", "пустое тело запроса")
+
+	default:
+		return service.Invalid("body", err.Error())
+	}
+}
+
+// jsonPath превращает путь валидатора (BatchRequest.Operations[0].Op) в путь по JSON (operations[0].op)
+func jsonPath(t reflect.Type, namespace string) string {
+	parts := strings.Split(namespace, ".")[1:] // первое - имя типа запроса
+	path := make([]string, 0, len(parts))
+
+	for _, part := range parts {
+		name, index, _ := strings.Cut(part, "[")
+		if index != "" {
+			index = "[" + index
+		}
+
+		for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			path = append(path, name+index)
+			continue
+		}
+
+		field, ok := t.FieldByName(name)
+		if !ok {
+			path = append(path, name+index)
+			t = nil
+			continue
+		}
+		if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag != "" && tag != "-" {
+			name = tag
+		}
+		path = append(path, name+index)
+		t = field.Type
+	}
+
+	return strings.Join(path, ".")
+}
+
+// validationMessage - понятный текст для нарушенного тега binding
+func validationMessage(fe validator.FieldError) string {
+	var unit string
+	switch fe.Kind() {
+	case reflect.String:
+		unit = " символов"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = " элементов"
+	}
+
+	switch fe.Tag() {
+	case "required":
+		return "обязательное поле"
+	case "gt":
+		return "должно быть больше " + fe.Param()
+	case "gte":
+		return "должно быть не меньше " + fe.Param()
+	case "min":
+		if fe.Kind() == reflect.String && fe.Param() == "1" {
+			return "не может быть пустым"
+		}
+		return "не меньше " + fe.Param() + unit
+	case "max":
+		return "не больше " + fe.Param() + unit
+	case "oneof":
+		return "допустимо: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	default:
+		return fmt.Sprintf("не проходит проверку %s", fe.Tag())
+	}
+}
+
+// jsonTypeName - тип Go глазами клиента, приславшего JSON
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "целое число"
+	case reflect.Float32, reflect.Float64:
+		return "число"
+	case reflect.Bool:
+		return "true или false"
+	case reflect.String:
+		return "строка"
+	case reflect.Slice, reflect.Array:
+		return "массив"
+	default:
+		return "объект"
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+	"github.com/dvoryadkinadv/expense-tracker/internal/service"
+)
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{service.Invalid("amount", "должно быть больше 0"), http.StatusBadRequest},
+		{fmt.Errorf("%w: id=1", service.ErrViewNotFound), http.StatusNotFound},
+		{&service.NotFoundError{Resource: "expense", ID: 1}, http.StatusNotFound},
+		{service.ErrViewForbidden, http.StatusForbidden},
+		{service.ErrBatchAborted, http.StatusConflict},
+		{fmt.Errorf("%w: id=1", service.ErrVersionConflict), http.StatusPreconditionFailed},
+		{service.ErrIdempotencyKeyReused, http.StatusUnprocessableEntity},
+		{fmt.Errorf("%w: подсказки категорий не включены", service.ErrInternal), http.StatusInternalServerError},
+		{errors.New("pq: connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		if got := errorStatus(tt.err); got != tt.want {
+			t.Errorf("%v: ожидали %d, получили %d", tt.err, tt.want, got)
+		}
+	}
+}
+
+func TestJSONPath(t *testing.T) {
+	tests := []struct {
+		namespace string
+		want      string
+	}{
+		{"BatchRequest.Operations", "operations"},
+		{"BatchRequest.Operations[2].Op", "operations[2].op"},
+		{"BatchRequest.Operations[0].Expense.Tags[1]", "operations[0].expense.tags[1]"},
+	}
+
+	for _, tt := range tests {
+		if got := jsonPath(reflect.TypeOf(&models.BatchRequest{}), tt.namespace); got != tt.want {
+			t.Errorf("%s: ожидали %s, получили %s", tt.namespace, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// errBadIfMatch - в If-Match пришло не то, что мы выдавали в ETag
+var errBadIfMatch = service.Invalid("If-Match", "ожидается ETag расхода или *")
+
+// expenseETag - сильный ETag расхода, это просто его версия
+func expenseETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseIfMatch возвращает версию из If-Match
+// 0 - заголовка нет или там *, то есть версию проверять не нужно
+func parseIfMatch(c *gin.Context) (int, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	// Слабые ETag для If-Match не годятся, а несколько версий сразу
+	// нам не передать в одно обновление
+	if strings.HasPrefix(header, "W/") || strings.Contains(header, ",") {
+		return 0, errBadIfMatch
+	}
+
+	version, err := strconv.Atoi(strings.Trim(header, `"`))
+	if err != nil || version <= 0 {
+		return 0, errBadIfMatch
+	}
+	return version, nil
+}
+
+// notModified проверяет If-None-Match против etag
+// Сравнение слабое, как и положено для GET: W/"x" совпадает с "x"
+func notModified(c *gin.Context, etag string) bool {
+	header := c.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == want {
+			return true
+		}
+	}
+	return false
+}
+
+// respondWithETag отдаёт ответ с ETag или 304, если у клиента он уже есть
+// etag == "" - посчитать слабый ETag по телу ответа (для списков и статистики)
+func respondWithETag(c *gin.Context, etag string, body APIResponse) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if etag == "" {
+		sum := sha256.Sum256(data)
+		etag = `W/"` + hex.EncodeToString(sum[:8]) + `"`
+	}
+
+	c.Header("ETag", etag)
+	if notModified(c, etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
@@ -19,13 +19,21 @@ func NewExpenseHandler(s *service.ExpenseService) *ExpenseHandler {
 	return &ExpenseHandler{service: s}
 }
 
-// APIResponse - стандартный формат ответа API
-// Всегда возвращаем структуру с полями success и data/error
-// Так фронтенду проще обрабатывать ответы
+// APIResponse - стандартный формат успешного ответа API
+// Ошибки отдаются отдельно, в формате problem+json (см. Problem)
 type APIResponse struct {
-	Success bool        `json:"success"`
-	Data    interface{} `json:"data,omitempty"`
-	Error   string      `json:"error,omitempty"`
+	Success    bool        `json:"success"`
+	Data       interface{} `json:"data,omitempty"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+// Pagination - информация о страницах для списков
+// Курсоры передаются обратно в ?cursor=, total_count - только с ?with_total=true
+type Pagination struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	TotalCount *int   `json:"total_count,omitempty"`
 }
 
 // CreateExpense создаёт новый расход
@@ -34,19 +42,13 @@ func (h *ExpenseHandler) CreateExpense(c *gin.Context) {
 
 	// Gin сам проверит валидацию по тегам binding
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, APIResponse{
-			Success: false,
-			Error:   "Неверные данные: " + err.Error(),
-		})
+		respondError(c, bindError(err, &req))
 		return
 	}
 
 	expense, err := h.service.CreateExpense(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respondError(c, err)
 		return
 	}
 
@@ -57,26 +59,21 @@ func (h *ExpenseHandler) CreateExpense(c *gin.Context) {
 }
 
 // GetExpense возвращает расход по ID
+// В ETag - версия расхода, её потом можно передать в If-Match
 func (h *ExpenseHandler) GetExpense(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, APIResponse{
-			Success: false,
-			Error:   "Неверный ID",
-		})
+		respondInvalidID(c)
 		return
 	}
 
 	expense, err := h.service.GetExpense(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, APIResponse{
+	respondWithETag(c, expenseETag(expense.Version), APIResponse{
 		Success: true,
 		Data:    expense,
 	})
@@ -84,112 +81,158 @@ func (h *ExpenseHandler) GetExpense(c *gin.Context) {
 
 // GetExpenses возвращает список расходов с фильтрацией
 func (h *ExpenseHandler) GetExpenses(c *gin.Context) {
-	filter := models.ExpenseFilter{
-		Category: c.Query("category"),
-		DateFrom: c.Query("date_from"),
-		DateTo:   c.Query("date_to"),
+	filter, err := parseExpenseFilter(c)
+	if err != nil {
+		respondError(c, err)
+		return
 	}
 
-	// Парсим limit и offset
-	if limitStr := c.Query("limit"); limitStr != "" {
-		if limit, err := strconv.Atoi(limitStr); err == nil {
-			filter.Limit = limit
-		}
+	page, err := h.service.GetExpenses(c.Request.Context(), filter)
+	if err != nil {
+		respondError(c, err)
+		return
 	}
 
-	if offsetStr := c.Query("offset"); offsetStr != "" {
-		if offset, err := strconv.Atoi(offsetStr); err == nil {
-			filter.Offset = offset
-		}
+	respondWithETag(c, "", APIResponse{
+		Success: true,
+		Data:    page.Items,
+		Pagination: &Pagination{
+			Limit:      page.Limit,
+			NextCursor: page.NextCursor,
+			PrevCursor: page.PrevCursor,
+			TotalCount: page.TotalCount,
+		},
+	})
+}
+
+// ReplaceExpense заменяет расход целиком (PUT)
+// Необязательные поля, которых нет в запросе, очищаются; частичное изменение - PATCH.
+// С If-Match (или version в теле) расход, изменённый кем-то ещё, не перезаписывается - 412
+func (h *ExpenseHandler) ReplaceExpense(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondInvalidID(c)
+		return
 	}
 
-	expenses, err := h.service.GetExpenses(c.Request.Context(), filter)
+	version, err := parseIfMatch(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respondError(c, err)
 		return
 	}
 
+	var req models.ReplaceExpenseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err, &req))
+		return
+	}
+
+	// Заголовок важнее версии из тела
+	if version > 0 {
+		req.Version = &version
+	}
+
+	expense, err := h.service.ReplaceExpense(c.Request.Context(), id, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Header("ETag", expenseETag(expense.Version))
 	c.JSON(http.StatusOK, APIResponse{
 		Success: true,
-		Data:    expenses,
+		Data:    expense,
 	})
 }
 
-// UpdateExpense обновляет расход
-func (h *ExpenseHandler) UpdateExpense(c *gin.Context) {
+// PatchExpense частично изменяет расход (PATCH)
+// Формат - по Content-Type: application/merge-patch+json (null очищает поле)
+// или application/json-patch+json (список операций). Понимает If-Match
+func (h *ExpenseHandler) PatchExpense(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, APIResponse{
-			Success: false,
-			Error:   "Неверный ID",
-		})
+		respondInvalidID(c)
 		return
 	}
 
-	var req models.UpdateExpenseRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, APIResponse{
-			Success: false,
-			Error:   "Неверные данные: " + err.Error(),
-		})
+	var format service.PatchFormat
+	switch c.ContentType() {
+	case "application/merge-patch+json":
+		format = service.MergePatch
+	case "application/json-patch+json":
+		format = service.JSONPatch
+	default:
+		c.Header("Accept-Patch", "application/merge-patch+json, application/json-patch+json")
+		respondProblem(c, http.StatusUnsupportedMediaType, "Ожидается Content-Type application/merge-patch+json или application/json-patch+json")
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	expense, err := h.service.UpdateExpense(c.Request.Context(), id, req)
+	patch, ok := readBody(c)
+	if !ok {
+		return
+	}
+
+	expense, err := h.service.PatchExpense(c.Request.Context(), id, format, patch, version)
 	if err != nil {
-		c.JSON(http.StatusNotFound, APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respondError(c, err)
 		return
 	}
 
+	c.Header("ETag", expenseETag(expense.Version))
 	c.JSON(http.StatusOK, APIResponse{
 		Success: true,
 		Data:    expense,
 	})
 }
 
-// DeleteExpense удаляет расход
+// DeleteExpense перемещает расход в корзину
+// Понимает If-Match так же, как ReplaceExpense
 func (h *ExpenseHandler) DeleteExpense(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, APIResponse{
-			Success: false,
-			Error:   "Неверный ID",
-		})
+		respondInvalidID(c)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	if err := h.service.DeleteExpense(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusNotFound, APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+	if err := h.service.DeleteExpense(c.Request.Context(), id, version); err != nil {
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, APIResponse{
 		Success: true,
-		Data:    "Расход успешно удалён",
+		Data:    "Расход перемещён в корзину",
 	})
 }
 
 // GetStats возвращает статистику по расходам
+// Понимает те же фильтры, что и список (category, date_from, filter, ...)
 func (h *ExpenseHandler) GetStats(c *gin.Context) {
-	stats, err := h.service.GetStats(c.Request.Context())
+	filter, err := parseExpenseFilter(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, APIResponse{
+	stats, err := h.service.GetStats(c.Request.Context(), filter)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	respondWithETag(c, "", APIResponse{
 		Success: true,
 		Data:    stats,
 	})
@@ -199,10 +242,7 @@ func (h *ExpenseHandler) GetStats(c *gin.Context) {
 func (h *ExpenseHandler) GetCategories(c *gin.Context) {
 	categories, err := h.service.GetCategories(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respondError(c, err)
 		return
 	}
 
@@ -212,11 +252,83 @@ func (h *ExpenseHandler) GetCategories(c *gin.Context) {
 	})
 }
 
-// HealthCheck проверяет состояние сервиса
-// Полезно для kubernetes liveness/readiness probes
+// SuggestCategories подсказывает категорию по описанию и сумме
+func (h *ExpenseHandler) SuggestCategories(c *gin.Context) {
+	var amount float64
+	if amountStr := c.Query("amount"); amountStr != "" {
+		parsed, err := strconv.ParseFloat(amountStr, 64)
+		if err != nil || parsed < 0 {
+			respondError(c, service.Invalid("amount", "нужно неотрицательное число"))
+			return
+		}
+		amount = parsed
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	suggestions, err := h.service.SuggestCategories(c.Request.Context(), c.Query("description"), amount, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    suggestions,
+	})
+}
+
+// HealthCheck - старая проверка, всегда "ok"
+// Для проб kubernetes есть /livez и /readyz (HealthHandler)
 func HealthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "ok",
 		"message": "Сервис работает нормально",
 	})
 }
+
+// ImportExpenses загружает расходы из CSV в теле запроса
+// Файл загружается целиком или не загружается совсем: ошибки - 400
+// с разбором по строкам (rows[i].поле)
+func (h *ExpenseHandler) ImportExpenses(c *gin.Context) {
+	result, err := h.service.Import(c.Request.Context(), c.Request.Body)
+	if err != nil {
+		problem := errorProblem(c, err)
+		if result != nil {
+			problem.Result = result
+		}
+		writeProblem(c, problem)
+		return
+	}
+
+	c.JSON(http.StatusCreated, APIResponse{
+		Success: true,
+		Data:    result,
+	})
+}
+
+// BatchExpenses применяет пакет операций создания, изменения и удаления
+// Неверный пакет - 400 с ошибками по каждой операции,
+// откатившийся атомарный пакет - 409
+func (h *ExpenseHandler) BatchExpenses(c *gin.Context) {
+	var req models.BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err, &req))
+		return
+	}
+
+	result, err := h.service.Batch(c.Request.Context(), req)
+	if err != nil {
+		problem := errorProblem(c, err)
+		if result != nil {
+			problem.Result = result
+		}
+		writeProblem(c, problem)
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    result,
+	})
+}
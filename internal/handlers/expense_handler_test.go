@@ -3,10 +3,14 @@ package handlers
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -19,6 +23,7 @@ import (
 type mockRepo struct {
 	expenses map[int64]*models.Expense
 	lastID   int64
+	fail     error // если задана, чтение и запись падают с ней, как при недоступной БД
 }
 
 func newMockRepo() *mockRepo {
@@ -29,14 +34,21 @@ func newMockRepo() *mockRepo {
 }
 
 func (m *mockRepo) Create(ctx context.Context, expense *models.Expense) error {
+	if m.fail != nil {
+		return m.fail
+	}
 	m.lastID++
 	expense.ID = m.lastID
 	expense.CreatedAt = time.Now()
+	expense.Version = 1
 	m.expenses[expense.ID] = expense
 	return nil
 }
 
 func (m *mockRepo) GetByID(ctx context.Context, id int64) (*models.Expense, error) {
+	if m.fail != nil {
+		return nil, m.fail
+	}
 	if e, ok := m.expenses[id]; ok {
 		return e, nil
 	}
@@ -51,29 +63,43 @@ func (m *mockRepo) GetAll(ctx context.Context, filter models.ExpenseFilter) ([]m
 	return result, nil
 }
 
+func (m *mockRepo) Count(ctx context.Context, filter models.ExpenseFilter) (int, error) {
+	return len(m.expenses), nil
+}
+
 func (m *mockRepo) Update(ctx context.Context, id int64, req models.UpdateExpenseRequest) (*models.Expense, error) {
 	e, ok := m.expenses[id]
-	if !ok {
+	if !ok || (req.Version != nil && *req.Version != e.Version) {
 		return nil, nil
 	}
+	e.Version++
 	if req.Description != nil {
 		e.Description = *req.Description
 	}
 	if req.Amount != nil {
 		e.Amount = *req.Amount
 	}
+	if req.Category != nil {
+		e.Category = *req.Category
+	}
+	if req.Merchant != nil {
+		e.Merchant = *req.Merchant
+	}
+	if req.Tags != nil {
+		e.Tags = models.Tags(*req.Tags)
+	}
 	return e, nil
 }
 
-func (m *mockRepo) Delete(ctx context.Context, id int64) error {
-	if _, ok := m.expenses[id]; !ok {
-		return errors.New("not found")
+func (m *mockRepo) Delete(ctx context.Context, id int64, version int) error {
+	if e, ok := m.expenses[id]; !ok || (version > 0 && version != e.Version) {
+		return sql.ErrNoRows
 	}
 	delete(m.expenses, id)
 	return nil
 }
 
-func (m *mockRepo) GetStats(ctx context.Context) (*models.ExpenseStats, error) {
+func (m *mockRepo) GetStats(ctx context.Context, filter models.ExpenseFilter) (*models.ExpenseStats, error) {
 	return &models.ExpenseStats{
 		TotalAmount:  1000,
 		ExpenseCount: 5,
@@ -97,8 +123,10 @@ func setupTestRouter() (*gin.Engine, *mockRepo) {
 	{
 		api.POST("/expenses", handler.CreateExpense)
 		api.GET("/expenses", handler.GetExpenses)
+		api.POST("/expenses/batch", handler.BatchExpenses)
 		api.GET("/expenses/:id", handler.GetExpense)
-		api.PUT("/expenses/:id", handler.UpdateExpense)
+		api.PUT("/expenses/:id", handler.ReplaceExpense)
+		api.PATCH("/expenses/:id", handler.PatchExpense)
 		api.DELETE("/expenses/:id", handler.DeleteExpense)
 		api.GET("/stats", handler.GetStats)
 		api.GET("/categories", handler.GetCategories)
@@ -178,6 +206,85 @@ func TestCreateExpense_ValidationError(t *testing.T) {
 	if w.Code != http.StatusBadRequest {
 		t.Errorf("Ожидали статус 400, получили %d", w.Code)
 	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, ProblemContentType) {
+		t.Errorf("Ожидали %s, получили %q", ProblemContentType, ct)
+	}
+
+	var problem Problem
+	json.Unmarshal(w.Body.Bytes(), &problem)
+
+	if problem.Status != http.StatusBadRequest || problem.Instance != "/api/expenses" {
+		t.Errorf("Неожиданный problem: %+v", problem)
+	}
+	if len(problem.Errors) != 1 || problem.Errors[0].Field != "description" {
+		t.Errorf("Ожидали ошибку поля description, получили %+v", problem.Errors)
+	}
+}
+
+func TestCreateExpense_FieldErrors(t *testing.T) {
+	router, _ := setupTestRouter()
+
+	body := `{"description": "Такси", "amount": -5, "tags": [""], "date": "2024-01-15"}`
+	req, _ := http.NewRequest("POST", "/api/expenses", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	var problem Problem
+	json.Unmarshal(w.Body.Bytes(), &problem)
+
+	fields := map[string]string{}
+	for _, f := range problem.Errors {
+		fields[f.Field] = f.Message
+	}
+	for _, name := range []string{"amount", "tags[0]"} {
+		if fields[name] == "" {
+			t.Errorf("Нет ошибки поля %s: %+v", name, problem.Errors)
+		}
+	}
+
+	// Не тот тип - тоже ошибка поля, а не текст из encoding/json
+	req, _ = http.NewRequest("POST", "/api/expenses", strings.NewReader(`{"amount": "сто"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	problem = Problem{}
+	json.Unmarshal(w.Body.Bytes(), &problem)
+	if w.Code != http.StatusBadRequest || len(problem.Errors) != 1 || problem.Errors[0].Field != "amount" {
+		t.Errorf("Ожидали 400 с ошибкой поля amount, получили %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// Отказ БД - это 500 без подробностей, а не 404 или 400
+func TestExpense_RepositoryFailure(t *testing.T) {
+	router, repo := setupTestRouter()
+	repo.fail = errors.New("pq: connection refused")
+
+	tests := []struct {
+		method, path, body string
+	}{
+		{"GET", "/api/expenses/1", ""},
+		{"PUT", "/api/expenses/1", `{"description": "Кофе", "amount": 150, "category": "Еда", "date": "2024-01-15"}`},
+		{"DELETE", "/api/expenses/1", ""},
+		{"POST", "/api/expenses", `{"description": "Кофе", "amount": 150, "category": "Еда", "date": "2024-01-15"}`},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("%s %s: ожидали 500, получили %d", tt.method, tt.path, w.Code)
+		}
+		if strings.Contains(w.Body.String(), "pq:") {
+			t.Errorf("%s %s: текст внутренней ошибки попал в ответ: %s", tt.method, tt.path, w.Body.String())
+		}
+	}
 }
 
 func TestGetExpenses_Handler(t *testing.T) {
@@ -274,7 +381,7 @@ func TestDeleteExpense_Handler(t *testing.T) {
 	}
 }
 
-func TestUpdateExpense_Handler(t *testing.T) {
+func TestReplaceExpense_Handler(t *testing.T) {
 	router, repo := setupTestRouter()
 
 	// Создаём расход напрямую
@@ -282,25 +389,171 @@ func TestUpdateExpense_Handler(t *testing.T) {
 		Description: "Старое",
 		Amount:      100,
 		Category:    "Тест",
+		Merchant:    "Магазин",
 		Date:        time.Now(),
 	}
 	repo.Create(context.Background(), expense)
 
-	// Обновляем
+	put := func(body interface{}) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req, _ := http.NewRequest("PUT", "/api/expenses/1", bytes.NewBuffer(data))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// PUT - это замена целиком: одного описания мало
 	newDesc := "Новое описание"
-	update := models.UpdateExpenseRequest{
-		Description: &newDesc,
+	if w := put(models.UpdateExpenseRequest{Description: &newDesc}); w.Code != http.StatusBadRequest {
+		t.Errorf("Частичный PUT: ожидали 400, получили %d", w.Code)
 	}
 
-	updateBody, _ := json.Marshal(update)
-	updateReq, _ := http.NewRequest("PUT", "/api/expenses/1", bytes.NewBuffer(updateBody))
-	updateReq.Header.Set("Content-Type", "application/json")
+	w := put(models.ReplaceExpenseRequest{
+		Description: newDesc,
+		Amount:      150,
+		Category:    "Тест",
+		Date:        "2024-01-15",
+	})
+	if w.Code != http.StatusOK {
+		t.Errorf("Ожидали статус 200, получили %d. Body: %s", w.Code, w.Body.String())
+	}
+	if repo.expenses[1].Description != newDesc || repo.expenses[1].Merchant != "" {
+		t.Errorf("Поля, которых нет в запросе, должны очиститься: %+v", repo.expenses[1])
+	}
+}
 
-	w := httptest.NewRecorder()
-	router.ServeHTTP(w, updateReq)
+func TestPatchExpense_Handler(t *testing.T) {
+	router, repo := setupTestRouter()
+	repo.Create(context.Background(), &models.Expense{
+		Description: "Обед",
+		Amount:      500,
+		Category:    "Еда",
+		Merchant:    "Столовая",
+		Tags:        models.Tags{"работа"},
+		Date:        time.Now(),
+	})
+
+	patch := func(contentType, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PATCH", "/api/expenses/1", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
 
+	w := patch("application/merge-patch+json", `{"amount": 650, "merchant": null}`)
 	if w.Code != http.StatusOK {
-		t.Errorf("Ожидали статус 200, получили %d. Body: %s", w.Code, w.Body.String())
+		t.Fatalf("Merge patch: ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+	if e := repo.expenses[1]; e.Amount != 650 || e.Merchant != "" || e.Description != "Обед" {
+		t.Errorf("Merge patch применился неверно: %+v", e)
+	}
+
+	w = patch("application/json-patch+json", `[
+		{"op": "test", "path": "/amount", "value": 650},
+		{"op": "add", "path": "/tags/-", "value": "обед"}
+	]`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("JSON Patch: ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+	if tags := repo.expenses[1].Tags; len(tags) != 2 || tags[1] != "обед" {
+		t.Errorf("JSON Patch применился неверно: %v", tags)
+	}
+
+	tests := []struct {
+		name, contentType, body string
+		status                  int
+	}{
+		{"test не совпал", "application/json-patch+json", `[{"op": "test", "path": "/amount", "value": 1}]`, http.StatusConflict},
+		{"служебное поле", "application/merge-patch+json", `{"id": 7}`, http.StatusBadRequest},
+		{"категорию нельзя убрать", "application/merge-patch+json", `{"category": null}`, http.StatusBadRequest},
+		{"обычный JSON", "application/json", `{"amount": 1}`, http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		if w := patch(tt.contentType, tt.body); w.Code != tt.status {
+			t.Errorf("%s: ожидали %d, получили %d: %s", tt.name, tt.status, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestExpense_ETagAndIfMatch(t *testing.T) {
+	router, repo := setupTestRouter()
+	repo.Create(context.Background(), &models.Expense{
+		Description: "Обед",
+		Amount:      500,
+		Category:    "Еда",
+		Date:        time.Now(),
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/expenses/1", nil)
+	router.ServeHTTP(w, req)
+
+	etag := w.Header().Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf("Ожидали ETag \"1\", получили %q", etag)
+	}
+
+	// Клиент уже видел эту версию
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/expenses/1", nil)
+	req.Header.Set("If-None-Match", etag)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("Ожидали 304 без тела, получили %d", w.Code)
+	}
+
+	update := func(ifMatch string) *httptest.ResponseRecorder {
+		body := `{"amount": 650}`
+		req, _ := http.NewRequest("PATCH", "/api/expenses/1", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		req.Header.Set("If-Match", ifMatch)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w = update(etag)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"2"` {
+		t.Errorf("Ожидали 200 и ETag \"2\", получили %d и %q", w.Code, w.Header().Get("ETag"))
+	}
+
+	// Та же версия второй раз - расход уже изменён
+	if w = update(etag); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("Ожидали 412, получили %d", w.Code)
+	}
+	if w = update(`W/"2"`); w.Code != http.StatusBadRequest {
+		t.Errorf("Слабый ETag в If-Match: ожидали 400, получили %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/expenses/1", nil)
+	req.Header.Set("If-Match", etag)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusPreconditionFailed {
+		t.Errorf("Удаление: ожидали 412, получили %d", w.Code)
+	}
+}
+
+func TestGetExpenses_IfNoneMatch(t *testing.T) {
+	router, _ := setupTestRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/stats", nil)
+	router.ServeHTTP(w, req)
+
+	etag := w.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("Ожидали слабый ETag, получили %q", etag)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/stats", nil)
+	req.Header.Set("If-None-Match", etag)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("Ожидали 304, получили %d", w.Code)
 	}
 }
 
@@ -332,3 +585,113 @@ func TestGetExpense_Success(t *testing.T) {
 		t.Error("Ответ должен быть успешным")
 	}
 }
+
+func TestGetExpenses_InvalidCursor(t *testing.T) {
+	router, _ := setupTestRouter()
+
+	req, _ := http.NewRequest("GET", "/api/expenses?cursor=not-a-cursor!", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидали статус 400, получили %d", w.Code)
+	}
+}
+
+func TestParseExpenseFilter_Categories(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		query    string
+		category string
+		list     []string
+		exclude  []string
+	}{
+		{"category=" + url.QueryEscape("Кафе, рестораны"), "Кафе, рестораны", nil, nil},
+		{"category=Еда&category=Транспорт", "", []string{"Еда", "Транспорт"}, nil},
+		{"category=&category!=" + url.QueryEscape("Кафе, рестораны"), "", nil, []string{"Кафе, рестораны"}},
+	}
+
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("GET", "/api/expenses?"+tt.query, nil)
+
+		filter, err := parseExpenseFilter(c)
+		if err != nil {
+			t.Fatalf("%s: неожиданная ошибка: %v", tt.query, err)
+		}
+		if filter.Category != tt.category || !reflect.DeepEqual(filter.Categories, tt.list) || !reflect.DeepEqual(filter.ExcludeCategories, tt.exclude) {
+			t.Errorf("%s: получили category=%q categories=%v exclude=%v", tt.query, filter.Category, filter.Categories, filter.ExcludeCategories)
+		}
+	}
+}
+
+func TestGetExpenses_InvalidFilterParams(t *testing.T) {
+	router, _ := setupTestRouter()
+
+	for _, query := range []string{"sort=secret", "amount_min=много", "amount_max=-5"} {
+		req, _ := http.NewRequest("GET", "/api/expenses?"+query, nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: ожидали статус 400, получили %d", query, w.Code)
+		}
+	}
+}
+
+func TestFilterExpression_InvalidReturnsPosition(t *testing.T) {
+	router, _ := setupTestRouter()
+
+	for _, path := range []string{"/api/expenses", "/api/stats"} {
+		req, _ := http.NewRequest("GET", path+"?filter=amount%3E%D0%BC%D0%BD%D0%BE%D0%B3%D0%BE", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: ожидали статус 400, получили %d", path, w.Code)
+		}
+
+		var problem Problem
+		json.Unmarshal(w.Body.Bytes(), &problem)
+
+		if len(problem.Errors) != 1 || problem.Errors[0].Field != "filter" || !strings.Contains(problem.Errors[0].Message, "позиция 8") {
+			t.Errorf("%s: в ошибке поля filter должна быть позиция, получили %+v", path, problem.Errors)
+		}
+	}
+}
+
+func TestBatchExpenses_Handler(t *testing.T) {
+	router, repo := setupTestRouter()
+	repo.Create(context.Background(), &models.Expense{Description: "Старое", Amount: 10, Category: "Тест", Date: time.Now()})
+
+	tests := []struct {
+		body   string
+		status int
+	}{
+		{`{"operations": []}`, http.StatusBadRequest},
+		{`{"operations": [{"op": "upsert", "id": 1}]}`, http.StatusBadRequest},
+		{`{"operations": [{"op": "create", "expense": {"description": "Такси", "amount": -5, "category": "Транспорт", "date": "2024-01-15"}}]}`, http.StatusBadRequest},
+		{`{"atomic": false, "operations": [{"op": "delete", "id": 42}]}`, http.StatusBadRequest},
+		{`{"atomic": false, "operations": [{"op": "delete", "id": 1}]}`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("POST", "/api/expenses/batch", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: ожидали статус %d, получили %d. Body: %s", tt.body, tt.status, w.Code, w.Body.String())
+		}
+	}
+
+	if len(repo.expenses) != 0 {
+		t.Error("Расход должен быть удалён последним пакетом")
+	}
+}
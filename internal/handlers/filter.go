@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+	"github.com/dvoryadkinadv/expense-tracker/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// parseExpenseFilter собирает фильтр списка из query-параметров
+//
+//	?category=Еда&category=Транспорт  - любая из категорий (запятая - часть названия)
+//	?category!=Развлечения            - исключить категорию
+//	?amount_min=100&amount_max=500
+//	?created_after=2024-01-01
+//	?description_contains=кофе
+//	?sort=-amount                     - date, amount, created_at, category
+//	?filter=category:Еда AND amount>500 - язык фильтров, см. пакет filterql
+//	?view=3                           - сохранённое представление
+func parseExpenseFilter(c *gin.Context) (models.ExpenseFilter, error) {
+	filter := models.ExpenseFilter{
+		Query:               c.Query("q"),
+		DateFrom:            c.Query("date_from"),
+		DateTo:              c.Query("date_to"),
+		CreatedAfter:        c.Query("created_after"),
+		DescriptionContains: c.Query("description_contains"),
+		Expression:          c.Query("filter"),
+		Sort:                c.Query("sort"),
+		Cursor:              c.Query("cursor"),
+		WithTotal:           c.Query("with_total") == "true" || c.Query("with_total") == "1",
+	}
+
+	// Одна категория - как раньше, несколько (повтором параметра) - "любая из".
+	// Через запятую не делим: в названиях категорий бывают запятые
+	categories := queryValues(c, "category")
+	if len(categories) == 1 {
+		filter.Category = categories[0]
+	} else if len(categories) > 1 {
+		filter.Categories = categories
+	}
+
+	// "category!=Еда" Go разбирает как параметр "category!" со значением "Еда"
+	filter.ExcludeCategories = queryValues(c, "category!")
+
+	var err error
+	if filter.AmountMin, err = parseAmount(c, "amount_min"); err != nil {
+		return filter, err
+	}
+	if filter.AmountMax, err = parseAmount(c, "amount_max"); err != nil {
+		return filter, err
+	}
+
+	if viewStr := c.Query("view"); viewStr != "" {
+		id, err := strconv.ParseInt(viewStr, 10, 64)
+		if err != nil || id <= 0 {
+			return filter, service.Invalid("view", "нужен ID представления")
+		}
+		filter.ViewID = id
+	}
+
+	// Парсим limit и offset
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil {
+			filter.Limit = limit
+		}
+	}
+
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if offset, err := strconv.Atoi(offsetStr); err == nil {
+			filter.Offset = offset
+		}
+	}
+
+	return filter, nil
+}
+
+// queryValues - непустые значения повторяющегося параметра, как есть
+func queryValues(c *gin.Context, name string) []string {
+	var result []string
+	for _, v := range c.QueryArray(name) {
+		if v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func parseAmount(c *gin.Context, name string) (*float64, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+
+	amount, err := strconv.ParseFloat(raw, 64)
+	if err != nil || amount < 0 {
+		return nil, service.Invalid(name, "нужно неотрицательное число")
+	}
+
+	return &amount, nil
+}
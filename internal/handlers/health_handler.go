@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+	"github.com/dvoryadkinadv/expense-tracker/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// HealthHandler - пробы kubernetes и балансировщика
+type HealthHandler struct {
+	service *service.HealthService
+}
+
+// NewHealthHandler создаёт новый хэндлер
+func NewHealthHandler(s *service.HealthService) *HealthHandler {
+	return &HealthHandler{service: s}
+}
+
+// Live - процесс жив и отвечает
+// Зависимости здесь нарочно не проверяются: если упадёт БД,
+// перезапуск экземпляров не поможет, а вот убрать их из балансировки - поможет (Ready)
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"status": models.HealthOK})
+}
+
+// Ready - готов ли экземпляр принимать запросы: 200 или 503 с разбором по проверкам
+func (h *HealthHandler) Ready(c *gin.Context) {
+	report := h.service.Ready(c.Request.Context())
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, report)
+}
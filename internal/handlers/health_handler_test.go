@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+	"github.com/dvoryadkinadv/expense-tracker/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type stubPinger struct{ err error }
+
+func (p stubPinger) PingContext(ctx context.Context) error { return p.err }
+
+func TestHealthHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for _, tt := range []struct {
+		name string
+		db   error
+		code int
+	}{
+		{"БД отвечает", nil, http.StatusOK},
+		{"БД недоступна", errors.New("connection refused"), http.StatusServiceUnavailable},
+	} {
+		h := NewHealthHandler(service.NewHealthService(stubPinger{tt.db}, nil, service.DefaultHealthConfig()))
+		router := gin.New()
+		router.GET("/livez", h.Live)
+		router.GET("/readyz", h.Ready)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/livez", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: /livez не зависит от БД, получили %d", tt.name, w.Code)
+		}
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		if w.Code != tt.code {
+			t.Errorf("%s: /readyz ожидали %d, получили %d", tt.name, tt.code, w.Code)
+		}
+
+		var report models.HealthReport
+		if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := report.Checks["database"]; !ok {
+			t.Errorf("%s: в ответе нет проверки database: %s", tt.name, w.Body.String())
+		}
+	}
+}
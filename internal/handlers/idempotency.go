@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/logging"
+	"github.com/dvoryadkinadv/expense-tracker/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// IdempotencyKeyHeader - ключ, с которым клиент повторяет запрос
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader ставится на ответ, отданный из сохранённых
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	// MaxBodyBytes - предел тела JSON-запроса, который хендлеры читают целиком
+	MaxBodyBytes = 1 << 20
+)
+
+// readBody читает тело запроса не больше MaxBodyBytes
+// Если не вышло, сама отвечает клиенту: 413 для слишком большого тела, иначе 400
+func readBody(c *gin.Context) ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, MaxBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondProblem(c, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("Тело запроса больше %d байт", MaxBodyBytes))
+			return nil, false
+		}
+		respondError(c, service.Invalid("body", "не удалось прочитать тело запроса"))
+		return nil, false
+	}
+	return body, true
+}
+
+// Idempotency повторяет сохранённый ответ на запрос с тем же Idempotency-Key
+// Без заголовка запрос проходит как обычно. Ставится на отдельные маршруты
+// (создание расходов), после Identity: ключи у каждого пользователя свои
+func Idempotency(s *service.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := strings.TrimSpace(c.GetHeader(IdempotencyKeyHeader))
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		body, ok := readBody(c)
+		if !ok {
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		saved, err := s.Begin(ctx, key, requestFingerprint(c.Request, body))
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+
+		if saved != nil {
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(saved.StatusCode, saved.ContentType, saved.Body)
+			c.Abort()
+			return
+		}
+
+		// Ключ освобождаем и после паники в обработчике: иначе он числился бы
+		// "в работе" до конца аренды и все повторы получали бы 409.
+		// Саму панику отдаём дальше, в Recovery
+		defer func() {
+			if p := recover(); p != nil {
+				if err := s.Release(context.WithoutCancel(ctx), key); err != nil {
+					slog.ErrorContext(ctx, "Не удалось освободить Idempotency-Key после паники",
+						slog.String("key", key), logging.Err(err))
+				}
+				panic(p)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// Ответ сохраняем, даже если клиент уже отключился: он как раз и будет повторять
+		status := recorder.Status()
+		if err := s.Complete(context.WithoutCancel(ctx), key, status, recorder.Header().Get("Content-Type"), recorder.body.Bytes()); err != nil {
+			slog.ErrorContext(c.Request.Context(), "Не удалось сохранить ответ по Idempotency-Key",
+				slog.String("key", key), logging.Err(err))
+		}
+	}
+}
+
+// requestFingerprint - отпечаток запроса: метод, путь и тело
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder пишет ответ клиенту и заодно копит тело для сохранения
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
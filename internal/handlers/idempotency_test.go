@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+	"github.com/dvoryadkinadv/expense-tracker/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// mockIdempotencyRepo - ключи в памяти без истечения
+type mockIdempotencyRepo struct {
+	records map[string]models.IdempotencyRecord
+}
+
+func (m *mockIdempotencyRepo) ReserveKey(ctx context.Context, record *models.IdempotencyRecord, staleBefore time.Time) (bool, error) {
+	if _, ok := m.records[record.Owner+"/"+record.Key]; ok {
+		return false, nil
+	}
+	m.records[record.Owner+"/"+record.Key] = *record
+	return true, nil
+}
+
+func (m *mockIdempotencyRepo) GetKey(ctx context.Context, owner, key string) (*models.IdempotencyRecord, error) {
+	if r, ok := m.records[owner+"/"+key]; ok {
+		return &r, nil
+	}
+	return nil, nil
+}
+
+func (m *mockIdempotencyRepo) CompleteKey(ctx context.Context, record *models.IdempotencyRecord) error {
+	r := m.records[record.Owner+"/"+record.Key]
+	r.StatusCode, r.ContentType, r.Body = record.StatusCode, record.ContentType, record.Body
+	m.records[record.Owner+"/"+record.Key] = r
+	return nil
+}
+
+func (m *mockIdempotencyRepo) ReleaseKey(ctx context.Context, owner, key string) error {
+	delete(m.records, owner+"/"+key)
+	return nil
+}
+
+func (m *mockIdempotencyRepo) DeleteExpiredKeys(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestIdempotency_Handler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	repo := newMockRepo()
+	handler := NewExpenseHandler(service.NewExpenseService(repo))
+	idempotency := service.NewIdempotencyService(&mockIdempotencyRepo{records: map[string]models.IdempotencyRecord{}}, time.Hour)
+
+	router := gin.New()
+	router.Use(Identity())
+	router.POST("/api/expenses", Idempotency(idempotency), handler.CreateExpense)
+
+	post := func(key, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/api/expenses", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(IdempotencyKeyHeader, key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	body := `{"description": "Такси", "amount": 300, "category": "Транспорт", "date": "2024-01-16"}`
+
+	first := post("retry-1", body)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("Ожидали 201, получили %d: %s", first.Code, first.Body.String())
+	}
+
+	// Клиент не дождался ответа и повторил запрос
+	retry := post("retry-1", body)
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("Повтор должен вернуть тот же ответ, получили %d: %s", retry.Code, retry.Body.String())
+	}
+	if retry.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("Повтор должен быть помечен заголовком %s", IdempotentReplayedHeader)
+	}
+	if len(repo.expenses) != 1 {
+		t.Errorf("Должен быть создан один расход, создано %d", len(repo.expenses))
+	}
+
+	if w := post("retry-1", `{"description": "Другое", "amount": 1, "category": "Еда", "date": "2024-01-16"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Тот же ключ с другим телом: ожидали 422, получили %d", w.Code)
+	}
+}
+
+func TestIdempotency_ReleasesKeyOnPanic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	idempotency := service.NewIdempotencyService(&mockIdempotencyRepo{records: map[string]models.IdempotencyRecord{}}, time.Hour)
+
+	panics := true
+	router := gin.New()
+	router.Use(Recovery(slog.New(slog.NewTextHandler(io.Discard, nil))), Identity())
+	router.POST("/api/things", Idempotency(idempotency), func(c *gin.Context) {
+		if panics {
+			panic("сбой")
+		}
+		c.Status(http.StatusCreated)
+	})
+
+	post := func() int {
+		req, _ := http.NewRequest("POST", "/api/things", bytes.NewBufferString(`{}`))
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := post(); code != http.StatusInternalServerError {
+		t.Fatalf("Ожидали 500 после паники, получили %d", code)
+	}
+
+	// Повтор не должен получить 409 "ещё выполняется"
+	panics = false
+	if code := post(); code != http.StatusCreated {
+		t.Errorf("После паники ключ должен освободиться, повтор получил %d", code)
+	}
+}
+
+func TestIdempotency_BodyTooLarge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	repo := &mockIdempotencyRepo{records: map[string]models.IdempotencyRecord{}}
+	idempotency := service.NewIdempotencyService(repo, time.Hour)
+
+	called := false
+	router := gin.New()
+	router.Use(Identity())
+	router.POST("/api/things", Idempotency(idempotency), func(c *gin.Context) {
+		called = true
+		c.Status(http.StatusCreated)
+	})
+
+	req, _ := http.NewRequest("POST", "/api/things", bytes.NewReader(make([]byte, MaxBodyBytes+1)))
+	req.Header.Set(IdempotencyKeyHeader, "big")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Ожидали 413, получили %d: %s", w.Code, w.Body.String())
+	}
+	if called {
+		t.Error("Слишком большое тело не должно доходить до обработчика")
+	}
+	if len(repo.records) != 0 {
+		t.Errorf("Ключ не должен резервироваться, получили %v", repo.records)
+	}
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// UserHeader - заголовок с идентификатором пользователя
+// Настоящей аутентификации пока нет: заголовок ставит фронтенд или прокси перед API
+const UserHeader = "X-User-ID"
+
+// maxUserLength - столько символов помещается в audit_log.actor и saved_views.owner
+const maxUserLength = 100
+
+// Identity кладёт пользователя из заголовка в контекст запроса
+// Слишком длинный идентификатор - 400: иначе запрос упал бы уже на записи в БД
+func Identity() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if user := strings.TrimSpace(c.GetHeader(UserHeader)); user != "" {
+			if utf8.RuneCountInString(user) > maxUserLength {
+				respondError(c, service.Invalid(UserHeader, fmt.Sprintf("не длиннее %d символов", maxUserLength)))
+				return
+			}
+			c.Request = c.Request.WithContext(service.WithUser(c.Request.Context(), user))
+		}
+		c.Next()
+	}
+}
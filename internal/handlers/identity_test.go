@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+func TestIdentity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Identity())
+	router.GET("/whoami", func(c *gin.Context) {
+		c.String(http.StatusOK, service.UserFromContext(c.Request.Context()))
+	})
+
+	tests := []struct {
+		user string
+		code int
+		body string
+	}{
+		{"  anna ", http.StatusOK, "anna"},
+		{"", http.StatusOK, ""},
+		{strings.Repeat("я", 100), http.StatusOK, strings.Repeat("я", 100)},
+		{strings.Repeat("я", 101), http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		req.Header.Set(UserHeader, tt.user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.code {
+			t.Errorf("%d символов: ожидали %d, получили %d", len([]rune(tt.user)), tt.code, w.Code)
+			continue
+		}
+		if tt.code == http.StatusOK && w.Body.String() != tt.body {
+			t.Errorf("Ожидали пользователя %q, получили %q", tt.body, w.Body.String())
+		}
+		if tt.code == http.StatusBadRequest && w.Header().Get("Content-Type") != "application/problem+json" {
+			t.Errorf("Ожидали application/problem+json, получили %s", w.Header().Get("Content-Type"))
+		}
+	}
+}
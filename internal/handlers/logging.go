@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/logging"
+	"github.com/dvoryadkinadv/expense-tracker/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader - заголовок с идентификатором запроса
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength - длиннее не принимаем, чтобы клиент не раздувал логи
+const maxRequestIDLength = 128
+
+// RequestID берёт идентификатор запроса из X-Request-ID или создаёт новый
+// Идентификатор попадает в контекст (и оттуда во все логи запроса)
+// и возвращается клиенту в том же заголовке
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// validRequestID - идентификатор от клиента или прокси годится для логов
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// AccessLog пишет строку на каждый запрос: маршрут, статус, время, пользователь
+// 5xx - уровень error, 4xx - warn, остальное - info.
+// Значения секретных параметров в строке запроса скрываются
+func AccessLog(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		// Пользователь и request_id - из контекста, который дополнили middleware дальше по цепочке
+		ctx := c.Request.Context()
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if query := logging.RedactQuery(c.Request.URL.RawQuery); query != "" {
+			attrs = append(attrs, slog.String("query", query))
+		}
+		if user := service.UserFromContext(ctx); user != "" {
+			attrs = append(attrs, slog.String("user", user))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", c.Errors.String()))
+		}
+
+		logger.LogAttrs(ctx, level, "HTTP-запрос", attrs...)
+	}
+}
+
+// Recovery превращает панику в обработчике в 500 и пишет её в лог со стеком
+// Вместо gin.Recovery, который пишет в stderr мимо slog
+func Recovery(logger *slog.Logger) gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered interface{}) {
+		logger.ErrorContext(c.Request.Context(), "Паника при обработке запроса",
+			slog.String("panic", fmt.Sprint(recovered)),
+			slog.String("stack", string(debug.Stack())),
+		)
+		respondProblem(c, http.StatusInternalServerError, internalDetail)
+	})
+}
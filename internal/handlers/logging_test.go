@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/logging"
+	"github.com/gin-gonic/gin"
+)
+
+func setupLoggingRouter(t *testing.T) (*gin.Engine, *bytes.Buffer) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, "info", logging.FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.Use(RequestID(), AccessLog(logger), Recovery(logger), Identity())
+	router.GET("/api/things/:id", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"request_id": logging.RequestID(c.Request.Context())})
+	})
+	router.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+	return router, &buf
+}
+
+func TestRequestID_GeneratedAndPassedThrough(t *testing.T) {
+	router, _ := setupLoggingRouter(t)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/things/1", nil))
+
+	generated := w.Header().Get(RequestIDHeader)
+	if len(generated) != 32 {
+		t.Errorf("Ожидали сгенерированный идентификатор из 32 символов, получили %q", generated)
+	}
+	if !strings.Contains(w.Body.String(), generated) {
+		t.Errorf("Идентификатор должен быть в контексте запроса: %s", w.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/things/1", nil)
+	req.Header.Set(RequestIDHeader, "upstream-42")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if got := w.Header().Get(RequestIDHeader); got != "upstream-42" {
+		t.Errorf("Идентификатор от клиента должен сохраниться, получили %q", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/things/1", nil)
+	req.Header.Set(RequestIDHeader, "bad id\n")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if got := w.Header().Get(RequestIDHeader); got == "bad id\n" || got == "" {
+		t.Errorf("Неверный идентификатор нужно заменить, получили %q", got)
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	router, buf := setupLoggingRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/things/7?token=secret&limit=5", nil)
+	req.Header.Set(RequestIDHeader, "req-7")
+	req.Header.Set(UserHeader, "alice")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Ожидали одну JSON-строку, получили %q", buf.String())
+	}
+
+	want := map[string]interface{}{
+		"level":      "INFO",
+		"route":      "/api/things/:id",
+		"path":       "/api/things/7",
+		"status":     float64(200),
+		"user":       "alice",
+		"request_id": "req-7",
+		"query":      "token=[REDACTED]&limit=5",
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("%s: ожидали %v, получили %v", key, value, entry[key])
+		}
+	}
+	if _, ok := entry["latency_ms"]; !ok {
+		t.Error("В логе нет latency_ms")
+	}
+	if strings.Contains(buf.String(), "secret") {
+		t.Errorf("Секрет попал в лог: %s", buf.String())
+	}
+}
+
+func TestRecovery_LogsPanic(t *testing.T) {
+	router, buf := setupLoggingRouter(t)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Ожидали 500, получили %d", w.Code)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Ожидали запись о панике и строку access log, получили %q", buf.String())
+	}
+
+	var entry map[string]interface{}
+	json.Unmarshal([]byte(lines[0]), &entry)
+	if entry["level"] != slog.LevelError.String() || entry["panic"] != "boom" || entry["request_id"] == nil {
+		t.Errorf("Неожиданная запись о панике: %v", entry)
+	}
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestObserver учитывает HTTP-запросы (реализует пакет metrics)
+type RequestObserver interface {
+	ObserveRequest(method, route string, status int, duration time.Duration)
+}
+
+// RequestMetrics передаёт в observer метод, шаблон маршрута, статус и время запроса
+func RequestMetrics(observer RequestObserver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		observer.ObserveRequest(c.Request.Method, c.FullPath(), c.Writer.Status(), time.Since(start))
+	}
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/logging"
+	"github.com/dvoryadkinadv/expense-tracker/internal/ratelimit"
+	"github.com/dvoryadkinadv/expense-tracker/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// Заголовки ограничения частоты (черновик IETF RateLimit header fields)
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+	RetryAfterHeader         = "Retry-After"
+
+	// APIKeyHeader - токен клиента API, как и "Authorization: Bearer"
+	APIKeyHeader = "X-API-Key"
+)
+
+// RateLimit ограничивает частоту запросов группы маршрутов
+// Корзин у запроса до двух: по IP - всегда, и по токену или пользователю,
+// если они есть. Пропускаем, только если запрос уместился в обе.
+// Выключенный лимит ("off") ничего не делает
+func RateLimit(store ratelimit.Store, group string, limit ratelimit.Limit) gin.HandlerFunc {
+	if !limit.Enabled() {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		keys := []string{rateLimitKey(group, "ip", clientNetwork(c.ClientIP()))}
+		if kind, id := rateLimitIdentity(c); id != "" {
+			keys = append(keys, rateLimitKey(group, kind, id))
+		}
+
+		for _, key := range keys {
+			result, err := store.Take(ctx, key, limit)
+			if err != nil {
+				// Хранилище недоступно - пропускаем запрос: лучше не ограничить,
+				// чем отказать всем клиентам сразу
+				slog.WarnContext(ctx, "Не удалось проверить лимит запросов", slog.String("group", group), logging.Err(err))
+				c.Next()
+				return
+			}
+
+			setRateLimitHeaders(c, result)
+
+			// Упёрлись в лимит по IP - корзину по токену уже не трогаем,
+			// чтобы перебор токенов с одного адреса не плодил новых корзин
+			if !result.Allowed {
+				retry := ceilSeconds(result.RetryAfter)
+				c.Header(RetryAfterHeader, strconv.Itoa(retry))
+				respondProblem(c, http.StatusTooManyRequests, fmt.Sprintf("Слишком много запросов, повторите через %d с", retry))
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// rateLimitIdentity - кто отправил запрос, если он назвался: токен или пользователь
+// Сервер их не проверяет, так что это только дополнительная корзина:
+// клиент со случайным токеном получает новую корзину по токену, но не по IP,
+// а один токен с разных адресов делит одну корзину
+func rateLimitIdentity(c *gin.Context) (kind, id string) {
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && strings.TrimSpace(token) != "" {
+		return "token", strings.TrimSpace(token)
+	}
+	if token := strings.TrimSpace(c.GetHeader(APIKeyHeader)); token != "" {
+		return "token", token
+	}
+	if user := service.UserFromContext(c.Request.Context()); user != "" {
+		return "user", user
+	}
+	return "", ""
+}
+
+// clientNetwork - IP клиента для корзины
+// IP - из c.ClientIP(), то есть X-Forwarded-For учитывается только от прокси
+// из server.trusted_proxies. IPv6 - по сети /64: столько адресов обычно у одного клиента
+func clientNetwork(clientIP string) string {
+	if ip := net.ParseIP(clientIP); ip != nil && ip.To4() == nil {
+		return ip.Mask(net.CIDRMask(64, 128)).String() + "/64"
+	}
+	return clientIP
+}
+
+// rateLimitKey - ключ корзины в хранилище
+// Вместо самого значения - хеш фиксированной длины: токены не попадают
+// в хранилище как есть, и ключ всегда влезает в rate_limits.key
+func rateLimitKey(group, kind, value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return group + ":" + kind + ":" + hex.EncodeToString(sum[:16])
+}
+
+// setRateLimitHeaders пишет состояние корзины
+// Если на маршруте несколько лимитов (/api и пакетные операции), клиенту
+// показываем самый строгий - тот, где осталось меньше запросов
+func setRateLimitHeaders(c *gin.Context, result ratelimit.Result) {
+	header := c.Writer.Header()
+	if prev, err := strconv.Atoi(header.Get(RateLimitRemainingHeader)); err == nil && prev < result.Remaining {
+		return
+	}
+
+	header.Set(RateLimitLimitHeader, strconv.Itoa(result.Limit))
+	header.Set(RateLimitRemainingHeader, strconv.Itoa(result.Remaining))
+	header.Set(RateLimitResetHeader, strconv.Itoa(ceilSeconds(result.Reset)))
+}
+
+// ceilSeconds - секунды с округлением вверх: Retry-After: 0 означал бы "повторяйте сразу"
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+func setupRateLimitRouter(store ratelimit.Store, limit ratelimit.Limit, trustedProxies ...string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.SetTrustedProxies(trustedProxies)
+	router.Use(Identity())
+	api := router.Group("/api", RateLimit(store, "api", limit))
+	api.GET("/things", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+// doRequest - запрос с адреса remoteAddr; headers - пары имя, значение
+func doRequest(router *gin.Engine, remoteAddr string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/things", nil)
+	req.RemoteAddr = remoteAddr
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit(t *testing.T) {
+	router := setupRateLimitRouter(ratelimit.NewMemoryStore(), ratelimit.Limit{Count: 1, Per: time.Minute, Burst: 2})
+	client := "192.0.2.1:40000"
+
+	w := doRequest(router, client, UserHeader, "alice")
+	if w.Code != http.StatusOK || w.Header().Get(RateLimitLimitHeader) != "2" || w.Header().Get(RateLimitRemainingHeader) != "1" {
+		t.Errorf("Первый запрос: ожидали 200 и RateLimit-Remaining: 1, получили %d %v", w.Code, w.Header())
+	}
+	doRequest(router, client, UserHeader, "alice")
+
+	w = doRequest(router, client, UserHeader, "alice")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Ожидали 429, получили %d", w.Code)
+	}
+	if got := w.Header().Get(RetryAfterHeader); got != "60" {
+		t.Errorf("Retry-After: ожидали 60, получили %q", got)
+	}
+	if got := w.Header().Get(RateLimitResetHeader); got != "120" {
+		t.Errorf("RateLimit-Reset: ожидали 120, получили %q", got)
+	}
+
+	// Новый пользователь или токен не обходит корзину по IP
+	for _, headers := range [][]string{
+		{UserHeader, "bob"},
+		{"Authorization", "Bearer random-token"},
+		{"X-API-Key", "random-key"},
+		{"X-Forwarded-For", "198.51.100.7"},
+	} {
+		if w := doRequest(router, client, headers...); w.Code != http.StatusTooManyRequests {
+			t.Errorf("%s: лимит не должен обходиться заголовком, получили %d", headers[0], w.Code)
+		}
+	}
+
+	if w := doRequest(router, "192.0.2.2:40000"); w.Code != http.StatusOK {
+		t.Errorf("Другой IP считается отдельно, получили %d", w.Code)
+	}
+}
+
+func TestRateLimit_TrustedProxy(t *testing.T) {
+	router := setupRateLimitRouter(ratelimit.NewMemoryStore(), ratelimit.Limit{Count: 1, Per: time.Minute, Burst: 1}, "10.0.0.1")
+	proxy := "10.0.0.1:50000"
+
+	// За доверенным прокси клиенты различаются по X-Forwarded-For
+	if w := doRequest(router, proxy, "X-Forwarded-For", "198.51.100.7"); w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d", w.Code)
+	}
+	if w := doRequest(router, proxy, "X-Forwarded-For", "198.51.100.7"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Ожидали 429 для того же клиента, получили %d", w.Code)
+	}
+	if w := doRequest(router, proxy, "X-Forwarded-For", "198.51.100.8"); w.Code != http.StatusOK {
+		t.Errorf("Другой клиент за прокси считается отдельно, получили %d", w.Code)
+	}
+}
+
+func TestRateLimit_Identity(t *testing.T) {
+	router := setupRateLimitRouter(ratelimit.NewMemoryStore(), ratelimit.Limit{Count: 1, Per: time.Minute, Burst: 2})
+
+	// Один токен с разных адресов - одна корзина
+	for i, addr := range []string{"192.0.2.1:40000", "192.0.2.2:40000"} {
+		if w := doRequest(router, addr, "Authorization", "Bearer t1"); w.Code != http.StatusOK {
+			t.Fatalf("Запрос %d: ожидали 200, получили %d", i+1, w.Code)
+		}
+	}
+	if w := doRequest(router, "192.0.2.3:40000", APIKeyHeader, "t1"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Тот же токен в X-API-Key с третьего адреса: ожидали 429, получили %d", w.Code)
+	}
+
+	// Так же по пользователю
+	doRequest(router, "192.0.2.4:40000", UserHeader, "alice")
+	doRequest(router, "192.0.2.5:40000", UserHeader, "alice")
+	w := doRequest(router, "192.0.2.6:40000", UserHeader, "alice")
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Пользователь с третьего адреса: ожидали 429, получили %d", w.Code)
+	}
+	if w.Header().Get(RateLimitRemainingHeader) != "0" {
+		t.Errorf("Показываем самую строгую корзину, RateLimit-Remaining: %q", w.Header().Get(RateLimitRemainingHeader))
+	}
+
+	if w := doRequest(router, "192.0.2.6:40000", UserHeader, "bob"); w.Code != http.StatusOK {
+		t.Errorf("Другой пользователь считается отдельно, получили %d", w.Code)
+	}
+}
+
+func TestRateLimitKey(t *testing.T) {
+	// Адреса из одной сети /64 - один клиент
+	if clientNetwork("2001:db8::1") != clientNetwork("2001:db8::ffff") {
+		t.Error("IPv6 из одной /64 должны попадать в одну корзину")
+	}
+	if clientNetwork("2001:db8::1") == clientNetwork("2001:db8:0:1::1") {
+		t.Error("Разные /64 должны попадать в разные корзины")
+	}
+	if clientNetwork("192.0.2.1") == clientNetwork("192.0.2.2") {
+		t.Error("Разные IPv4 должны попадать в разные корзины")
+	}
+	if rateLimitKey("api", "ip", "192.0.2.1") == rateLimitKey("batch", "ip", "192.0.2.1") {
+		t.Error("У групп должны быть свои корзины")
+	}
+	if rateLimitKey("api", "user", "192.0.2.1") == rateLimitKey("api", "ip", "192.0.2.1") {
+		t.Error("Пользователь с именем-адресом не должен попадать в корзину IP")
+	}
+
+	// Длина ключа не зависит от ввода и влезает в rate_limits.key, токен в ключе не виден
+	long := rateLimitKey("batch", "token", strings.Repeat("секрет", 100))
+	if len(long) != len(rateLimitKey("batch", "token", "t")) {
+		t.Error("Ключ должен быть фиксированной длины")
+	}
+	if strings.Contains(long, "секрет") {
+		t.Error("Токен не должен попадать в ключ как есть")
+	}
+}
+
+func TestRateLimit_Off(t *testing.T) {
+	router := setupRateLimitRouter(ratelimit.NewMemoryStore(), ratelimit.Limit{})
+	for i := 0; i < 5; i++ {
+		if w := doRequest(router, "192.0.2.1:40000"); w.Code != http.StatusOK || w.Header().Get(RateLimitLimitHeader) != "" {
+			t.Fatalf("Выключенный лимит не должен мешать, получили %d %v", w.Code, w.Header())
+		}
+	}
+}
+
+// brokenStore - хранилище, до которого не достучаться
+type brokenStore struct{}
+
+func (brokenStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("connection refused")
+}
+func (brokenStore) Purge(ctx context.Context) (int64, error) { return 0, nil }
+
+func TestRateLimit_StoreErrorLetsThrough(t *testing.T) {
+	router := setupRateLimitRouter(brokenStore{}, ratelimit.Limit{Count: 1, Per: time.Second, Burst: 1})
+	if w := doRequest(router, "192.0.2.1:40000"); w.Code != http.StatusOK {
+		t.Errorf("При недоступном хранилище запрос должен пройти, получили %d", w.Code)
+	}
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+	"github.com/dvoryadkinadv/expense-tracker/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// RuleHandler обрабатывает запросы к правилам автокатегоризации
+// Правила к сохранённым расходам применяются через сервис расходов,
+// поэтому он тоже нужен
+type RuleHandler struct {
+	service  *service.RuleService
+	expenses *service.ExpenseService
+}
+
+// NewRuleHandler создаёт новый хэндлер
+func NewRuleHandler(s *service.RuleService, expenses *service.ExpenseService) *RuleHandler {
+	return &RuleHandler{service: s, expenses: expenses}
+}
+
+// CreateRule создаёт правило
+func (h *RuleHandler) CreateRule(c *gin.Context) {
+	var req models.RuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err, &req))
+		return
+	}
+
+	rule, err := h.service.CreateRule(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, APIResponse{
+		Success: true,
+		Data:    rule,
+	})
+}
+
+// GetRules возвращает все правила в порядке применения
+func (h *RuleHandler) GetRules(c *gin.Context) {
+	rules, err := h.service.ListRules(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    rules,
+	})
+}
+
+// GetRule возвращает правило по ID
+func (h *RuleHandler) GetRule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondInvalidID(c)
+		return
+	}
+
+	rule, err := h.service.GetRule(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    rule,
+	})
+}
+
+// UpdateRule заменяет правило
+func (h *RuleHandler) UpdateRule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondInvalidID(c)
+		return
+	}
+
+	var req models.RuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err, &req))
+		return
+	}
+
+	rule, err := h.service.UpdateRule(c.Request.Context(), id, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    rule,
+	})
+}
+
+// DeleteRule удаляет правило
+func (h *RuleHandler) DeleteRule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondInvalidID(c)
+		return
+	}
+
+	if err := h.service.DeleteRule(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    "Правило успешно удалено",
+	})
+}
+
+// ApplyRules повторно применяет правила к существующим расходам
+// С "dry_run": true возвращает только предпросмотр изменений
+func (h *RuleHandler) ApplyRules(c *gin.Context) {
+	var req models.ApplyRulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err, &req))
+		return
+	}
+
+	result, err := h.expenses.ReapplyRules(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    result,
+	})
+}
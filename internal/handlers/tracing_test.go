@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// setupTracingRouter подменяет глобальный провайдер на запоминающий спаны
+func setupTracingRouter(t *testing.T) (*gin.Engine, *tracetest.SpanRecorder) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+
+	router := gin.New()
+	router.Use(Tracing(), Identity())
+	router.GET("/api/things/:id", func(c *gin.Context) {
+		_, child := otel.Tracer("test").Start(c.Request.Context(), "child")
+		child.End()
+		c.Status(http.StatusOK)
+	})
+	router.GET("/broken", func(c *gin.Context) {
+		c.Status(http.StatusInternalServerError)
+	})
+	return router, recorder
+}
+
+func spanAttr(span sdktrace.ReadOnlySpan, key string) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracing_ServerSpan(t *testing.T) {
+	router, recorder := setupTracingRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/things/7", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set(UserHeader, "alice")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Ожидали спан запроса и дочерний, получили %d", len(spans))
+	}
+	child, server := spans[0], spans[1]
+
+	if server.Name() != "GET /api/things/:id" || server.SpanKind() != trace.SpanKindServer {
+		t.Errorf("Неожиданный спан запроса: %q, %v", server.Name(), server.SpanKind())
+	}
+	if got := server.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Спан должен продолжить трассировку из traceparent, получили %s", got)
+	}
+	if server.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("Родитель должен быть из traceparent, получили %s", server.Parent().SpanID())
+	}
+	if child.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Error("Спан обработчика должен быть дочерним для спана запроса")
+	}
+	if got := spanAttr(server, "http.response.status_code").AsInt64(); got != http.StatusOK {
+		t.Errorf("http.response.status_code: ожидали 200, получили %d", got)
+	}
+	if got := spanAttr(server, "enduser.id").AsString(); got != "alice" {
+		t.Errorf("enduser.id: ожидали alice, получили %q", got)
+	}
+}
+
+func TestTracing_ErrorStatus(t *testing.T) {
+	router, recorder := setupTracingRouter(t)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/broken", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Ожидали 2 спана, получили %d", len(spans))
+	}
+	if spans[0].Status().Code != codes.Error {
+		t.Errorf("Ответ 500 должен отмечать спан ошибкой, получили %v", spans[0].Status())
+	}
+	if spans[1].Name() != http.MethodGet || spans[1].Status().Code == codes.Error {
+		t.Errorf("404 мимо маршрутов: ожидали спан GET без ошибки, получили %q %v", spans[1].Name(), spans[1].Status())
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// TrashHandler - корзина удалённых расходов
+type TrashHandler struct {
+	service *service.TrashService
+}
+
+// NewTrashHandler создаёт новый хэндлер
+func NewTrashHandler(s *service.TrashService) *TrashHandler {
+	return &TrashHandler{service: s}
+}
+
+// GetTrash возвращает удалённые расходы, недавно удалённые сверху
+func (h *TrashHandler) GetTrash(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+
+	expenses, err := h.service.List(c.Request.Context(), limit, offset)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    expenses,
+	})
+}
+
+// RestoreExpense возвращает расход из корзины
+func (h *TrashHandler) RestoreExpense(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondInvalidID(c)
+		return
+	}
+
+	expense, err := h.service.Restore(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    expense,
+	})
+}
+
+// PurgeExpense удаляет расход из корзины насовсем
+func (h *TrashHandler) PurgeExpense(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondInvalidID(c)
+		return
+	}
+
+	if err := h.service.Purge(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    "Расход удалён насовсем",
+	})
+}
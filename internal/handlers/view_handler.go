@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+	"github.com/dvoryadkinadv/expense-tracker/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// ViewHandler обрабатывает запросы к сохранённым представлениям
+type ViewHandler struct {
+	service *service.ViewService
+}
+
+// NewViewHandler создаёт новый хэндлер
+func NewViewHandler(s *service.ViewService) *ViewHandler {
+	return &ViewHandler{service: s}
+}
+
+// CreateView сохраняет представление
+func (h *ViewHandler) CreateView(c *gin.Context) {
+	var req models.SavedViewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err, &req))
+		return
+	}
+
+	view, err := h.service.CreateView(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, APIResponse{
+		Success: true,
+		Data:    view,
+	})
+}
+
+// GetViews возвращает свои и общие представления
+func (h *ViewHandler) GetViews(c *gin.Context) {
+	views, err := h.service.ListViews(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    views,
+	})
+}
+
+// GetView возвращает представление по ID
+func (h *ViewHandler) GetView(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondInvalidID(c)
+		return
+	}
+
+	view, err := h.service.GetView(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    view,
+	})
+}
+
+// UpdateView заменяет представление
+func (h *ViewHandler) UpdateView(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondInvalidID(c)
+		return
+	}
+
+	var req models.SavedViewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err, &req))
+		return
+	}
+
+	view, err := h.service.UpdateView(c.Request.Context(), id, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    view,
+	})
+}
+
+// DeleteView удаляет представление
+func (h *ViewHandler) DeleteView(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondInvalidID(c)
+		return
+	}
+
+	if err := h.service.DeleteView(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    "Представление успешно удалено",
+	})
+}
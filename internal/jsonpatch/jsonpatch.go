@@ -0,0 +1,201 @@
+// Package jsonpatch применяет изменения к JSON-документам в двух форматах:
+//
+//   - JSON Merge Patch (RFC 7396): {"merchant": null, "amount": 650} -
+//     присланные поля заменяются, null удаляет поле;
+//   - JSON Patch (RFC 6902): [{"op": "replace", "path": "/amount", "value": 650}] -
+//     список операций add, remove, replace, move, copy и test.
+//
+// Документ не меняется, если хоть одна операция не прошла
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	// ErrInvalidPatch - патч не разбирается или в нём неверная операция
+	ErrInvalidPatch = errors.New("неверный патч")
+	// ErrPathNotFound - операция ссылается на путь, которого нет в документе
+	ErrPathNotFound = errors.New("путь не найден")
+	// ErrTestFailed - операция test не совпала с документом
+	ErrTestFailed = errors.New("проверка test не прошла")
+)
+
+// Operation - одна операция JSON Patch
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// MergePatch применяет JSON Merge Patch к документу
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var target, changes interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, fmt.Errorf("неверный документ: %w", err)
+	}
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	return json.Marshal(mergeValue(target, changes))
+}
+
+func mergeValue(target, patch interface{}) interface{} {
+	changes, ok := patch.(map[string]interface{})
+	if !ok {
+		// Не объект заменяет документ целиком
+		return patch
+	}
+
+	object, ok := target.(map[string]interface{})
+	if !ok {
+		object = map[string]interface{}{}
+	}
+
+	for key, value := range changes {
+		if value == nil {
+			delete(object, key)
+			continue
+		}
+		object[key] = mergeValue(object[key], value)
+	}
+	return object
+}
+
+// Apply применяет JSON Patch к документу
+// Ошибка в любой операции - ErrInvalidPatch, ErrPathNotFound или ErrTestFailed
+// с номером операции, документ при этом не меняется
+func Apply(doc, patch []byte) ([]byte, error) {
+	var target interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, fmt.Errorf("неверный документ: %w", err)
+	}
+
+	var ops []Operation
+	decoder := json.NewDecoder(bytes.NewReader(patch))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&ops); err != nil {
+		return nil, fmt.Errorf("%w: ожидается массив операций: %v", ErrInvalidPatch, err)
+	}
+
+	for i, op := range ops {
+		var err error
+		target, err = applyOperation(target, op)
+		if err != nil {
+			return nil, fmt.Errorf("операция %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+
+	return json.Marshal(target)
+}
+
+func applyOperation(doc interface{}, op Operation) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		value, err := op.value()
+		if err != nil {
+			return nil, err
+		}
+
+		switch op.Op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			// replace = remove + add, но поле обязано существовать
+			if _, err := get(doc, path); err != nil {
+				return nil, err
+			}
+			if len(path) == 0 {
+				return value, nil
+			}
+			if doc, err = remove(doc, path); err != nil {
+				return nil, err
+			}
+			return add(doc, path, value)
+		default:
+			current, err := get(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(current, value) {
+				return nil, ErrTestFailed
+			}
+			return doc, nil
+		}
+
+	case "remove":
+		return remove(doc, path)
+
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+
+		if op.Op == "copy" {
+			return add(doc, path, deepCopy(value))
+		}
+
+		if op.From == op.Path {
+			return doc, nil
+		}
+		if strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, fmt.Errorf("%w: нельзя переместить значение внутрь самого себя", ErrInvalidPatch)
+		}
+		if doc, err = remove(doc, from); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+
+	default:
+		return nil, fmt.Errorf("%w: неизвестная операция %q", ErrInvalidPatch, op.Op)
+	}
+}
+
+// value разбирает значение операции; у add, replace и test оно обязательно
+func (op Operation) value() (interface{}, error) {
+	if op.Value == nil {
+		return nil, fmt.Errorf("%w: у операции %s нет value", ErrInvalidPatch, op.Op)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(op.Value, &value); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return value, nil
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = deepCopy(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = deepCopy(item)
+		}
+		return result
+	default:
+		return v
+	}
+}
@@ -0,0 +1,112 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// sameJSON сравнивает документы без учёта порядка полей
+func sameJSON(t *testing.T, got []byte, want string) bool {
+	t.Helper()
+
+	var g, w interface{}
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("Результат - не JSON: %s", got)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatalf("Ожидаемое - не JSON: %s", want)
+	}
+	return reflect.DeepEqual(g, w)
+}
+
+func TestMergePatch(t *testing.T) {
+	// Примеры из приложения A RFC 7396
+	tests := []struct {
+		doc, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		got, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+		if err != nil {
+			t.Errorf("%s + %s: неожиданная ошибка %v", tt.doc, tt.patch, err)
+			continue
+		}
+		if !sameJSON(t, got, tt.want) {
+			t.Errorf("%s + %s = %s, ожидали %s", tt.doc, tt.patch, got, tt.want)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name, doc, patch, want string
+	}{
+		{"add в объект", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{"add в массив", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{"add в конец", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc"]}]`, `{"foo":["bar",["abc"]]}`},
+		{"add null", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":null}]`, `{"foo":"bar","baz":null}`},
+		{"remove", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{"remove из массива", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{"replace", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{"move", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{"move в массиве", `{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{"copy", `{"a":{"b":[1]}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"add","path":"/c/b/-","value":2}]`, `{"a":{"b":[1]},"c":{"b":[1,2]}}`},
+		{"test и экранирование", `{"a/b":{"m~n":8}}`, `[{"op":"test","path":"/a~1b/m~0n","value":8}]`, `{"a/b":{"m~n":8}}`},
+		{"весь документ", `{"a":1}`, `[{"op":"replace","path":"","value":[1]}]`, `[1]`},
+	}
+
+	for _, tt := range tests {
+		got, err := Apply([]byte(tt.doc), []byte(tt.patch))
+		if err != nil {
+			t.Errorf("%s: неожиданная ошибка %v", tt.name, err)
+			continue
+		}
+		if !sameJSON(t, got, tt.want) {
+			t.Errorf("%s: получили %s, ожидали %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApply_Errors(t *testing.T) {
+	tests := []struct {
+		name, patch string
+		want        error
+	}{
+		{"не массив", `{"op":"add"}`, ErrInvalidPatch},
+		{"неизвестная операция", `[{"op":"merge","path":"/a"}]`, ErrInvalidPatch},
+		{"нет value", `[{"op":"add","path":"/b"}]`, ErrInvalidPatch},
+		{"путь без /", `[{"op":"remove","path":"a"}]`, ErrInvalidPatch},
+		{"лишнее поле", `[{"op":"remove","path":"/a","vlaue":1}]`, ErrInvalidPatch},
+		{"нет поля", `[{"op":"remove","path":"/nope"}]`, ErrPathNotFound},
+		{"replace несуществующего", `[{"op":"replace","path":"/nope","value":1}]`, ErrPathNotFound},
+		{"индекс за концом", `[{"op":"add","path":"/list/5","value":1}]`, ErrPathNotFound},
+		{"индекс с нулём", `[{"op":"remove","path":"/list/01"}]`, ErrInvalidPatch},
+		{"move внутрь себя", `[{"op":"move","from":"/obj","path":"/obj/x"}]`, ErrInvalidPatch},
+		{"test не совпал", `[{"op":"test","path":"/a","value":"2"}]`, ErrTestFailed},
+	}
+
+	doc := []byte(`{"a":1,"list":[1,2],"obj":{}}`)
+	for _, tt := range tests {
+		if _, err := Apply(doc, []byte(tt.patch)); !errors.Is(err, tt.want) {
+			t.Errorf("%s: ожидали %v, получили %v", tt.name, tt.want, err)
+		}
+	}
+}
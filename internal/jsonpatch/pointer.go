@@ -0,0 +1,164 @@
+package jsonpatch
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parsePointer разбирает JSON Pointer (RFC 6901): "/tags/0" -> ["tags", "0"]
+// Пустая строка - весь документ
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: путь %q должен начинаться с /", ErrInvalidPatch, pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		// Порядок важен: "~01" - это "~1", а не "/"
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// arrayIndex - номер элемента массива длины n
+// allowEnd разрешает n и "-" (добавление в конец)
+func arrayIndex(token string, n int, allowEnd bool) (int, error) {
+	if token == "-" && allowEnd {
+		return n, nil
+	}
+
+	// Ведущие нули и знаки RFC 6901 запрещает
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.ContainsAny(token, "+-") {
+		return 0, fmt.Errorf("%w: %q - не номер элемента массива", ErrInvalidPatch, token)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q - не номер элемента массива", ErrInvalidPatch, token)
+	}
+
+	max := n - 1
+	if allowEnd {
+		max = n
+	}
+	if i > max {
+		return 0, fmt.Errorf("%w: элемента %d нет, в массиве %d", ErrPathNotFound, i, n)
+	}
+	return i, nil
+}
+
+// get возвращает значение по пути
+func get(doc interface{}, path []string) (interface{}, error) {
+	node := doc
+	for _, token := range path {
+		switch container := node.(type) {
+		case map[string]interface{}:
+			value, ok := container[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: нет поля %q", ErrPathNotFound, token)
+			}
+			node = value
+		case []interface{}:
+			i, err := arrayIndex(token, len(container), false)
+			if err != nil {
+				return nil, err
+			}
+			node = container[i]
+		default:
+			return nil, fmt.Errorf("%w: %q - внутри не объект и не массив", ErrPathNotFound, token)
+		}
+	}
+	return node, nil
+}
+
+// update находит контейнер, в котором лежит последний элемент пути, и вызывает для него fn
+// Возвращает новый документ: массивы при вставке и удалении пересоздаются
+func update(doc interface{}, path []string, fn func(container interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+
+	switch container := doc.(type) {
+	case map[string]interface{}:
+		child, ok := container[path[0]]
+		if !ok {
+			return nil, fmt.Errorf("%w: нет поля %q", ErrPathNotFound, path[0])
+		}
+		updated, err := update(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		container[path[0]] = updated
+		return container, nil
+	case []interface{}:
+		i, err := arrayIndex(path[0], len(container), false)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := update(container[i], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		container[i] = updated
+		return container, nil
+	default:
+		return nil, fmt.Errorf("%w: %q - внутри не объект и не массив", ErrPathNotFound, path[0])
+	}
+}
+
+// add добавляет значение: в объект - ставит поле, в массив - вставляет перед элементом
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return update(doc, path, func(node interface{}, token string) (interface{}, error) {
+		switch container := node.(type) {
+		case map[string]interface{}:
+			container[token] = value
+			return container, nil
+		case []interface{}:
+			i, err := arrayIndex(token, len(container), true)
+			if err != nil {
+				return nil, err
+			}
+			result := make([]interface{}, 0, len(container)+1)
+			result = append(result, container[:i]...)
+			result = append(result, value)
+			return append(result, container[i:]...), nil
+		default:
+			return nil, fmt.Errorf("%w: %q - внутри не объект и не массив", ErrPathNotFound, token)
+		}
+	})
+}
+
+// remove удаляет значение по пути
+func remove(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w: нельзя удалить весь документ", ErrInvalidPatch)
+	}
+
+	return update(doc, path, func(node interface{}, token string) (interface{}, error) {
+		switch container := node.(type) {
+		case map[string]interface{}:
+			if _, ok := container[token]; !ok {
+				return nil, fmt.Errorf("%w: нет поля %q", ErrPathNotFound, token)
+			}
+			delete(container, token)
+			return container, nil
+		case []interface{}:
+			i, err := arrayIndex(token, len(container), false)
+			if err != nil {
+				return nil, err
+			}
+			result := make([]interface{}, 0, len(container)-1)
+			result = append(result, container[:i]...)
+			return append(result, container[i+1:]...), nil
+		default:
+			return nil, fmt.Errorf("%w: %q - внутри не объект и не массив", ErrPathNotFound, token)
+		}
+	})
+}
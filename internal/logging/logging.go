@@ -0,0 +1,98 @@
+// Package logging - структурные логи на log/slog
+//
+// Логгер пишет JSON (или текст для локальной работы) и сам добавляет
+// request_id из контекста: достаточно логировать через slog.InfoContext(ctx, ...)
+// и прочие *Context-функции, чтобы строку можно было связать с запросом.
+// Если в контексте есть спан OpenTelemetry, добавляются и trace_id со span_id
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Форматы вывода
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// New создаёт логгер с уровнем level (debug, info, warn, error) и форматом json или text
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	var handler slog.Handler
+	switch format {
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	case FormatText:
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("неизвестный формат логов %q, ожидается json или text", format)
+	}
+
+	return slog.New(contextHandler{handler}), nil
+}
+
+// ParseLevel разбирает уровень логирования
+func ParseLevel(level string) (slog.Level, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+		return 0, fmt.Errorf("неизвестный уровень логов %q, ожидается debug, info, warn или error", level)
+	}
+	return lvl, nil
+}
+
+// Err - ошибка как атрибут лога, чтобы везде был один ключ
+func Err(err error) slog.Attr {
+	return slog.Any("error", err)
+}
+
+// requestIDKey - ключ идентификатора запроса в контексте
+type requestIDKey struct{}
+
+// WithRequestID кладёт идентификатор запроса в контекст
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID возвращает идентификатор запроса из контекста, "" - вне запроса
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// contextHandler добавляет к записи request_id и trace_id/span_id из контекста
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		if id := RequestID(ctx); id != "" {
+			r.AddAttrs(slog.String("request_id", id))
+		}
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,91 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNew_RequestIDFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info", FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	logger.With("component", "test").InfoContext(ctx, "привет", "n", 1)
+	logger.DebugContext(ctx, "не должно попасть в лог")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Ожидали одну JSON-строку, получили %q: %v", buf.String(), err)
+	}
+	if entry["request_id"] != "req-1" {
+		t.Errorf("Ожидали request_id req-1, получили %v", entry["request_id"])
+	}
+	if entry["component"] != "test" || entry["msg"] != "привет" {
+		t.Errorf("Атрибуты логгера потерялись: %v", entry)
+	}
+}
+
+func TestNew_TraceIDFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _ := New(&buf, "info", FormatJSON)
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+	logger.InfoContext(ctx, "в спане")
+	logger.Info("без контекста")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	var entry map[string]interface{}
+	json.Unmarshal(lines[0], &entry)
+	if entry["trace_id"] != traceID.String() || entry["span_id"] != spanID.String() {
+		t.Errorf("Ожидали trace_id и span_id из контекста, получили %v", entry)
+	}
+	if bytes.Contains(lines[1], []byte("trace_id")) {
+		t.Errorf("Без спана trace_id не нужен: %s", lines[1])
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "verbose", FormatJSON); err == nil {
+		t.Error("Ожидали ошибку для неизвестного уровня")
+	}
+	if _, err := New(&bytes.Buffer{}, "info", "xml"); err == nil {
+		t.Error("Ожидали ошибку для неизвестного формата")
+	}
+
+	lvl, err := ParseLevel("WARN")
+	if err != nil || lvl != slog.LevelWarn {
+		t.Errorf("Уровень без учёта регистра: получили %v, %v", lvl, err)
+	}
+}
+
+func TestRedactQuery(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"category=food&limit=10", "category=food&limit=10"},
+		{"token=abc&q=1", "token=[REDACTED]&q=1"},
+		{"access_token=abc", "access_token=[REDACTED]"},
+		{"Password=x&password", "Password=[REDACTED]&password"},
+		{"api%5Fkey=k", "api%5Fkey=[REDACTED]"},
+	}
+
+	for _, tt := range tests {
+		if got := RedactQuery(tt.in); got != tt.want {
+			t.Errorf("RedactQuery(%q) = %q, ожидали %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package logging
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Redacted - чем заменяются секреты в логах
+const Redacted = "[REDACTED]"
+
+// sensitiveParams - части имён параметров, значения которых не пишем в лог
+var sensitiveParams = []string{"password", "passwd", "secret", "token", "api_key", "apikey", "auth", "signature"}
+
+// IsSensitive - похоже ли имя параметра или заголовка на секрет
+func IsSensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range sensitiveParams {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactQuery скрывает значения секретных параметров в строке запроса
+// Остальное оставляет как есть, в том числе порядок параметров
+func RedactQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	parts := strings.Split(rawQuery, "&")
+	for i, part := range parts {
+		name, _, found := strings.Cut(part, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if found && IsSensitive(name) {
+			parts[i] = part[:strings.IndexByte(part, '=')+1] + Redacted
+		}
+	}
+	return strings.Join(parts, "&")
+}
@@ -0,0 +1,145 @@
+// Package metrics - метрики Prometheus: HTTP, пул соединений, запросы к БД и бизнес-счётчики
+//
+// Все метрики регистрируются в собственном реестре, а не в глобальном
+// prometheus.DefaultRegisterer: так тесты могут создавать Metrics сколько угодно раз
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace - префикс всех метрик приложения
+const namespace = "expense_tracker"
+
+// UnmatchedRoute - метка route для запросов мимо маршрутов (404)
+// Иначе каждый случайный путь стал бы отдельным временным рядом
+const UnmatchedRoute = "unmatched"
+
+// MaxCategoryLabels - сколько разных категорий получают свою метку category
+// Категории задают пользователи, и без предела каждая новая была бы
+// новым временным рядом. Метку получают первые MaxCategoryLabels категорий,
+// встреченных с запуска, остальные считаются под OtherCategory
+const MaxCategoryLabels = 50
+
+// OtherCategory - метка category для категорий сверх MaxCategoryLabels
+const OtherCategory = "other"
+
+// Metrics - все метрики приложения
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	queryDuration   *prometheus.HistogramVec
+
+	expensesCreated *prometheus.CounterVec
+	expenseAmount   *prometheus.CounterVec
+
+	mu         sync.Mutex
+	categories map[string]bool // категории, у которых своя метка
+}
+
+// New создаёт метрики вместе с метриками рантайма Go и процесса
+func New() *Metrics {
+	m := &Metrics{
+		registry:   prometheus.NewRegistry(),
+		categories: make(map[string]bool),
+
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Количество HTTP-запросов по маршруту, методу и статусу.",
+		}, []string{"method", "route", "status"}),
+
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Время обработки HTTP-запроса.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "repository_query_duration_seconds",
+			Help:      "Время выполнения методов ExpenseRepository.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"method"}),
+
+		expensesCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "expenses_created_total",
+			Help:      "Количество созданных расходов по категории.",
+		}, []string{"category"}),
+
+		expenseAmount: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "expense_amount_total",
+			Help:      "Сумма созданных расходов по категории.",
+		}, []string{"category"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.requestDuration, m.queryDuration,
+		m.expensesCreated, m.expenseAmount,
+	)
+
+	return m
+}
+
+// RegisterDB добавляет статистику пула соединений (открытые, занятые, ожидания)
+// db - *sql.DB, для sqlx это db.DB
+func (m *Metrics) RegisterDB(db *sql.DB, name string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler отдаёт метрики в текстовом формате Prometheus
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// ObserveRequest учитывает HTTP-запрос
+// route - шаблон маршрута (/api/expenses/:id), а не путь, чтобы не плодить ряды
+func (m *Metrics) ObserveRequest(method, route string, status int, duration time.Duration) {
+	if route == "" {
+		route = UnmatchedRoute
+	}
+	m.requests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.requestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// ObserveQuery учитывает время метода репозитория
+func (m *Metrics) ObserveQuery(method string, duration time.Duration) {
+	m.queryDuration.WithLabelValues(method).Observe(duration.Seconds())
+}
+
+// ExpenseCreated учитывает созданный расход
+func (m *Metrics) ExpenseCreated(category string, amount float64) {
+	label := m.categoryLabel(category)
+	m.expensesCreated.WithLabelValues(label).Inc()
+	m.expenseAmount.WithLabelValues(label).Add(amount)
+}
+
+// categoryLabel - метка для категории: своя или OtherCategory, если мест нет
+func (m *Metrics) categoryLabel(category string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.categories[category] {
+		return category
+	}
+	if len(m.categories) >= MaxCategoryLabels {
+		return OtherCategory
+	}
+	m.categories[category] = true
+	return category
+}
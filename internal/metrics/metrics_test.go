@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+	return string(body)
+}
+
+func TestMetrics_Exposition(t *testing.T) {
+	m := New()
+	m.ObserveRequest(http.MethodGet, "/api/expenses/:id", 200, 20*time.Millisecond)
+	m.ObserveRequest(http.MethodGet, "/api/expenses/:id", 200, 30*time.Millisecond)
+	m.ObserveRequest(http.MethodGet, "", 404, time.Millisecond)
+	m.ObserveQuery("GetAll", 5*time.Millisecond)
+	m.ExpenseCreated("Еда", 150.5)
+	m.ExpenseCreated("Еда", 49.5)
+
+	out := scrape(t, m)
+	for _, want := range []string{
+		`expense_tracker_http_requests_total{method="GET",route="/api/expenses/:id",status="200"} 2`,
+		`expense_tracker_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`expense_tracker_http_request_duration_seconds_count{method="GET",route="/api/expenses/:id"} 2`,
+		`expense_tracker_repository_query_duration_seconds_count{method="GetAll"} 1`,
+		`expense_tracker_expenses_created_total{category="Еда"} 2`,
+		`expense_tracker_expense_amount_total{category="Еда"} 200`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("В выводе нет %s", want)
+		}
+	}
+}
+
+func TestNew_IndependentRegistries(t *testing.T) {
+	// Второй New не должен паниковать из-за повторной регистрации
+	a, b := New(), New()
+	a.ExpenseCreated("Еда", 1)
+
+	if strings.Contains(scrape(t, b), `category="Еда"`) {
+		t.Error("Метрики разных экземпляров не должны смешиваться")
+	}
+}
+
+func TestExpenseCreated_CategoryLabelsCapped(t *testing.T) {
+	m := New()
+	for i := 0; i < MaxCategoryLabels+10; i++ {
+		m.ExpenseCreated(fmt.Sprintf("Категория %d", i), 1)
+	}
+	m.ExpenseCreated("Категория 0", 1)
+
+	out := scrape(t, m)
+	for _, want := range []string{
+		`expense_tracker_expenses_created_total{category="Категория 0"} 2`,
+		`expense_tracker_expenses_created_total{category="other"} 10`,
+		`expense_tracker_expense_amount_total{category="other"} 10`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("В выводе нет %s", want)
+		}
+	}
+	if n := strings.Count(out, "expense_tracker_expenses_created_total{"); n != MaxCategoryLabels+1 {
+		t.Errorf("Ожидали %d рядов, получили %d", MaxCategoryLabels+1, n)
+	}
+}
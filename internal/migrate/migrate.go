@@ -0,0 +1,182 @@
+// Package migrate применяет миграции схемы из встроенных файлов
+//
+// Применённые версии хранятся в таблице schema_migrations вместе с контрольной
+// суммой up-скрипта: если файл поменяли после применения, раннер откажется
+// работать, а не будет молча расходиться со схемой. Каждая миграция идёт
+// в своей транзакции, а advisory lock не даёт двум экземплярам приложения
+// мигрировать одновременно
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrChecksumMismatch - применённую миграцию изменили
+	ErrChecksumMismatch = errors.New("миграция изменена после применения")
+	// ErrUnknownVersion - в БД есть версия, которой нет в бинарнике (он старее схемы)
+	ErrUnknownVersion = errors.New("в БД применена неизвестная миграция")
+	// ErrNoDown - у миграции нет down-скрипта, откатить её нельзя
+	ErrNoDown = errors.New("у миграции нет отката")
+)
+
+// Migration - одна версия схемы
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // sha256 от Up
+}
+
+// Applied - запись о применённой миграции из schema_migrations
+type Applied struct {
+	Version   int       `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+// Status - состояние миграции для команды migrate status
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+	Modified  bool // применена, но файл с тех пор поменялся
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Load читает миграции из fsys (обычно migrations.FS)
+// Файлы: NNN_описание.up.sql и необязательный NNN_описание.down.sql
+func Load(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, file := range files {
+		m := fileName.FindStringSubmatch(file)
+		if m == nil {
+			return nil, fmt.Errorf("файл миграции %q: ожидается имя вида 001_описание.up.sql", file)
+		}
+
+		version, err := strconv.Atoi(m[1])
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("файл миграции %q: неверная версия", file)
+		}
+
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != m[2] {
+			return nil, fmt.Errorf("версия %d: разные имена %q и %q", version, migration.Name, m[2])
+		}
+
+		if m[3] == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("версия %d (%s): нет up-скрипта", migration.Version, migration.Name)
+		}
+		migration.Checksum = checksum(migration.Up)
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Latest - последняя версия среди миграций, 0 - миграций нет
+func Latest(migrations []Migration) int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+func checksum(sql string) string {
+	sum := sha256.Sum256([]byte(sql))
+	return hex.EncodeToString(sum[:])
+}
+
+// verify сверяет применённые миграции с файлами
+func verify(migrations []Migration, applied []Applied) error {
+	known := make(map[int]Migration, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = m
+	}
+
+	for _, a := range applied {
+		m, ok := known[a.Version]
+		if !ok {
+			return fmt.Errorf("%w: версия %d (%s)", ErrUnknownVersion, a.Version, a.Name)
+		}
+		if m.Checksum != a.Checksum {
+			return fmt.Errorf("%w: версия %d (%s)", ErrChecksumMismatch, a.Version, a.Name)
+		}
+	}
+
+	return nil
+}
+
+// pending - миграции, которые ещё не применены, по возрастанию версии
+// Пропущенные старые версии (например, при слиянии веток) тоже сюда попадают
+func pending(migrations []Migration, applied []Applied) []Migration {
+	done := make(map[int]bool, len(applied))
+	for _, a := range applied {
+		done[a.Version] = true
+	}
+
+	var result []Migration
+	for _, m := range migrations {
+		if !done[m.Version] {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// status собирает состояние всех миграций
+func status(migrations []Migration, applied []Applied) []Status {
+	byVersion := make(map[int]Applied, len(applied))
+	for _, a := range applied {
+		byVersion[a.Version] = a
+	}
+
+	result := make([]Status, len(migrations))
+	for i, m := range migrations {
+		result[i] = Status{Migration: m}
+		if a, ok := byVersion[m.Version]; ok {
+			result[i].Applied = true
+			result[i].AppliedAt = a.AppliedAt
+			result[i].Modified = a.Checksum != m.Checksum
+		}
+	}
+	return result
+}
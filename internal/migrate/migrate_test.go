@@ -0,0 +1,86 @@
+package migrate
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dvoryadkinadv/expense-tracker/migrations"
+)
+
+func TestLoad_EmbeddedMigrations(t *testing.T) {
+	loaded, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatalf("Встроенные миграции не загрузились: %v", err)
+	}
+
+	for i, m := range loaded {
+		if m.Version != i+1 {
+			t.Errorf("Версии должны идти подряд с 1: на месте %d версия %d", i+1, m.Version)
+		}
+		if m.Down == "" {
+			t.Errorf("У %03d_%s нет down-скрипта", m.Version, m.Name)
+		}
+	}
+}
+
+func TestLoad_Validation(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+	}{
+		{"без up", fstest.MapFS{"001_init.down.sql": {Data: []byte("DROP TABLE t;")}}},
+		{"неверное имя", fstest.MapFS{"init.sql": {Data: []byte("SELECT 1;")}}},
+		{"разные имена", fstest.MapFS{
+			"001_init.up.sql":  {Data: []byte("SELECT 1;")},
+			"001_other.up.sql": {Data: []byte("SELECT 2;")},
+		}},
+	}
+
+	for _, tt := range tests {
+		if _, err := Load(tt.files); err == nil {
+			t.Errorf("%s: ожидали ошибку", tt.name)
+		}
+	}
+}
+
+func TestVerifyAndPending(t *testing.T) {
+	loaded, err := Load(fstest.MapFS{
+		"001_init.up.sql":      {Data: []byte("CREATE TABLE t (id INT);")},
+		"001_init.down.sql":    {Data: []byte("DROP TABLE t;")},
+		"002_add_name.up.sql":  {Data: []byte("ALTER TABLE t ADD name TEXT;")},
+		"003_add_index.up.sql": {Data: []byte("CREATE INDEX ON t(name);")},
+		"readme.txt":           {Data: []byte("не миграция")},
+	})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if Latest(loaded) != 3 {
+		t.Errorf("Latest: ожидали 3, получили %d", Latest(loaded))
+	}
+
+	// Вторую версию пропустили (например, пришла из другой ветки) - её тоже надо применить
+	applied := []Applied{
+		{Version: 1, Name: "init", Checksum: loaded[0].Checksum},
+		{Version: 3, Name: "add_index", Checksum: loaded[2].Checksum},
+	}
+	if err := verify(loaded, applied); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if p := pending(loaded, applied); len(p) != 1 || p[0].Version != 2 {
+		t.Errorf("Ожидали к применению только версию 2, получили %v", p)
+	}
+
+	edited := []Applied{{Version: 1, Name: "init", Checksum: "другая"}}
+	if err := verify(loaded, edited); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Ожидали ErrChecksumMismatch, получили %v", err)
+	}
+	if s := status(loaded, edited); !s[0].Applied || !s[0].Modified || s[1].Applied {
+		t.Errorf("Неожиданный статус: %+v", s)
+	}
+
+	future := []Applied{{Version: 4, Name: "from_newer_binary"}}
+	if err := verify(loaded, future); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("Ожидали ErrUnknownVersion, получили %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// lockKey - ключ advisory lock на время миграций
+// Любое число, лишь бы не совпало с другими блокировками в этой БД
+const lockKey int64 = 7_310_425_001
+
+// Runner применяет и откатывает миграции
+type Runner struct {
+	db         *sqlx.DB
+	migrations []Migration
+}
+
+// New загружает миграции из fsys и создаёт раннер
+func New(db *sqlx.DB, fsys fs.FS) (*Runner, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{db: db, migrations: migrations}, nil
+}
+
+// Latest - версия схемы, которую ожидает этот бинарник
+func (r *Runner) Latest() int {
+	return Latest(r.migrations)
+}
+
+// Version - последняя применённая версия, 0 - не применено ничего
+// Таблицу не создаёт, поэтому годится для проверок готовности
+func (r *Runner) Version(ctx context.Context) (int, error) {
+	var exists bool
+	if err := r.db.GetContext(ctx, &exists, `SELECT to_regclass('schema_migrations') IS NOT NULL`); err != nil {
+		return 0, fmt.Errorf("ошибка чтения версии схемы: %w", err)
+	}
+	if !exists {
+		return 0, nil
+	}
+
+	var version int
+	if err := r.db.GetContext(ctx, &version, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`); err != nil {
+		return 0, fmt.Errorf("ошибка чтения версии схемы: %w", err)
+	}
+	return version, nil
+}
+
+// Up применяет все неприменённые миграции и возвращает их
+func (r *Runner) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+
+	err := r.withLock(ctx, func(conn *sqlx.Conn) error {
+		applied, err := r.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := verify(r.migrations, applied); err != nil {
+			return err
+		}
+
+		for _, m := range pending(r.migrations, applied) {
+			err := inTx(ctx, conn, func(tx *sqlx.Tx) error {
+				if _, err := tx.ExecContext(ctx, m.Up); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx,
+					`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+					m.Version, m.Name, m.Checksum,
+				)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("миграция %03d_%s: %w", m.Version, m.Name, err)
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+
+	return done, err
+}
+
+// Down откатывает steps последних применённых миграций и возвращает их
+func (r *Runner) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+
+	err := r.withLock(ctx, func(conn *sqlx.Conn) error {
+		applied, err := r.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := verify(r.migrations, applied); err != nil {
+			return err
+		}
+
+		byVersion := make(map[int]Migration, len(r.migrations))
+		for _, m := range r.migrations {
+			byVersion[m.Version] = m
+		}
+
+		for i := len(applied) - 1; i >= 0 && len(done) < steps; i-- {
+			m := byVersion[applied[i].Version]
+			if m.Down == "" {
+				return fmt.Errorf("%w: %03d_%s", ErrNoDown, m.Version, m.Name)
+			}
+
+			err := inTx(ctx, conn, func(tx *sqlx.Tx) error {
+				if _, err := tx.ExecContext(ctx, m.Down); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, m.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("откат %03d_%s: %w", m.Version, m.Name, err)
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+
+	return done, err
+}
+
+// Status возвращает состояние каждой миграции
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	var result []Status
+
+	err := r.withLock(ctx, func(conn *sqlx.Conn) error {
+		applied, err := r.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		result = status(r.migrations, applied)
+		return nil
+	})
+
+	return result, err
+}
+
+// withLock выполняет fn на отдельном соединении под advisory lock
+// Блокировка сессионная, поэтому всё должно идти через одно соединение
+func (r *Runner) withLock(ctx context.Context, fn func(conn *sqlx.Conn) error) error {
+	conn, err := r.db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка подключения для миграций: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("ошибка блокировки миграций: %w", err)
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name VARCHAR(200) NOT NULL,
+			checksum VARCHAR(64) NOT NULL,
+			applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("ошибка создания schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// applied - применённые миграции по возрастанию версии
+func (r *Runner) applied(ctx context.Context, conn *sqlx.Conn) ([]Applied, error) {
+	var applied []Applied
+	err := conn.SelectContext(ctx, &applied,
+		`SELECT version, name, checksum, applied_at FROM schema_migrations ORDER BY version`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения schema_migrations: %w", err)
+	}
+	return applied, nil
+}
+
+func inTx(ctx context.Context, conn *sqlx.Conn, fn func(tx *sqlx.Tx) error) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
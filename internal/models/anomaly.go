@@ -0,0 +1,37 @@
+package models
+
+// AnomalyReason - почему расход посчитали необычным
+type AnomalyReason string
+
+const (
+	// AnomalyAmount - сумма сильно отличается от обычной для категории
+	AnomalyAmount AnomalyReason = "amount"
+	// AnomalyQuietCategory - расход в категории, где обычно почти ничего не бывает
+	AnomalyQuietCategory AnomalyReason = "quiet_category"
+)
+
+// AnomalyHint - подсказка о необычном расходе
+// Score - робастный z-score (через медиану и MAD),
+// Median - типичная сумма в категории, с которой сравнивали
+type AnomalyHint struct {
+	Reason  AnomalyReason `json:"reason"`
+	Score   float64       `json:"score"`
+	Median  float64       `json:"median"`
+	Samples int           `json:"samples"`
+	Message string        `json:"message"`
+}
+
+// Anomaly - элемент списка необычных расходов
+type Anomaly struct {
+	Expense Expense     `json:"expense"`
+	Hint    AnomalyHint `json:"hint"`
+}
+
+// AnomalyFilter - параметры поиска необычных расходов
+// Threshold = 0 означает порог по умолчанию
+type AnomalyFilter struct {
+	Category  string
+	DateFrom  string
+	DateTo    string
+	Threshold float64
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// AuditAction - что произошло с расходом
+type AuditAction string
+
+const (
+	AuditCreate  AuditAction = "create"
+	AuditUpdate  AuditAction = "update"
+	AuditDelete  AuditAction = "delete"
+	AuditRestore AuditAction = "restore"
+	AuditPurge   AuditAction = "purge"
+)
+
+// AuditEntry - запись журнала изменений
+// Записи только добавляются: менять и удалять их не даёт триггер в БД
+type AuditEntry struct {
+	ID        int64        `json:"id" db:"id"`
+	ExpenseID int64        `json:"expense_id" db:"expense_id"`
+	Action    AuditAction  `json:"action" db:"action"`
+	Actor     string       `json:"actor" db:"actor"`
+	Changes   AuditChanges `json:"changes" db:"changes"`
+	CreatedAt time.Time    `json:"created_at" db:"created_at"`
+}
+
+// FieldChange - значение поля до и после (nil - поля не было)
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditChanges - изменившиеся поля, хранятся в JSONB
+type AuditChanges map[string]FieldChange
+
+func (c AuditChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(map[string]FieldChange(c))
+}
+
+func (c *AuditChanges) Scan(src interface{}) error {
+	return scanJSON(src, c)
+}
+
+// AuditFilter - фильтр ленты изменений
+type AuditFilter struct {
+	ExpenseID int64
+	Actor     string
+	Action    AuditAction
+	From      string // дата или RFC3339, включительно
+	To        string // дата или RFC3339, не включительно
+	Limit     int
+	Offset    int
+	// Ascending - от старых к новым (для истории одного расхода)
+	Ascending bool
+}
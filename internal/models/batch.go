@@ -0,0 +1,51 @@
+package models
+
+// BatchOp - вид операции в пакете
+type BatchOp string
+
+const (
+	BatchCreate BatchOp = "create"
+	BatchUpdate BatchOp = "update"
+	BatchDelete BatchOp = "delete"
+)
+
+// BatchRequest - пакет операций над расходами (POST /api/expenses/batch)
+type BatchRequest struct {
+	// Atomic - всё в одной транзакции или ничего (по умолчанию true)
+	// С false каждая операция применяется сама по себе
+	Atomic     *bool            `json:"atomic"`
+	Operations []BatchOperation `json:"operations" binding:"required,min=1,dive"`
+}
+
+// BatchOperation - одна операция:
+//
+//	{"op": "create", "expense": {...}}
+//	{"op": "update", "id": 5, "changes": {...}}
+//	{"op": "delete", "id": 7, "version": 3}
+//
+// Для изменения ожидаемая версия передаётся в changes.version
+type BatchOperation struct {
+	Op      BatchOp               `json:"op" binding:"required,oneof=create update delete"`
+	ID      int64                 `json:"id,omitempty"`
+	Version int                   `json:"version,omitempty"` // для delete, 0 - без проверки
+	Expense *CreateExpenseRequest `json:"expense,omitempty"`
+	Changes *UpdateExpenseRequest `json:"changes,omitempty"`
+}
+
+// BatchItemResult - результат одной операции
+type BatchItemResult struct {
+	Index   int      `json:"index"`
+	Op      BatchOp  `json:"op"`
+	ID      int64    `json:"id,omitempty"`
+	Success bool     `json:"success"`
+	Expense *Expense `json:"expense,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// BatchResult - итог пакета
+type BatchResult struct {
+	Atomic    bool              `json:"atomic"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Results   []BatchItemResult `json:"results"`
+}
@@ -12,36 +12,168 @@ type Expense struct {
 	Description string    `json:"description" db:"description"`
 	Amount      float64   `json:"amount" db:"amount"`
 	Category    string    `json:"category" db:"category"`
+	Merchant    string    `json:"merchant" db:"merchant"`
+	Tags        Tags      `json:"tags" db:"tags"`
 	Date        time.Time `json:"date" db:"date"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	// Version растёт на каждое изменение, из неё строится ETag
+	Version   int       `json:"version" db:"version"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	// DeletedAt - когда расход попал в корзину; у обычных расходов nil
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+
+	// Rank и Highlight заполняются только при полнотекстовом поиске (?q=):
+	// релевантность и описание с подсвеченными совпадениями
+	Rank      *float64 `json:"rank,omitempty" db:"rank"`
+	Highlight string   `json:"highlight,omitempty" db:"highlight"`
+
+	// Anomaly заполняется только в ответе на создание,
+	// если расход выглядит необычно. В БД не хранится
+	Anomaly *AnomalyHint `json:"anomaly,omitempty" db:"-"`
 }
 
 // CreateExpenseRequest - то, что приходит от клиента при создании расхода
 // Валидацию делаю через теги binding - Gin сам всё проверит
+// Категорию можно не указывать, если её подберут правила
 type CreateExpenseRequest struct {
-	Description string  `json:"description" binding:"required,min=1,max=500"`
-	Amount      float64 `json:"amount" binding:"required,gt=0"`
-	Category    string  `json:"category" binding:"required,min=1,max=100"`
-	Date        string  `json:"date" binding:"required"` // формат: 2024-01-15
+	Description string   `json:"description" binding:"required,min=1,max=500"`
+	Amount      float64  `json:"amount" binding:"required,gt=0"`
+	Category    string   `json:"category" binding:"max=100"`
+	Merchant    string   `json:"merchant" binding:"max=200"`
+	Tags        []string `json:"tags" binding:"max=20,dive,min=1,max=50"`
+	Date        string   `json:"date" binding:"required"` // формат: 2024-01-15
 }
 
 // UpdateExpenseRequest - для обновления расхода
 // Все поля опциональные, обновляем только то, что прислали
 type UpdateExpenseRequest struct {
-	Description *string  `json:"description,omitempty" binding:"omitempty,min=1,max=500"`
-	Amount      *float64 `json:"amount,omitempty" binding:"omitempty,gt=0"`
-	Category    *string  `json:"category,omitempty" binding:"omitempty,min=1,max=100"`
-	Date        *string  `json:"date,omitempty"`
+	Description *string   `json:"description,omitempty" binding:"omitempty,min=1,max=500"`
+	Amount      *float64  `json:"amount,omitempty" binding:"omitempty,gt=0"`
+	Category    *string   `json:"category,omitempty" binding:"omitempty,min=1,max=100"`
+	Merchant    *string   `json:"merchant,omitempty" binding:"omitempty,max=200"`
+	Tags        *[]string `json:"tags,omitempty" binding:"omitempty,max=20,dive,min=1,max=50"`
+	Date        *string   `json:"date,omitempty"`
+
+	// Version - ожидаемая версия расхода: если он успел измениться,
+	// обновление отклоняется. Можно прислать в теле или в заголовке If-Match
+	Version *int `json:"version,omitempty" binding:"omitempty,gt=0"`
+}
+
+// ReplaceExpenseRequest - расход целиком, для PUT
+// Чего нет в запросе, то очищается: так можно убрать продавца или теги
+type ReplaceExpenseRequest struct {
+	Description string   `json:"description" binding:"required,min=1,max=500"`
+	Amount      float64  `json:"amount" binding:"required,gt=0"`
+	Category    string   `json:"category" binding:"required,max=100"`
+	Merchant    string   `json:"merchant" binding:"max=200"`
+	Tags        []string `json:"tags" binding:"max=20,dive,min=1,max=50"`
+	Date        string   `json:"date" binding:"required"` // формат: 2024-01-15
+
+	Version *int `json:"version,omitempty" binding:"omitempty,gt=0"`
+}
+
+// NewReplaceExpenseRequest - расход в виде запроса на замену
+// С этого документа начинается PATCH
+func NewReplaceExpenseRequest(e Expense) ReplaceExpenseRequest {
+	tags := []string(e.Tags)
+	if tags == nil {
+		tags = []string{}
+	}
+	return ReplaceExpenseRequest{
+		Description: e.Description,
+		Amount:      e.Amount,
+		Category:    e.Category,
+		Merchant:    e.Merchant,
+		Tags:        tags,
+		Date:        e.Date.Format("2006-01-02"),
+	}
+}
+
+// Changes - замена как обновление, в котором заданы все поля
+func (r ReplaceExpenseRequest) Changes() UpdateExpenseRequest {
+	tags := r.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return UpdateExpenseRequest{
+		Description: &r.Description,
+		Amount:      &r.Amount,
+		Category:    &r.Category,
+		Merchant:    &r.Merchant,
+		Tags:        &tags,
+		Date:        &r.Date,
+		Version:     r.Version,
+	}
 }
 
 // ExpenseFilter - фильтры для списка расходов
 // Сделать фильтрацию гибкой, но не переусложнить
 type ExpenseFilter struct {
+	Query    string // полнотекстовый поиск по описанию и продавцу
 	Category string
 	DateFrom string
 	DateTo   string
 	Limit    int
 	Offset   int
+
+	// Categories - любая из перечисленных категорий,
+	// ExcludeCategories - ни одна из них (category!=)
+	Categories        []string
+	ExcludeCategories []string
+	AmountMin         *float64
+	AmountMax         *float64
+	CreatedAfter      string // дата или RFC3339
+	// DescriptionContains - подстрока в описании без учёта регистра
+	// (в отличие от Query - без морфологии, ищется буквально)
+	DescriptionContains string
+	// Expression - условие на языке фильтров (filter=), см. пакет filterql:
+	// category:food AND amount>500 OR tag:trip
+	Expression string
+
+	// ViewID - запустить сохранённое представление: его условия заменяют
+	// условия фильтра, сортировка берётся из него, если не задана явно
+	ViewID int64
+
+	// Sort - поле сортировки, "-" в начале - по убыванию: -date, amount, ...
+	// Пустая строка - по умолчанию (-date, а при поиске - по релевантности)
+	Sort string
+
+	// Cursor - непрозрачный курсор из next_cursor/prev_cursor
+	// С ним Offset игнорируется
+	Cursor string
+	// WithTotal - посчитать total_count (лишний запрос, поэтому по желанию)
+	WithTotal bool
+	// Keyset - разобранный курсор, заполняет сервис
+	Keyset *Keyset
+}
+
+// SortFields - поля, по которым можно сортировать список
+// Всё остальное отклоняем: имя поля попадает в SQL
+var SortFields = map[string]bool{
+	"date":       true,
+	"amount":     true,
+	"created_at": true,
+	"category":   true,
+}
+
+// DefaultSort - сортировка списка по умолчанию: новые сверху
+const DefaultSort = "-date"
+
+// Keyset - позиция в списке для постраничного вывода без OFFSET
+// Value - значение ключа сортировки у крайней записи, ID - её id
+type Keyset struct {
+	Value    string
+	ID       int64
+	Backward bool // true - страница перед этой позицией
+}
+
+// ExpensePage - страница списка расходов
+type ExpensePage struct {
+	Items      []Expense
+	Limit      int
+	NextCursor string
+	PrevCursor string
+	TotalCount *int
 }
 
 // ExpenseStats - статистика по расходам
@@ -51,3 +183,9 @@ type ExpenseStats struct {
 	AverageAmount float64            `json:"average_amount"`
 	ByCategory    map[string]float64 `json:"by_category"`
 }
+
+// CategorySuggestion - предполагаемая категория с уверенностью от 0 до 1
+type CategorySuggestion struct {
+	Category   string  `json:"category"`
+	Confidence float64 `json:"confidence"`
+}
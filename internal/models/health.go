@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Статусы проверок готовности
+const (
+	// HealthOK - всё в порядке
+	HealthOK = "ok"
+	// HealthDegraded - есть проблема, но запросы обслуживать можно
+	// (например, фоновая задача падает); экземпляр остаётся готовым
+	HealthDegraded = "degraded"
+	// HealthFail - без этой зависимости сервис не работает, экземпляр не готов
+	HealthFail = "fail"
+)
+
+// HealthCheck - результат одной проверки
+type HealthCheck struct {
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+	// Details - подробности проверки: версия схемы, время последнего прогона задачи
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// HealthReport - ответ /readyz: общий статус и каждая проверка
+type HealthReport struct {
+	Status    string                 `json:"status"`
+	CheckedAt time.Time              `json:"checked_at"`
+	Checks    map[string]HealthCheck `json:"checks"`
+}
+
+// Ready - можно ли направлять на экземпляр запросы
+func (r HealthReport) Ready() bool {
+	return r.Status != HealthFail
+}
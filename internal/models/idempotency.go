@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// IdempotencyRecord - сохранённый ответ на запрос с Idempotency-Key
+// Ключи у каждого пользователя (X-User-ID) свои
+type IdempotencyRecord struct {
+	Owner string `db:"owner"`
+	Key   string `db:"key"`
+	// Fingerprint - хеш метода, пути и тела запроса:
+	// тот же ключ с другим запросом - ошибка клиента
+	Fingerprint string `db:"fingerprint"`
+	// StatusCode == 0 - первый запрос ещё выполняется
+	StatusCode  int       `db:"status_code"`
+	ContentType string    `db:"content_type"`
+	Body        []byte    `db:"body"`
+	CreatedAt   time.Time `db:"created_at"`
+	ExpiresAt   time.Time `db:"expires_at"`
+}
+
+// Completed - ответ уже сохранён и его можно отдать повторно
+func (r IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Rule - пользовательское правило автокатегоризации
+// Правила применяются по убыванию Priority: если поле уже поменяло
+// более приоритетное правило, менее приоритетные его не трогают
+type Rule struct {
+	ID         int64          `json:"id" db:"id"`
+	Name       string         `json:"name" db:"name"`
+	Priority   int            `json:"priority" db:"priority"`
+	Enabled    bool           `json:"enabled" db:"enabled"`
+	Stop       bool           `json:"stop" db:"stop"` // не применять правила ниже после срабатывания
+	Conditions RuleConditions `json:"conditions" db:"conditions"`
+	Actions    RuleActions    `json:"actions" db:"actions"`
+	CreatedAt  time.Time      `json:"created_at" db:"created_at"`
+}
+
+// RuleConditions - условия правила, все заданные должны выполниться
+type RuleConditions struct {
+	DescriptionRegex string   `json:"description_regex,omitempty"`
+	AmountMin        *float64 `json:"amount_min,omitempty"`
+	AmountMax        *float64 `json:"amount_max,omitempty"`
+	Merchant         string   `json:"merchant,omitempty"` // подстрока, без учёта регистра
+}
+
+// RuleActions - что делает правило
+// В RewriteDescription можно ссылаться на группы из регулярки: $1, ${name}
+type RuleActions struct {
+	SetCategory        string   `json:"set_category,omitempty"`
+	AddTags            []string `json:"add_tags,omitempty"`
+	RewriteDescription string   `json:"rewrite_description,omitempty"`
+}
+
+// Value и Scan - условия и действия лежат в JSONB
+func (c RuleConditions) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+func (c *RuleConditions) Scan(src interface{}) error {
+	return scanJSON(src, c)
+}
+
+func (a RuleActions) Value() (driver.Value, error) {
+	return json.Marshal(a)
+}
+
+func (a *RuleActions) Scan(src interface{}) error {
+	return scanJSON(src, a)
+}
+
+func scanJSON(src interface{}, dest interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, dest)
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	case nil:
+		return nil
+	default:
+		return errors.New("неподдерживаемый тип для JSON-колонки")
+	}
+}
+
+// RuleRequest - создание и изменение правила
+type RuleRequest struct {
+	Name       string         `json:"name" binding:"required,min=1,max=200"`
+	Priority   int            `json:"priority"`
+	Enabled    *bool          `json:"enabled"` // по умолчанию true
+	Stop       bool           `json:"stop"`
+	Conditions RuleConditions `json:"conditions"`
+	Actions    RuleActions    `json:"actions"`
+}
+
+// ApplyRulesRequest - повторное применение правил к существующим расходам
+// DryRun = true только показывает, что изменится
+type ApplyRulesRequest struct {
+	DryRun   bool   `json:"dry_run"`
+	Category string `json:"category"`
+	DateFrom string `json:"date_from"`
+	DateTo   string `json:"date_to"`
+}
+
+// RuleChange - что правила поменяли (или поменяют) в одном расходе
+type RuleChange struct {
+	ExpenseID int64       `json:"expense_id"`
+	RuleIDs   []int64     `json:"rule_ids"`
+	Before    RuleSubject `json:"before"`
+	After     RuleSubject `json:"after"`
+}
+
+// RuleSubject - поля расхода, которые могут менять правила
+type RuleSubject struct {
+	Description string `json:"description"`
+	Category    string `json:"category"`
+	Tags        Tags   `json:"tags"`
+}
+
+// ApplyRulesResult - итог применения правил
+type ApplyRulesResult struct {
+	DryRun  bool         `json:"dry_run"`
+	Checked int          `json:"checked"`
+	Changes []RuleChange `json:"changes"`
+}
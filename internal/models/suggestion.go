@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// SuggestField - по какому полю подсказываем
+type SuggestField string
+
+const (
+	SuggestDescription SuggestField = "description"
+	SuggestCategory    SuggestField = "category"
+	SuggestMerchant    SuggestField = "merchant"
+)
+
+// Suggestion - вариант автодополнения
+// Для описаний сразу отдаём обычную категорию, сумму и продавца,
+// чтобы форма заполнялась одним нажатием
+type Suggestion struct {
+	Value    string    `json:"value" db:"value"`
+	Category string    `json:"category,omitempty" db:"category"`
+	Amount   float64   `json:"amount,omitempty" db:"amount"`
+	Merchant string    `json:"merchant,omitempty" db:"merchant"`
+	Uses     int       `json:"uses" db:"uses"`
+	LastUsed time.Time `json:"last_used" db:"last_used"`
+	Score    float64   `json:"score" db:"score"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Tags - список тегов расхода
+// В БД хранится как TEXT[], в JSON всегда массив (даже пустой)
+type Tags []string
+
+// Value сохраняет теги как массив Postgres
+func (t Tags) Value() (driver.Value, error) {
+	if t == nil {
+		return pq.StringArray{}.Value()
+	}
+	return pq.StringArray(t).Value()
+}
+
+// Scan читает массив Postgres
+func (t *Tags) Scan(src interface{}) error {
+	var arr pq.StringArray
+	if err := arr.Scan(src); err != nil {
+		return err
+	}
+	*t = Tags(arr)
+	return nil
+}
+
+// MarshalJSON отдаёт [] вместо null, фронтенду так проще
+func (t Tags) MarshalJSON() ([]byte, error) {
+	if t == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]string(t))
+}
+
+// Has проверяет, есть ли тег (без учёта регистра)
+func (t Tags) Has(tag string) bool {
+	for _, existing := range t {
+		if strings.EqualFold(existing, tag) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// SavedView - сохранённый набор фильтров ("бизнес-ланчи за квартал")
+// Владелец видит и меняет свои представления, остальным они видны только с Shared
+type SavedView struct {
+	ID        int64       `json:"id" db:"id"`
+	Name      string      `json:"name" db:"name"`
+	Owner     string      `json:"owner" db:"owner"`
+	Shared    bool        `json:"shared" db:"shared"`
+	Filter    ViewFilter  `json:"filter" db:"filter"`
+	Sort      string      `json:"sort" db:"sort"`
+	Columns   ViewColumns `json:"columns" db:"columns"`
+	CreatedAt time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+// ViewFilter - условия представления
+// Те же поля, что в ExpenseFilter, но без пагинации, плюс Period -
+// относительный период, который пересчитывается при каждом запуске
+type ViewFilter struct {
+	Query               string   `json:"q,omitempty"`
+	Category            string   `json:"category,omitempty"`
+	Categories          []string `json:"categories,omitempty"`
+	ExcludeCategories   []string `json:"exclude_categories,omitempty"`
+	DateFrom            string   `json:"date_from,omitempty"`
+	DateTo              string   `json:"date_to,omitempty"`
+	AmountMin           *float64 `json:"amount_min,omitempty"`
+	AmountMax           *float64 `json:"amount_max,omitempty"`
+	CreatedAfter        string   `json:"created_after,omitempty"`
+	DescriptionContains string   `json:"description_contains,omitempty"`
+	Expression          string   `json:"filter,omitempty"`
+
+	// Period - this_month, last_month, this_quarter, last_quarter,
+	// this_year, last_30_days; задаёт DateFrom/DateTo на момент запуска
+	Period string `json:"period,omitempty"`
+}
+
+// Apply переносит условия представления в фильтр списка
+// Пагинация и сортировка фильтра остаются как были
+func (v ViewFilter) Apply(filter ExpenseFilter) ExpenseFilter {
+	filter.Query = v.Query
+	filter.Category = v.Category
+	filter.Categories = v.Categories
+	filter.ExcludeCategories = v.ExcludeCategories
+	filter.DateFrom = v.DateFrom
+	filter.DateTo = v.DateTo
+	filter.AmountMin = v.AmountMin
+	filter.AmountMax = v.AmountMax
+	filter.CreatedAfter = v.CreatedAfter
+	filter.DescriptionContains = v.DescriptionContains
+	filter.Expression = v.Expression
+	return filter
+}
+
+// Value и Scan - фильтр лежит в JSONB
+func (v ViewFilter) Value() (driver.Value, error) {
+	return json.Marshal(v)
+}
+
+func (v *ViewFilter) Scan(src interface{}) error {
+	return scanJSON(src, v)
+}
+
+// ViewColumns - какие колонки показывать в таблице, для клиента
+type ViewColumns []string
+
+func (c ViewColumns) Value() (driver.Value, error) {
+	if c == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]string(c))
+}
+
+func (c *ViewColumns) Scan(src interface{}) error {
+	return scanJSON(src, c)
+}
+
+// MarshalJSON отдаёт [] вместо null
+func (c ViewColumns) MarshalJSON() ([]byte, error) {
+	if c == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]string(c))
+}
+
+// ViewColumnNames - колонки, которые можно выбрать в представлении
+var ViewColumnNames = map[string]bool{
+	"id":          true,
+	"description": true,
+	"amount":      true,
+	"category":    true,
+	"merchant":    true,
+	"tags":        true,
+	"date":        true,
+	"created_at":  true,
+}
+
+// SavedViewRequest - создание и изменение представления
+type SavedViewRequest struct {
+	Name    string     `json:"name" binding:"required,min=1,max=100"`
+	Shared  bool       `json:"shared"`
+	Filter  ViewFilter `json:"filter"`
+	Sort    string     `json:"sort"`
+	Columns []string   `json:"columns"`
+}
@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// MaxMemoryBuckets - сколько корзин хранит MemoryStore
+// Purge идёт раз в несколько минут, а между ними поток запросов с разных
+// адресов занимал бы память без предела. На пределе вытесняется корзина,
+// которой дольше всех не пользовались
+const MaxMemoryBuckets = 100_000
+
+// MemoryStore - корзины в памяти процесса
+// У каждого экземпляра сервиса свои: при N экземплярах за балансировщиком
+// клиент фактически получает до N лимитов. Для общего лимита есть хранилище в БД
+type MemoryStore struct {
+	mu         sync.Mutex
+	buckets    map[string]*memoryBucket
+	recent     *list.List // ключи корзин, недавно использованные - в начале
+	maxBuckets int
+	now        func() time.Time
+}
+
+// memoryBucket помнит лимит, чтобы Purge знал, когда корзина полная,
+// и своё место в очереди на вытеснение
+type memoryBucket struct {
+	bucket
+	limit Limit
+	elem  *list.Element
+}
+
+// NewMemoryStore создаёт пустое хранилище на MaxMemoryBuckets корзин
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:    make(map[string]*memoryBucket),
+		recent:     list.New(),
+		maxBuckets: MaxMemoryBuckets,
+		now:        time.Now,
+	}
+}
+
+// Take забирает токен; новая корзина начинается полной
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[key]
+	if ok {
+		s.recent.MoveToFront(b.elem)
+	} else {
+		if len(s.buckets) >= s.maxBuckets {
+			s.remove(s.recent.Back().Value.(string))
+		}
+		b = &memoryBucket{bucket: bucket{tokens: float64(limit.Burst), updated: now}}
+		b.elem = s.recent.PushFront(key)
+		s.buckets[key] = b
+	}
+	b.limit = limit
+
+	allowed := b.take(limit, now)
+	return NewResult(limit, b.tokens, allowed), nil
+}
+
+// Purge удаляет полные корзины, чтобы память не росла от разовых клиентов
+func (s *MemoryStore) Purge(ctx context.Context) (int64, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged int64
+	for key, b := range s.buckets {
+		if b.full(b.limit, now) {
+			s.remove(key)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// remove удаляет корзину; вызывается под s.mu
+func (s *MemoryStore) remove(key string) {
+	if b, ok := s.buckets[key]; ok {
+		s.recent.Remove(b.elem)
+		delete(s.buckets, key)
+	}
+}
@@ -0,0 +1,171 @@
+// Package ratelimit - ограничение частоты запросов алгоритмом token bucket
+//
+// У каждого ключа (IP клиента в своей группе маршрутов) есть
+// корзина на Burst токенов. Запрос забирает токен, а корзина пополняется
+// со скоростью Limit.Rate. Пустая корзина - запрос отклоняется до пополнения.
+// Так разрешены короткие всплески до Burst, но не больше Rate в среднем
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Store хранит корзины
+// Память - для одного экземпляра, общая БД - чтобы лимит действовал на все сразу
+type Store interface {
+	// Take забирает токен из корзины key
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+	// Purge удаляет полные корзины: они ничем не отличаются от отсутствующих
+	Purge(ctx context.Context) (int64, error)
+}
+
+// Limit - Count запросов за Per, всплеск до Burst
+// В конфигурации пишется как "10/s:20", "100/m" (Burst = Count) или "off"
+type Limit struct {
+	Count int
+	Per   time.Duration
+	Burst int
+}
+
+// Enabled - лимит задан; "off" - без ограничения
+func (l Limit) Enabled() bool {
+	return l.Count > 0 && l.Per > 0 && l.Burst > 0
+}
+
+// Rate - скорость пополнения, токенов в секунду
+func (l Limit) Rate() float64 {
+	return float64(l.Count) / l.Per.Seconds()
+}
+
+// periods - единицы в записи лимита
+var periods = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+}
+
+// ParseLimit разбирает "10/s:20"
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "off" || s == "0" {
+		return Limit{}, nil
+	}
+
+	invalid := fmt.Errorf("ожидается лимит вида 10/s, 100/m:20 или off, получили %q", s)
+
+	rate, burst, hasBurst := strings.Cut(s, ":")
+	count, unit, ok := strings.Cut(rate, "/")
+	if !ok {
+		return Limit{}, invalid
+	}
+
+	var l Limit
+	var err error
+	if l.Count, err = strconv.Atoi(count); err != nil || l.Count <= 0 {
+		return Limit{}, invalid
+	}
+	if l.Per, ok = periods[unit]; !ok {
+		return Limit{}, invalid
+	}
+	l.Burst = l.Count
+	if hasBurst {
+		if l.Burst, err = strconv.Atoi(burst); err != nil || l.Burst <= 0 {
+			return Limit{}, invalid
+		}
+	}
+	return l, nil
+}
+
+// String - обратно в "10/s:20"
+func (l Limit) String() string {
+	if !l.Enabled() {
+		return "off"
+	}
+	unit := "s"
+	for u, d := range periods {
+		if d == l.Per {
+			unit = u
+		}
+	}
+	return fmt.Sprintf("%d/%s:%d", l.Count, unit, l.Burst)
+}
+
+// UnmarshalText - для файла конфигурации и переменных окружения
+func (l *Limit) UnmarshalText(text []byte) error {
+	parsed, err := ParseLimit(string(text))
+	if err != nil {
+		return err
+	}
+	*l = parsed
+	return nil
+}
+
+// MarshalText - для --print-config
+func (l Limit) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// Result - решение по запросу и состояние корзины после него
+type Result struct {
+	Allowed bool
+	// Limit - размер корзины
+	Limit int
+	// Remaining - сколько запросов ещё можно сделать сразу
+	Remaining int
+	// RetryAfter - через сколько появится токен (0, если запрос разрешён)
+	RetryAfter time.Duration
+	// Reset - через сколько корзина снова будет полной
+	Reset time.Duration
+}
+
+// NewResult считает Result по числу токенов, оставшихся в корзине после запроса
+func NewResult(limit Limit, tokens float64, allowed bool) Result {
+	rate := limit.Rate()
+	r := Result{
+		Allowed:   allowed,
+		Limit:     limit.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(limit.Burst) - tokens) / rate),
+	}
+	if !allowed {
+		r.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	return r
+}
+
+func seconds(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
+
+// bucket - корзина: токены на момент updated
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// take пополняет корзину на прошедшее время и забирает токен, если он есть
+func (b *bucket) take(limit Limit, now time.Time) bool {
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.Rate())
+		b.updated = now
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// full - корзина полная к моменту now
+func (b *bucket) full(limit Limit, now time.Time) bool {
+	return b.tokens+now.Sub(b.updated).Seconds()*limit.Rate() >= float64(limit.Burst)
+}
@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Limit
+	}{
+		{"10/s:20", Limit{Count: 10, Per: time.Second, Burst: 20}},
+		{"100/m", Limit{Count: 100, Per: time.Minute, Burst: 100}},
+		{" 5/h:1 ", Limit{Count: 5, Per: time.Hour, Burst: 1}},
+		{"off", Limit{}},
+	}
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("%q: ожидали %+v, получили %+v, %v", tt.in, tt.want, got, err)
+		}
+		if back, _ := ParseLimit(got.String()); back != got {
+			t.Errorf("%q: String не разбирается обратно: %q", tt.in, got.String())
+		}
+	}
+
+	for _, bad := range []string{"", "10", "10/d", "-1/s", "10/s:0", "10/s:x"} {
+		if _, err := ParseLimit(bad); err == nil {
+			t.Errorf("%q: ожидали ошибку", bad)
+		}
+	}
+}
+
+func TestMemoryStore_TokenBucket(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+	limit := Limit{Count: 1, Per: time.Second, Burst: 3}
+
+	// Полная корзина разрешает всплеск до Burst
+	for i := 2; i >= 0; i-- {
+		r, _ := store.Take(ctx, "alice", limit)
+		if !r.Allowed || r.Remaining != i {
+			t.Fatalf("Ожидали разрешение с остатком %d, получили %+v", i, r)
+		}
+	}
+
+	r, _ := store.Take(ctx, "alice", limit)
+	if r.Allowed || r.RetryAfter != time.Second || r.Reset != 3*time.Second {
+		t.Errorf("Пустая корзина: ожидали отказ, повтор через 1s, полная через 3s, получили %+v", r)
+	}
+
+	// У другого ключа своя корзина
+	if r, _ := store.Take(ctx, "bob", limit); !r.Allowed {
+		t.Error("Лимит одного клиента не должен задевать другого")
+	}
+
+	// Через полсекунды токена ещё нет, через секунду - есть
+	now = now.Add(500 * time.Millisecond)
+	if r, _ := store.Take(ctx, "alice", limit); r.Allowed || r.RetryAfter != 500*time.Millisecond {
+		t.Errorf("Ожидали отказ с повтором через 500ms, получили %+v", r)
+	}
+	now = now.Add(500 * time.Millisecond)
+	if r, _ := store.Take(ctx, "alice", limit); !r.Allowed {
+		t.Errorf("Через секунду токен должен появиться, получили %+v", r)
+	}
+}
+
+func TestMemoryStore_Purge(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+	limit := Limit{Count: 1, Per: time.Second, Burst: 2}
+
+	store.Take(ctx, "alice", limit)
+	store.Take(ctx, "bob", limit)
+	store.Take(ctx, "bob", limit)
+
+	now = now.Add(time.Second)
+	if purged, _ := store.Purge(ctx); purged != 1 {
+		t.Errorf("Через секунду наполнилась только корзина alice, удалено %d", purged)
+	}
+	now = now.Add(time.Second)
+	if purged, _ := store.Purge(ctx); purged != 1 || len(store.buckets) != 0 {
+		t.Errorf("Ожидали удаление корзины bob, удалено %d, осталось %d", purged, len(store.buckets))
+	}
+}
+
+func TestMemoryStore_Bounded(t *testing.T) {
+	store := NewMemoryStore()
+	store.maxBuckets = 2
+	ctx := context.Background()
+	limit := Limit{Count: 1, Per: time.Minute, Burst: 1}
+
+	store.Take(ctx, "alice", limit)
+	store.Take(ctx, "bob", limit)
+	store.Take(ctx, "alice", limit) // alice использовалась последней
+
+	store.Take(ctx, "carol", limit)
+	if len(store.buckets) != 2 || store.recent.Len() != 2 {
+		t.Fatalf("Ожидали не больше 2 корзин, получили %d", len(store.buckets))
+	}
+	if _, ok := store.buckets["bob"]; ok {
+		t.Error("Вытесняться должна корзина, которой дольше всех не пользовались")
+	}
+	if result, _ := store.Take(ctx, "alice", limit); result.Allowed {
+		t.Error("Корзина alice не должна была сброситься")
+	}
+}
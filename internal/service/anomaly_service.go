@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+)
+
+// HistoryRepository - то, что нужно детектору аномалий от хранилища
+// Отдельный маленький интерфейс, чтобы не тащить весь ExpenseRepository
+type HistoryRepository interface {
+	GetSince(ctx context.Context, since time.Time) ([]models.Expense, error)
+}
+
+// AnomalyConfig - настройки детектора
+type AnomalyConfig struct {
+	// Threshold - порог робастного z-score. 3.5 - классическое значение
+	// из статьи Iglewicz и Hoaglin
+	Threshold float64
+	// MinSamples - сколько расходов в категории нужно, чтобы судить о сумме
+	MinSamples int
+	// LookbackDays - сколько дней истории учитываем
+	LookbackDays int
+	// QuietMaxCount - категория считается "тихой", если за период
+	// в ней было не больше стольких расходов
+	QuietMaxCount int
+	// MinHistory - меньше этого общего числа расходов про "тихие"
+	// категории не говорим: у нового пользователя все категории новые
+	MinHistory int
+}
+
+// DefaultAnomalyConfig возвращает настройки по умолчанию
+func DefaultAnomalyConfig() AnomalyConfig {
+	return AnomalyConfig{
+		Threshold:     3.5,
+		MinSamples:    5,
+		LookbackDays:  365,
+		QuietMaxCount: 1,
+		MinHistory:    20,
+	}
+}
+
+// maxAnomalyScore - ограничение score, когда разброс в категории нулевой
+// (все суммы одинаковые). Бесконечность в JSON не закодировать
+const maxAnomalyScore = 99
+
+// AnomalyService ищет необычные расходы
+type AnomalyService struct {
+	repo HistoryRepository
+	cfg  AnomalyConfig
+	now  func() time.Time
+}
+
+// NewAnomalyService создаёт новый сервис поиска аномалий
+func NewAnomalyService(repo HistoryRepository, cfg AnomalyConfig) *AnomalyService {
+	return &AnomalyService{repo: repo, cfg: cfg, now: time.Now}
+}
+
+// Check проверяет новый расход на фоне истории
+// История - расходы за [date-lookback, date): задним числом расход
+// не сравнивается с теми, что были после него.
+// Возвращает nil, если ничего необычного нет
+func (s *AnomalyService) Check(ctx context.Context, expense *models.Expense) (*models.AnomalyHint, error) {
+	since := expense.Date.AddDate(0, 0, -s.cfg.LookbackDays)
+
+	history, err := s.repo.GetSince(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.assess(*expense, history, s.cfg.Threshold), nil
+}
+
+// List возвращает необычные расходы за период
+// По умолчанию смотрим последние 30 дней
+func (s *AnomalyService) List(ctx context.Context, filter models.AnomalyFilter) ([]models.Anomaly, error) {
+	to := s.now()
+	if filter.DateTo != "" {
+		parsed, err := time.Parse("2006-01-02", filter.DateTo)
+		if err != nil {
+			return nil, invalidAs(ErrInvalidFilter, "date_to", "неверный формат даты, используйте YYYY-MM-DD")
+		}
+		to = parsed
+	}
+
+	from := to.AddDate(0, 0, -30)
+	if filter.DateFrom != "" {
+		parsed, err := time.Parse("2006-01-02", filter.DateFrom)
+		if err != nil {
+			return nil, invalidAs(ErrInvalidFilter, "date_from", "неверный формат даты, используйте YYYY-MM-DD")
+		}
+		from = parsed
+	}
+
+	threshold := filter.Threshold
+	if threshold <= 0 {
+		threshold = s.cfg.Threshold
+	}
+
+	history, err := s.repo.GetSince(ctx, from.AddDate(0, 0, -s.cfg.LookbackDays))
+	if err != nil {
+		return nil, err
+	}
+
+	anomalies := []models.Anomaly{}
+	for _, e := range history {
+		if e.Date.Before(from) || e.Date.After(to) {
+			continue
+		}
+		if filter.Category != "" && e.Category != filter.Category {
+			continue
+		}
+
+		// Каждый расход - на фоне своей истории, как при создании в Check:
+		// иначе выброс сам попадал бы в выборку и уменьшал свой score
+		if hint := s.assess(e, history, threshold); hint != nil {
+			anomalies = append(anomalies, models.Anomaly{Expense: e, Hint: *hint})
+		}
+	}
+
+	// Самые подозрительные - сверху
+	sort.SliceStable(anomalies, func(i, j int) bool {
+		return math.Abs(anomalies[i].Hint.Score) > math.Abs(anomalies[j].Hint.Score)
+	})
+
+	return anomalies, nil
+}
+
+// assess проверяет расход на фоне истории за [date-lookback, date)
+// Общая часть Check и List: один и тот же расход получает один вердикт
+func (s *AnomalyService) assess(expense models.Expense, history []models.Expense, threshold float64) *models.AnomalyHint {
+	since := expense.Date.AddDate(0, 0, -s.cfg.LookbackDays)
+
+	var sample []float64
+	total := 0
+	for _, e := range history {
+		if e.ID == expense.ID || e.Date.Before(since) || !e.Date.Before(expense.Date) {
+			continue
+		}
+		total++
+		if e.Category == expense.Category {
+			sample = append(sample, e.Amount)
+		}
+	}
+
+	return s.evaluate(expense.Amount, sample, len(sample), total, threshold)
+}
+
+// evaluate - сама проверка: сначала "тихая" категория, потом сумма
+// others - сколько других расходов в категории, total - сколько всего в истории
+func (s *AnomalyService) evaluate(amount float64, sample []float64, others, total int, threshold float64) *models.AnomalyHint {
+	if total >= s.cfg.MinHistory && others <= s.cfg.QuietMaxCount {
+		return &models.AnomalyHint{
+			Reason:  models.AnomalyQuietCategory,
+			Samples: others,
+			Message: fmt.Sprintf("В этой категории за %d дней было всего %d расходов", s.cfg.LookbackDays, others),
+		}
+	}
+
+	if len(sample) < s.cfg.MinSamples {
+		return nil
+	}
+
+	median := Median(sample)
+	score := RobustZScore(amount, median, MAD(sample, median), sample)
+	if math.Abs(score) < threshold {
+		return nil
+	}
+
+	direction := "больше"
+	if score < 0 {
+		direction = "меньше"
+	}
+
+	return &models.AnomalyHint{
+		Reason:  models.AnomalyAmount,
+		Score:   math.Round(score*100) / 100,
+		Median:  median,
+		Samples: len(sample),
+		Message: fmt.Sprintf("Сумма заметно %s обычной для категории (медиана %.2f)", direction, median),
+	}
+}
+
+// Median возвращает медиану выборки (исходный слайс не меняется)
+func Median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// MAD - медианное абсолютное отклонение от медианы
+func MAD(values []float64, median float64) float64 {
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - median)
+	}
+	return Median(deviations)
+}
+
+// RobustZScore считает модифицированный z-score: 0.6745 * (x - медиана) / MAD
+// Если MAD = 0 (больше половины сумм одинаковые), берём среднее
+// абсолютное отклонение с поправочным коэффициентом 1.2533
+func RobustZScore(x, median, mad float64, values []float64) float64 {
+	if mad > 0 {
+		return clampScore(0.6745 * (x - median) / mad)
+	}
+
+	var meanAD float64
+	for _, v := range values {
+		meanAD += math.Abs(v - median)
+	}
+	if len(values) > 0 {
+		meanAD /= float64(len(values))
+	}
+
+	if meanAD > 0 {
+		return clampScore((x - median) / (1.2533 * meanAD))
+	}
+
+	// Все суммы одинаковые: любое отличие - аномалия
+	switch {
+	case x > median:
+		return maxAnomalyScore
+	case x < median:
+		return -maxAnomalyScore
+	default:
+		return 0
+	}
+}
+
+func clampScore(score float64) float64 {
+	return math.Max(-maxAnomalyScore, math.Min(maxAnomalyScore, score))
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+)
+
+// seedHistory заполняет мок одинаковыми по порядку величины расходами
+func seedHistory(repo *MockExpenseRepository, category string, amounts ...float64) {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, amount := range amounts {
+		repo.Create(context.Background(), &models.Expense{
+			Description: "История",
+			Amount:      amount,
+			Category:    category,
+			Date:        date.AddDate(0, 0, i),
+		})
+	}
+}
+
+func TestMedianAndMAD(t *testing.T) {
+	values := []float64{1, 2, 3, 4, 100}
+
+	median := Median(values)
+	if median != 3 {
+		t.Errorf("Median: ожидали 3, получили %f", median)
+	}
+
+	if mad := MAD(values, median); mad != 1 {
+		t.Errorf("MAD: ожидали 1, получили %f", mad)
+	}
+
+	if even := Median([]float64{4, 1, 3, 2}); even != 2.5 {
+		t.Errorf("Median для чётного числа: ожидали 2.5, получили %f", even)
+	}
+}
+
+func TestRobustZScore_ConstantSample(t *testing.T) {
+	values := []float64{300, 300, 300, 300}
+
+	if score := RobustZScore(300, 300, 0, values); score != 0 {
+		t.Errorf("Такая же сумма не должна быть аномалией, score = %f", score)
+	}
+
+	if score := RobustZScore(900, 300, 0, values); score != maxAnomalyScore {
+		t.Errorf("Ожидали максимальный score, получили %f", score)
+	}
+}
+
+func TestCreateExpense_AnomalyHint(t *testing.T) {
+	repo := NewMockRepository()
+	seedHistory(repo, "Еда", 300, 320, 280, 310, 290, 305, 295)
+
+	anomalies := NewAnomalyService(repo, DefaultAnomalyConfig())
+	svc := NewExpenseService(repo, WithAnomalyDetector(anomalies))
+	ctx := context.Background()
+
+	usual, err := svc.CreateExpense(ctx, models.CreateExpenseRequest{
+		Description: "Обед", Amount: 300, Category: "Еда", Date: "2024-02-01",
+	})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if usual.Anomaly != nil {
+		t.Errorf("Обычный расход не должен помечаться, получили %+v", usual.Anomaly)
+	}
+
+	unusual, err := svc.CreateExpense(ctx, models.CreateExpenseRequest{
+		Description: "Ресторан", Amount: 5000, Category: "Еда", Date: "2024-02-01",
+	})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if unusual.Anomaly == nil || unusual.Anomaly.Reason != models.AnomalyAmount {
+		t.Fatalf("Ожидали подсказку про сумму, получили %+v", unusual.Anomaly)
+	}
+	if unusual.Anomaly.Median != 300 {
+		t.Errorf("Median: ожидали 300, получили %f", unusual.Anomaly.Median)
+	}
+}
+
+func TestAnomalyCheck_QuietCategory(t *testing.T) {
+	repo := NewMockRepository()
+	amounts := make([]float64, 25)
+	for i := range amounts {
+		amounts[i] = 300
+	}
+	seedHistory(repo, "Еда", amounts...)
+
+	svc := NewAnomalyService(repo, DefaultAnomalyConfig())
+	expense := &models.Expense{Amount: 1000, Category: "Ювелирка", Date: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)}
+
+	hint, err := svc.Check(context.Background(), expense)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if hint == nil || hint.Reason != models.AnomalyQuietCategory {
+		t.Errorf("Ожидали подсказку про тихую категорию, получили %+v", hint)
+	}
+}
+
+func TestAnomalyCheck_IgnoresLaterExpenses(t *testing.T) {
+	repo := NewMockRepository()
+	seedHistory(repo, "Еда", 300, 310, 290, 305, 295, 300, 320)
+
+	svc := NewAnomalyService(repo, DefaultAnomalyConfig())
+
+	// Задним числом, до всей истории: сравнивать не с чем
+	expense := &models.Expense{Amount: 5000, Category: "Еда", Date: time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)}
+	hint, err := svc.Check(context.Background(), expense)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if hint != nil {
+		t.Errorf("Расходы после даты не должны учитываться, получили %+v", hint)
+	}
+
+	// Тот же расход после истории - уже аномалия
+	expense.Date = time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	if hint, _ := svc.Check(context.Background(), expense); hint == nil {
+		t.Error("Ожидали подсказку для расхода после истории")
+	}
+}
+
+func TestAnomalyList(t *testing.T) {
+	repo := NewMockRepository()
+	seedHistory(repo, "Транспорт", 100, 120, 90, 110, 105, 95, 3000)
+
+	svc := NewAnomalyService(repo, DefaultAnomalyConfig())
+
+	anomalies, err := svc.List(context.Background(), models.AnomalyFilter{
+		DateFrom: "2024-01-01",
+		DateTo:   "2024-01-31",
+	})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	if len(anomalies) != 1 {
+		t.Fatalf("Ожидали 1 аномалию, получили %d", len(anomalies))
+	}
+	if anomalies[0].Expense.Amount != 3000 {
+		t.Errorf("Ожидали расход на 3000, получили %f", anomalies[0].Expense.Amount)
+	}
+}
+
+func TestAnomalyList_AgreesWithCheck(t *testing.T) {
+	repo := NewMockRepository()
+	// Выброс в середине: после него идут обычные расходы
+	seedHistory(repo, "Транспорт", 100, 120, 90, 110, 105, 3000, 95, 100, 115, 105)
+
+	svc := NewAnomalyService(repo, DefaultAnomalyConfig())
+	ctx := context.Background()
+
+	anomalies, err := svc.List(ctx, models.AnomalyFilter{DateFrom: "2024-01-01", DateTo: "2024-01-31"})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	listed := make(map[int64]models.AnomalyHint)
+	for _, a := range anomalies {
+		listed[a.Expense.ID] = a.Hint
+	}
+
+	for _, e := range repo.expenses {
+		hint, err := svc.Check(ctx, e)
+		if err != nil {
+			t.Fatalf("Неожиданная ошибка: %v", err)
+		}
+		got, ok := listed[e.ID]
+		if (hint != nil) != ok || (hint != nil && *hint != got) {
+			t.Errorf("Расход %d (%.0f): Check %+v, List %+v", e.ID, e.Amount, hint, got)
+		}
+	}
+
+	if len(anomalies) != 1 || anomalies[0].Expense.Amount != 3000 {
+		t.Errorf("Ожидали одну аномалию 3000, получили %+v", anomalies)
+	}
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+)
+
+// AuditRepository - журнал изменений
+type AuditRepository interface {
+	AddAudit(ctx context.Context, entry *models.AuditEntry) error
+	ListAudit(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+}
+
+// anonymousActor - автор изменений без X-User-ID
+const anonymousActor = "anonymous"
+
+// AuditService ведёт журнал изменений расходов: кто, когда и что поменял
+type AuditService struct {
+	repo AuditRepository
+	tx   Transactor
+}
+
+// NewAuditService создаёт сервис журнала
+// С tx изменение и запись о нём сохраняются одной транзакцией;
+// без него (в тестах) - просто друг за другом
+func NewAuditService(repo AuditRepository, tx Transactor) *AuditService {
+	return &AuditService{repo: repo, tx: tx}
+}
+
+// Track выполняет изменение и записывает его в журнал
+// change возвращает расход до и после: для создания before == nil,
+// для удаления after == nil. Если запись в журнал не удалась, изменение откатывается.
+// У nil-сервиса (журнал не подключён) просто выполняет изменение
+func (a *AuditService) Track(ctx context.Context, action models.AuditAction, change func(ctx context.Context) (before, after *models.Expense, err error)) error {
+	if a == nil {
+		_, _, err := change(ctx)
+		return err
+	}
+
+	run := func(ctx context.Context) error {
+		before, after, err := change(ctx)
+		if err != nil {
+			return err
+		}
+		return a.record(ctx, action, before, after)
+	}
+
+	if a.tx == nil {
+		return run(ctx)
+	}
+	return a.tx.WithinTx(ctx, run)
+}
+
+// TrackRemoved - Track для удаления сразу нескольких расходов
+// (очистка корзины по сроку): в журнал идёт запись на каждый расход
+// из возвращённых change, всё одной транзакцией
+func (a *AuditService) TrackRemoved(ctx context.Context, action models.AuditAction, change func(ctx context.Context) ([]models.Expense, error)) error {
+	if a == nil {
+		_, err := change(ctx)
+		return err
+	}
+
+	run := func(ctx context.Context) error {
+		removed, err := change(ctx)
+		if err != nil {
+			return err
+		}
+		for i := range removed {
+			if err := a.record(ctx, action, &removed[i], nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if a.tx == nil {
+		return run(ctx)
+	}
+	return a.tx.WithinTx(ctx, run)
+}
+
+// record пишет в журнал одно изменение расхода
+func (a *AuditService) record(ctx context.Context, action models.AuditAction, before, after *models.Expense) error {
+	changes := diffExpenses(before, after)
+	if len(changes) == 0 {
+		return nil // изменение ничего не поменяло - писать нечего
+	}
+
+	entry := &models.AuditEntry{
+		Action:  action,
+		Actor:   actorFromContext(ctx),
+		Changes: changes,
+	}
+	if after != nil {
+		entry.ExpenseID = after.ID
+	} else {
+		entry.ExpenseID = before.ID
+	}
+
+	return a.repo.AddAudit(ctx, entry)
+}
+
+// History возвращает историю расхода от старых изменений к новым
+func (a *AuditService) History(ctx context.Context, expenseID int64) ([]models.AuditEntry, error) {
+	return a.repo.ListAudit(ctx, models.AuditFilter{
+		ExpenseID: expenseID,
+		Ascending: true,
+		Limit:     1000,
+	})
+}
+
+// List возвращает ленту изменений, новые сверху
+// По умолчанию 50 записей (максимум 200)
+func (a *AuditService) List(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 50
+	}
+	if filter.Limit > 200 {
+		filter.Limit = 200
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	switch filter.Action {
+	case "", models.AuditCreate, models.AuditUpdate, models.AuditDelete, models.AuditRestore, models.AuditPurge:
+	default:
+		return nil, invalidAs(ErrInvalidFilter, "action", fmt.Sprintf("неизвестное действие %q", filter.Action))
+	}
+
+	for name, value := range map[string]string{"from": filter.From, "to": filter.To} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			if _, err := time.Parse("2006-01-02", value); err != nil {
+				return nil, invalidAs(ErrInvalidFilter, name, "нужна дата (YYYY-MM-DD) или RFC3339")
+			}
+		}
+	}
+
+	return a.repo.ListAudit(ctx, filter)
+}
+
+func actorFromContext(ctx context.Context) string {
+	if user := UserFromContext(ctx); user != "" {
+		return user
+	}
+	return anonymousActor
+}
+
+// diffExpenses сравнивает пользовательские поля расхода
+// Служебные (id, created_at, deleted_at) в журнал не попадают
+func diffExpenses(before, after *models.Expense) models.AuditChanges {
+	changes := models.AuditChanges{}
+
+	fields := func(e *models.Expense) map[string]interface{} {
+		if e == nil {
+			return nil
+		}
+		tags := []string(e.Tags)
+		if tags == nil {
+			tags = []string{}
+		}
+		return map[string]interface{}{
+			"description": e.Description,
+			"amount":      e.Amount,
+			"category":    e.Category,
+			"merchant":    e.Merchant,
+			"tags":        tags,
+			"date":        e.Date.Format("2006-01-02"),
+		}
+	}
+
+	b, a := fields(before), fields(after)
+	for _, field := range []string{"description", "amount", "category", "merchant", "tags", "date"} {
+		var bv, av interface{}
+		if b != nil {
+			bv = b[field]
+		}
+		if a != nil {
+			av = a[field]
+		}
+		if !sameValue(bv, av) {
+			changes[field] = models.FieldChange{Before: bv, After: av}
+		}
+	}
+
+	return changes
+}
+
+func sameValue(a, b interface{}) bool {
+	switch av := a.(type) {
+	case nil:
+		return b == nil
+	case []string:
+		bv, ok := b.([]string)
+		return ok && slices.Equal(av, bv)
+	case float64:
+		bv, ok := b.(float64)
+		// Суммы из БД и из запроса могут разойтись в последнем знаке
+		return ok && math.Abs(av-bv) < 1e-9
+	default:
+		return a == b
+	}
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+)
+
+// mockAuditRepository - журнал в памяти
+type mockAuditRepository struct {
+	entries []models.AuditEntry
+	failAdd bool
+}
+
+func (m *mockAuditRepository) AddAudit(ctx context.Context, entry *models.AuditEntry) error {
+	if m.failAdd {
+		return errors.New("журнал недоступен")
+	}
+	entry.ID = int64(len(m.entries) + 1)
+	m.entries = append(m.entries, *entry)
+	return nil
+}
+
+func (m *mockAuditRepository) ListAudit(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	result := []models.AuditEntry{}
+	for _, e := range m.entries {
+		if filter.ExpenseID == 0 || e.ExpenseID == filter.ExpenseID {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+func TestAudit_RecordsChangesWithActor(t *testing.T) {
+	repo := NewMockRepository()
+	auditRepo := &mockAuditRepository{}
+	audit := NewAuditService(auditRepo, nil)
+	svc := NewExpenseService(repo, WithAudit(audit))
+	ctx := WithUser(context.Background(), "anna")
+
+	created, err := svc.CreateExpense(ctx, models.CreateExpenseRequest{
+		Description: "Обед", Amount: 500, Category: "Еда", Date: "2024-01-15",
+	})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	amount := 650.0
+	sameCategory := "Еда"
+	if _, err := svc.UpdateExpense(ctx, created.ID, models.UpdateExpenseRequest{Amount: &amount, Category: &sameCategory}); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	// Изменение, которое ничего не меняет, в журнал не пишется
+	svc.UpdateExpense(ctx, created.ID, models.UpdateExpenseRequest{Category: &sameCategory})
+
+	if err := svc.DeleteExpense(context.Background(), created.ID, 0); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	history, _ := audit.History(context.Background(), created.ID)
+	if len(history) != 3 {
+		t.Fatalf("Ожидали 3 записи (create, update, delete), получили %d: %+v", len(history), history)
+	}
+
+	if history[0].Action != models.AuditCreate || history[0].Actor != "anna" || history[0].Changes["amount"].After != 500.0 {
+		t.Errorf("Неожиданная запись создания: %+v", history[0])
+	}
+
+	update := history[1]
+	if len(update.Changes) != 1 || update.Changes["amount"].Before != 500.0 || update.Changes["amount"].After != 650.0 {
+		t.Errorf("В изменении должна быть только сумма 500 -> 650, получили %+v", update.Changes)
+	}
+
+	if history[2].Action != models.AuditDelete || history[2].Actor != anonymousActor || history[2].Changes["description"].After != nil {
+		t.Errorf("Неожиданная запись удаления: %+v", history[2])
+	}
+}
+
+func TestAudit_FailedRecordFailsChange(t *testing.T) {
+	repo := NewMockRepository()
+	audit := NewAuditService(&mockAuditRepository{failAdd: true}, &mockTransactor{repo: repo})
+	svc := NewExpenseService(repo, WithAudit(audit))
+
+	_, err := svc.CreateExpense(context.Background(), models.CreateExpenseRequest{
+		Description: "Обед", Amount: 500, Category: "Еда", Date: "2024-01-15",
+	})
+	if err == nil {
+		t.Fatal("Без записи в журнал изменение не должно проходить")
+	}
+	if len(repo.expenses) != 0 {
+		t.Error("Расход должен откатиться вместе с записью журнала")
+	}
+}
+
+func TestAudit_ListValidation(t *testing.T) {
+	audit := NewAuditService(&mockAuditRepository{}, nil)
+
+	for _, filter := range []models.AuditFilter{
+		{Action: "hack"},
+		{From: "вчера"},
+		{To: "2024-13-45"},
+	} {
+		if _, err := audit.List(context.Background(), filter); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("%+v: ожидали ErrInvalidFilter, получили %v", filter, err)
+		}
+	}
+}
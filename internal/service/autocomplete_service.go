@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+)
+
+// SuggestRepository - источник подсказок для автодополнения
+type SuggestRepository interface {
+	Suggest(ctx context.Context, field models.SuggestField, prefix string, limit int) ([]models.Suggestion, error)
+}
+
+// AutocompleteService подсказывает описания, категории и продавцов
+// по тому, что уже вводили раньше
+type AutocompleteService struct {
+	repo SuggestRepository
+}
+
+// NewAutocompleteService создаёт новый сервис
+func NewAutocompleteService(repo SuggestRepository) *AutocompleteService {
+	return &AutocompleteService{repo: repo}
+}
+
+// Suggest возвращает подсказки, лучшие сверху
+// По умолчанию подсказываем описания, 10 штук (максимум 20)
+func (s *AutocompleteService) Suggest(ctx context.Context, field models.SuggestField, q string, limit int) ([]models.Suggestion, error) {
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return []models.Suggestion{}, nil
+	}
+
+	if len([]rune(q)) > 100 {
+		return nil, Invalid("q", "слишком длинный запрос (максимум 100 символов)")
+	}
+
+	switch field {
+	case "":
+		field = models.SuggestDescription
+	case models.SuggestDescription, models.SuggestCategory, models.SuggestMerchant:
+	default:
+		return nil, Invalid("field", fmt.Sprintf("неизвестное поле %q, допустимо: description, category, merchant", field))
+	}
+
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 20 {
+		limit = 20
+	}
+
+	return s.repo.Suggest(ctx, field, q, limit)
+}
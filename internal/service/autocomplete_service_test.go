@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+)
+
+// mockSuggestRepository запоминает, с чем его вызвали
+type mockSuggestRepository struct {
+	field models.SuggestField
+	limit int
+	calls int
+}
+
+func (m *mockSuggestRepository) Suggest(ctx context.Context, field models.SuggestField, prefix string, limit int) ([]models.Suggestion, error) {
+	m.field, m.limit = field, limit
+	m.calls++
+	return []models.Suggestion{{Value: "Кофе", Category: "Еда", Amount: 250, Uses: 12}}, nil
+}
+
+func TestAutocomplete_Defaults(t *testing.T) {
+	repo := &mockSuggestRepository{}
+	svc := NewAutocompleteService(repo)
+
+	result, err := svc.Suggest(context.Background(), "", "коф", 0)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	if repo.field != models.SuggestDescription || repo.limit != 10 {
+		t.Errorf("Ожидали description и лимит 10, получили %s и %d", repo.field, repo.limit)
+	}
+	if len(result) != 1 || result[0].Category != "Еда" {
+		t.Errorf("Неожиданный результат: %+v", result)
+	}
+
+	svc.Suggest(context.Background(), models.SuggestMerchant, "пят", 1000)
+	if repo.limit != 20 {
+		t.Errorf("Лимит должен обрезаться до 20, получили %d", repo.limit)
+	}
+}
+
+func TestAutocomplete_Validation(t *testing.T) {
+	repo := &mockSuggestRepository{}
+	svc := NewAutocompleteService(repo)
+	ctx := context.Background()
+
+	if _, err := svc.Suggest(ctx, "amount", "100", 5); err == nil {
+		t.Error("Ожидали ошибку для неизвестного поля")
+	}
+
+	result, err := svc.Suggest(ctx, models.SuggestCategory, "   ", 5)
+	if err != nil || len(result) != 0 {
+		t.Errorf("Пустой запрос должен давать пустой список, получили %v, %v", result, err)
+	}
+	if repo.calls != 0 {
+		t.Error("Для пустого запроса в БД ходить не нужно")
+	}
+}
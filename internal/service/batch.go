@@ -0,0 +1,333 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Transactor выполняет fn в одной транзакции БД
+// Репозитории находят транзакцию в переданном контексте
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type afterCommitKey struct{}
+
+// afterCommitHooks - что сделать, когда внешняя транзакция зафиксируется
+type afterCommitHooks struct {
+	fns []func()
+}
+
+// withinTx - транзакция с хуками после коммита
+// Вложенный вызов хуки не запускает: их выполнит самая внешняя транзакция,
+// и только если она зафиксировалась. При откате хуки просто выбрасываются
+func (s *ExpenseService) withinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, nested := ctx.Value(afterCommitKey{}).(*afterCommitHooks); nested {
+		return s.tx.WithinTx(ctx, fn)
+	}
+
+	hooks := &afterCommitHooks{}
+	if err := s.tx.WithinTx(context.WithValue(ctx, afterCommitKey{}, hooks), fn); err != nil {
+		return err
+	}
+	for _, f := range hooks.fns {
+		f()
+	}
+	return nil
+}
+
+// afterCommit откладывает fn до коммита транзакции из withinTx
+// Вне такой транзакции изменения уже сохранены, и fn выполняется сразу
+func afterCommit(ctx context.Context, fn func()) {
+	if hooks, ok := ctx.Value(afterCommitKey{}).(*afterCommitHooks); ok {
+		hooks.fns = append(hooks.fns, fn)
+		return
+	}
+	fn()
+}
+
+// DefaultBatchLimit - сколько операций можно прислать в одном пакете по умолчанию
+const DefaultBatchLimit = 100
+
+var (
+	// ErrInvalidBatch - пакет не прошёл проверку, ничего не применено
+	ErrInvalidBatch = newError(ErrValidation, "пакет операций не прошёл проверку")
+	// ErrBatchAborted - атомарный пакет упал на одной из операций и откатился целиком
+	ErrBatchAborted = newError(ErrConflict, "пакет отменён, ни одна операция не применена")
+)
+
+// batchItem - операция после проверки
+type batchItem struct {
+	op      models.BatchOperation
+	expense *models.Expense // create: готовый к сохранению расход
+	before  models.Expense  // update, delete: каким расход был до изменения
+}
+
+// Batch применяет пакет операций создания, изменения и удаления
+// Сначала проверяются все операции: если хоть одна неверна, не применяется ничего.
+// Дальше в атомарном режиме всё идёт одной транзакцией, иначе - по одной,
+// и в результате видно, какие операции прошли
+func (s *ExpenseService) Batch(ctx context.Context, req models.BatchRequest) (*models.BatchResult, error) {
+	return s.runBatch(ctx, "ExpenseService.Batch", req, s.batchLimit)
+}
+
+// runBatch - сам пакет; limit у пакета из API и у импорта свой
+func (s *ExpenseService) runBatch(ctx context.Context, spanName string, req models.BatchRequest, limit int) (_ *models.BatchResult, err error) {
+	atomic := req.Atomic == nil || *req.Atomic
+
+	ctx, span := startSpan(ctx, spanName,
+		attribute.Int("batch.operations", len(req.Operations)), attribute.Bool("batch.atomic", atomic))
+	defer func() { endSpan(span, err) }()
+
+	if len(req.Operations) == 0 {
+		return nil, fmt.Errorf("%w: пустой пакет", ErrInvalidBatch)
+	}
+	if len(req.Operations) > limit {
+		return nil, fmt.Errorf("%w: слишком много операций: %d (максимум %d)", ErrInvalidBatch, len(req.Operations), limit)
+	}
+	if atomic && s.tx == nil {
+		return nil, fmt.Errorf("%w: атомарный режим недоступен, транзакции не подключены", ErrInternal)
+	}
+
+	result := &models.BatchResult{
+		Atomic:  atomic,
+		Results: make([]models.BatchItemResult, len(req.Operations)),
+	}
+	for i, op := range req.Operations {
+		result.Results[i] = models.BatchItemResult{Index: i, Op: op.Op, ID: op.ID}
+	}
+
+	items, err := s.prepareBatch(ctx, req.Operations, result.Results)
+	if err != nil {
+		return nil, err
+	}
+
+	if invalid := countFailed(result.Results); invalid > 0 {
+		result.Failed = invalid
+		return result, &ValidationError{Err: ErrInvalidBatch, Fields: batchFieldErrors(result.Results)}
+	}
+
+	if atomic {
+		err := s.withinTx(ctx, func(ctx context.Context) error {
+			for i := range items {
+				if err := s.applyBatchItem(ctx, items[i], &result.Results[i]); err != nil {
+					return fmt.Errorf("операция %d: %w", i, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			// Транзакция откатилась, так что успешных операций нет
+			for i := range result.Results {
+				r := &result.Results[i]
+				r.Success = false
+				r.Expense = nil
+				r.ID = items[i].op.ID
+				if r.Error == "" {
+					r.Error = "не применено: пакет отменён"
+				}
+			}
+			result.Failed = len(result.Results)
+			if !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrConflict) && !errors.Is(err, ErrValidation) {
+				// БД отказала посреди пакета - это сбой, а не конфликт с данными
+				return result, err
+			}
+			return result, fmt.Errorf("%w: %v", ErrBatchAborted, err)
+		}
+	} else {
+		for i := range items {
+			s.applyBatchItem(ctx, items[i], &result.Results[i])
+		}
+	}
+
+	for i, r := range result.Results {
+		if !r.Success {
+			result.Failed++
+			continue
+		}
+		result.Succeeded++
+		s.learnBatchItem(ctx, items[i], r)
+		if items[i].op.Op == models.BatchCreate {
+			s.recordCreated(r.Expense)
+		}
+	}
+
+	return result, nil
+}
+
+// batchFieldErrors - ошибки проверки операций как ошибки полей operations[i]
+func batchFieldErrors(results []models.BatchItemResult) []FieldError {
+	var fields []FieldError
+	for _, r := range results {
+		if r.Error != "" {
+			fields = append(fields, FieldError{Field: fmt.Sprintf("operations[%d]", r.Index), Message: r.Error})
+		}
+	}
+	return fields
+}
+
+// prepareBatch проверяет все операции и записывает ошибки в results
+// Возвращает ошибку только если не удалось сходить в БД
+func (s *ExpenseService) prepareBatch(ctx context.Context, ops []models.BatchOperation, results []models.BatchItemResult) ([]batchItem, error) {
+	items := make([]batchItem, len(ops))
+	var created []*models.Expense
+
+	for i, op := range ops {
+		items[i].op = op
+
+		switch op.Op {
+		case models.BatchCreate:
+			if op.Expense == nil {
+				results[i].Error = "нет данных расхода (expense)"
+				continue
+			}
+			expense, err := newExpense(*op.Expense)
+			if err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+			items[i].expense = expense
+			created = append(created, expense)
+
+		case models.BatchUpdate, models.BatchDelete:
+			if op.ID <= 0 {
+				results[i].Error = "не указан id расхода"
+				continue
+			}
+			if op.Op == models.BatchUpdate {
+				if op.Changes == nil {
+					results[i].Error = "нет изменений (changes)"
+					continue
+				}
+				if op.Changes.Date != nil {
+					if _, err := time.Parse("2006-01-02", *op.Changes.Date); err != nil {
+						results[i].Error = "неверный формат даты, используйте YYYY-MM-DD"
+						continue
+					}
+				}
+			}
+
+			existing, err := s.repo.GetByID(ctx, op.ID)
+			if err != nil {
+				return nil, err
+			}
+			if existing == nil {
+				results[i].Error = (&NotFoundError{Resource: "expense", ID: op.ID}).Error()
+				continue
+			}
+			if expected := batchVersion(op); expected > 0 && expected != existing.Version {
+				results[i].Error = versionConflict(op.ID, expected, existing.Version).Error()
+				continue
+			}
+			items[i].before = *existing
+
+		default:
+			results[i].Error = fmt.Sprintf("неизвестная операция %q", op.Op)
+		}
+	}
+
+	// Правила для новых расходов - до проверки категории, как при обычном создании
+	if s.rules != nil && len(created) > 0 {
+		if err := s.rules.ApplyEach(ctx, created); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range items {
+		if items[i].expense == nil {
+			continue
+		}
+		if err := checkCategory(items[i].expense); err != nil {
+			results[i].Error = err.Error()
+		}
+	}
+
+	return items, nil
+}
+
+// applyBatchItem выполняет одну проверенную операцию и записывает результат
+func (s *ExpenseService) applyBatchItem(ctx context.Context, item batchItem, r *models.BatchItemResult) error {
+	var err error
+
+	switch item.op.Op {
+	case models.BatchCreate:
+		err = s.audit.Track(ctx, models.AuditCreate, func(ctx context.Context) (*models.Expense, *models.Expense, error) {
+			return nil, item.expense, s.repo.Create(ctx, item.expense)
+		})
+		if err == nil {
+			r.ID = item.expense.ID
+			r.Expense = item.expense
+		}
+
+	case models.BatchUpdate:
+		err = s.audit.Track(ctx, models.AuditUpdate, func(ctx context.Context) (*models.Expense, *models.Expense, error) {
+			updated, err := s.repo.Update(ctx, item.op.ID, *item.op.Changes)
+			if err == nil && updated == nil {
+				if item.op.Changes.Version != nil {
+					err = fmt.Errorf("%w: id=%d", ErrVersionConflict, item.op.ID)
+				} else {
+					err = &NotFoundError{Resource: "expense", ID: item.op.ID}
+				}
+			}
+			r.Expense = updated
+			return &item.before, updated, err
+		})
+
+	case models.BatchDelete:
+		err = s.audit.Track(ctx, models.AuditDelete, func(ctx context.Context) (*models.Expense, *models.Expense, error) {
+			return &item.before, nil, notFound(s.repo.Delete(ctx, item.op.ID, item.op.Version), "expense", item.op.ID)
+		})
+	}
+
+	if err != nil {
+		r.Expense = nil
+		r.Error = err.Error()
+		return err
+	}
+
+	r.Success = true
+	return nil
+}
+
+// learnBatchItem дообучает классификатор на применённой операции
+// Если пакет сам идёт внутри чужой транзакции - после её коммита
+func (s *ExpenseService) learnBatchItem(ctx context.Context, item batchItem, r models.BatchItemResult) {
+	if s.classifier == nil {
+		return
+	}
+
+	afterCommit(ctx, func() {
+		switch item.op.Op {
+		case models.BatchCreate:
+			s.classifier.Learn(*r.Expense)
+		case models.BatchUpdate:
+			s.classifier.Forget(item.before)
+			s.classifier.Learn(*r.Expense)
+		case models.BatchDelete:
+			s.classifier.Forget(item.before)
+		}
+	})
+}
+
+// batchVersion - ожидаемая версия расхода для операции, 0 - без проверки
+func batchVersion(op models.BatchOperation) int {
+	if op.Op == models.BatchUpdate && op.Changes != nil && op.Changes.Version != nil {
+		return *op.Changes.Version
+	}
+	return op.Version
+}
+
+func countFailed(results []models.BatchItemResult) int {
+	n := 0
+	for _, r := range results {
+		if r.Error != "" {
+			n++
+		}
+	}
+	return n
+}
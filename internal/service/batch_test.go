@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+)
+
+// mockTransactor откатывает мок-репозиторий к снимку, если fn вернула ошибку
+type mockTransactor struct {
+	repo *MockExpenseRepository
+}
+
+func (t *mockTransactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	snapshot := make(map[int64]models.Expense, len(t.repo.expenses))
+	for id, e := range t.repo.expenses {
+		snapshot[id] = *e
+	}
+	lastID := t.repo.lastID
+
+	if err := fn(ctx); err != nil {
+		t.repo.expenses = make(map[int64]*models.Expense, len(snapshot))
+		for id, e := range snapshot {
+			e := e
+			t.repo.expenses[id] = &e
+		}
+		t.repo.lastID = lastID
+		return err
+	}
+
+	return nil
+}
+
+func newBatchService(t *testing.T) (*ExpenseService, *MockExpenseRepository) {
+	t.Helper()
+
+	repo := NewMockRepository()
+	svc := NewExpenseService(repo, WithTransactions(&mockTransactor{repo: repo}), WithBatchLimit(5))
+
+	for _, desc := range []string{"Кофе", "Обед"} {
+		if _, err := svc.CreateExpense(context.Background(), models.CreateExpenseRequest{
+			Description: desc, Amount: 100, Category: "Еда", Date: "2024-01-15",
+		}); err != nil {
+			t.Fatalf("Неожиданная ошибка: %v", err)
+		}
+	}
+
+	return svc, repo
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
+
+func TestBatch_AtomicSuccess(t *testing.T) {
+	svc, repo := newBatchService(t)
+	newDesc := "Кофе с собой"
+
+	result, err := svc.Batch(context.Background(), models.BatchRequest{Operations: []models.BatchOperation{
+		{Op: models.BatchCreate, Expense: &models.CreateExpenseRequest{Description: "Такси", Amount: 300, Category: "Транспорт", Date: "2024-01-16"}},
+		{Op: models.BatchUpdate, ID: 1, Changes: &models.UpdateExpenseRequest{Description: &newDesc}},
+		{Op: models.BatchDelete, ID: 2},
+	}})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	if !result.Atomic || result.Succeeded != 3 || result.Failed != 0 {
+		t.Errorf("Неожиданный итог: %+v", result)
+	}
+	if result.Results[0].ID != 3 || result.Results[0].Expense == nil {
+		t.Errorf("Для создания ожидали id=3 и расход, получили %+v", result.Results[0])
+	}
+	if repo.expenses[1].Description != newDesc {
+		t.Errorf("Обновление не применилось")
+	}
+	if _, ok := repo.expenses[2]; ok {
+		t.Errorf("Удаление не применилось")
+	}
+}
+
+func TestBatch_ValidationRejectsWholeBatch(t *testing.T) {
+	for _, atomic := range []bool{true, false} {
+		svc, repo := newBatchService(t)
+		badDate := "15.01.2024"
+
+		result, err := svc.Batch(context.Background(), models.BatchRequest{Atomic: boolPtr(atomic), Operations: []models.BatchOperation{
+			{Op: models.BatchDelete, ID: 1},
+			{Op: models.BatchUpdate, ID: 2, Changes: &models.UpdateExpenseRequest{Date: &badDate}},
+			{Op: models.BatchDelete, ID: 99},
+			{Op: models.BatchCreate, Expense: &models.CreateExpenseRequest{Description: "Без категории", Amount: 1, Date: "2024-01-16"}},
+			{Op: models.BatchUpdate, ID: 1},
+		}})
+
+		if !errors.Is(err, ErrInvalidBatch) {
+			t.Fatalf("atomic=%v: ожидали ErrInvalidBatch, получили %v", atomic, err)
+		}
+		if result.Failed != 4 || result.Results[0].Error != "" {
+			t.Errorf("atomic=%v: ожидали ошибки у операций 1-4, получили %+v", atomic, result.Results)
+		}
+		if len(repo.expenses) != 2 {
+			t.Errorf("atomic=%v: при ошибке проверки ничего не должно примениться", atomic)
+		}
+	}
+}
+
+func TestBatch_AtomicRollback(t *testing.T) {
+	svc, repo := newBatchService(t)
+
+	// Оба удаления проходят проверку, но второе падает при применении
+	result, err := svc.Batch(context.Background(), models.BatchRequest{Operations: []models.BatchOperation{
+		{Op: models.BatchCreate, Expense: &models.CreateExpenseRequest{Description: "Такси", Amount: 300, Category: "Транспорт", Date: "2024-01-16"}},
+		{Op: models.BatchDelete, ID: 1},
+		{Op: models.BatchDelete, ID: 1},
+	}})
+
+	if !errors.Is(err, ErrBatchAborted) {
+		t.Fatalf("Ожидали ErrBatchAborted, получили %v", err)
+	}
+	if result.Succeeded != 0 || result.Results[0].ID != 0 {
+		t.Errorf("После отката успешных операций быть не должно: %+v", result)
+	}
+	if len(repo.expenses) != 2 || repo.expenses[1] == nil {
+		t.Errorf("Данные должны откатиться, осталось %d расходов", len(repo.expenses))
+	}
+}
+
+func TestBatch_NonAtomicReportsPerItem(t *testing.T) {
+	svc, repo := newBatchService(t)
+
+	result, err := svc.Batch(context.Background(), models.BatchRequest{Atomic: boolPtr(false), Operations: []models.BatchOperation{
+		{Op: models.BatchDelete, ID: 1},
+		{Op: models.BatchDelete, ID: 1},
+		{Op: models.BatchDelete, ID: 2},
+	}})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	if result.Succeeded != 2 || result.Failed != 1 || result.Results[1].Success || result.Results[1].Error == "" {
+		t.Errorf("Ожидали ошибку только у второй операции: %+v", result)
+	}
+	if len(repo.expenses) != 0 {
+		t.Errorf("Удачные операции должны примениться, осталось %d", len(repo.expenses))
+	}
+}
+
+func TestBatch_Limit(t *testing.T) {
+	svc, _ := newBatchService(t)
+
+	ops := make([]models.BatchOperation, 6)
+	for i := range ops {
+		ops[i] = models.BatchOperation{Op: models.BatchDelete, ID: 1}
+	}
+
+	if _, err := svc.Batch(context.Background(), models.BatchRequest{Operations: ops}); !errors.Is(err, ErrInvalidBatch) {
+		t.Errorf("Ожидали ErrInvalidBatch для слишком большого пакета, получили %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+)
+
+// CategoryClassifier - наивный байесовский классификатор категорий
+// Учится на истории расходов пользователя: токены из описания
+// (русские и английские слова) плюс "корзина" суммы.
+// Модель целиком в памяти и дообучается на каждом изменении
+type CategoryClassifier struct {
+	mu sync.RWMutex
+
+	docs        map[string]int            // категория -> сколько расходов
+	tokens      map[string]map[string]int // категория -> токен -> сколько раз
+	tokenTotals map[string]int            // категория -> всего токенов
+	vocabulary  map[string]int            // токен -> сколько раз встречался вообще
+	total       int                       // всего расходов в модели
+}
+
+// NewCategoryClassifier создаёт пустой классификатор
+func NewCategoryClassifier() *CategoryClassifier {
+	return &CategoryClassifier{
+		docs:        make(map[string]int),
+		tokens:      make(map[string]map[string]int),
+		tokenTotals: make(map[string]int),
+		vocabulary:  make(map[string]int),
+	}
+}
+
+// Train обучает модель на всей истории расходов
+func (c *CategoryClassifier) Train(ctx context.Context, repo HistoryRepository) error {
+	history, err := repo.GetSince(ctx, time.Time{})
+	if err != nil {
+		return err
+	}
+
+	for _, e := range history {
+		c.Learn(e)
+	}
+
+	return nil
+}
+
+// Learn добавляет расход в модель
+func (c *CategoryClassifier) Learn(e models.Expense) {
+	c.update(e, 1)
+}
+
+// Forget убирает расход из модели (при изменении и удалении)
+func (c *CategoryClassifier) Forget(e models.Expense) {
+	c.update(e, -1)
+}
+
+func (c *CategoryClassifier) update(e models.Expense, delta int) {
+	if e.Category == "" {
+		return
+	}
+
+	features := expenseFeatures(e.Description, e.Amount)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Забывать то, чего модель не видела, нельзя - счётчики уйдут в минус
+	if delta < 0 && c.docs[e.Category] == 0 {
+		return
+	}
+
+	c.docs[e.Category] += delta
+	c.total += delta
+
+	counts := c.tokens[e.Category]
+	if counts == nil {
+		counts = make(map[string]int)
+		c.tokens[e.Category] = counts
+	}
+
+	for _, f := range features {
+		counts[f] += delta
+		c.tokenTotals[e.Category] += delta
+		c.vocabulary[f] += delta
+
+		if counts[f] <= 0 {
+			delete(counts, f)
+		}
+		if c.vocabulary[f] <= 0 {
+			delete(c.vocabulary, f)
+		}
+	}
+
+	if c.docs[e.Category] <= 0 {
+		delete(c.docs, e.Category)
+		delete(c.tokens, e.Category)
+		delete(c.tokenTotals, e.Category)
+	}
+}
+
+// Suggest возвращает категории по убыванию уверенности
+// Confidence - апостериорная вероятность, в сумме по всем категориям даёт 1
+func (c *CategoryClassifier) Suggest(description string, amount float64, limit int) []models.CategorySuggestion {
+	features := expenseFeatures(description, amount)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	suggestions := []models.CategorySuggestion{}
+	if c.total == 0 {
+		return suggestions
+	}
+
+	vocabSize := float64(len(c.vocabulary) + 1)
+	scores := make(map[string]float64, len(c.docs))
+	maxScore := math.Inf(-1)
+
+	for category, docs := range c.docs {
+		// log P(категория) + сумма log P(токен | категория) со сглаживанием Лапласа
+		score := math.Log(float64(docs) / float64(c.total))
+		denominator := float64(c.tokenTotals[category]) + vocabSize
+		for _, f := range features {
+			score += math.Log((float64(c.tokens[category][f]) + 1) / denominator)
+		}
+
+		scores[category] = score
+		if score > maxScore {
+			maxScore = score
+		}
+	}
+
+	// Softmax: вычитаем максимум, чтобы exp не ушёл в ноль
+	var sum float64
+	for category, score := range scores {
+		scores[category] = math.Exp(score - maxScore)
+		sum += scores[category]
+	}
+
+	for category, score := range scores {
+		suggestions = append(suggestions, models.CategorySuggestion{
+			Category:   category,
+			Confidence: math.Round(score/sum*1000) / 1000,
+		})
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Confidence != suggestions[j].Confidence {
+			return suggestions[i].Confidence > suggestions[j].Confidence
+		}
+		return suggestions[i].Category < suggestions[j].Category
+	})
+
+	if limit > 0 && len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	return suggestions
+}
+
+// stopWords - частые слова, которые ничего не говорят о категории
+var stopWords = map[string]bool{
+	"и": true, "в": true, "во": true, "на": true, "с": true, "со": true, "по": true,
+	"за": true, "для": true, "из": true, "от": true, "до": true, "к": true, "у": true,
+	"the": true, "a": true, "an": true, "and": true, "of": true, "for": true,
+	"to": true, "in": true, "on": true, "at": true, "with": true,
+}
+
+// stemLength - до скольких букв обрезаем слова
+// Вместо полноценного стемминга: "кофейня", "кофейне" и "кофейню"
+// для модели становятся одним словом "кофейн"
+const stemLength = 6
+
+// expenseFeatures превращает расход в набор признаков
+func expenseFeatures(description string, amount float64) []string {
+	features := tokenize(description)
+
+	if amount > 0 {
+		// Корзины по степеням двойки: 64-127, 128-255 и т.д.
+		bucket := int(math.Floor(math.Log2(amount)))
+		features = append(features, "amount:"+strconv.Itoa(bucket))
+	}
+
+	return features
+}
+
+// tokenize разбивает описание на слова (русские и английские буквы, цифры)
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	tokens := make([]string, 0, len(words))
+	for _, w := range words {
+		w = strings.ReplaceAll(w, "ё", "е")
+		runes := []rune(w)
+
+		// Одиночные буквы, стоп-слова и чистые числа (номера карт, чеков) не нужны
+		if len(runes) < 2 || stopWords[w] || isDigits(w) {
+			continue
+		}
+
+		if len(runes) > stemLength {
+			w = string(runes[:stemLength])
+		}
+		tokens = append(tokens, w)
+	}
+
+	return tokens
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
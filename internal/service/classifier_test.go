@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+)
+
+func TestTokenize(t *testing.T) {
+	tokens := tokenize("Кофе в Кофейне, Starbucks 1234 Ёлка")
+
+	expected := []string{"кофе", "кофейн", "starbu", "елка"}
+	if len(tokens) != len(expected) {
+		t.Fatalf("Ожидали %v, получили %v", expected, tokens)
+	}
+	for i := range expected {
+		if tokens[i] != expected[i] {
+			t.Errorf("Токен %d: ожидали %s, получили %s", i, expected[i], tokens[i])
+		}
+	}
+}
+
+func TestClassifier_Suggest(t *testing.T) {
+	c := NewCategoryClassifier()
+
+	history := []models.Expense{
+		{Description: "Кофе латте", Amount: 250, Category: "Еда"},
+		{Description: "Обед в столовой", Amount: 400, Category: "Еда"},
+		{Description: "Кофе и круассан", Amount: 350, Category: "Еда"},
+		{Description: "Такси домой", Amount: 600, Category: "Транспорт"},
+		{Description: "Uber taxi", Amount: 800, Category: "Транспорт"},
+		{Description: "Метро", Amount: 60, Category: "Транспорт"},
+	}
+	for _, e := range history {
+		c.Learn(e)
+	}
+
+	suggestions := c.Suggest("такси до аэропорта", 700, 3)
+	if len(suggestions) == 0 || suggestions[0].Category != "Транспорт" {
+		t.Fatalf("Ожидали Транспорт первым, получили %+v", suggestions)
+	}
+
+	var sum float64
+	for _, s := range suggestions {
+		sum += s.Confidence
+	}
+	if sum < 0.99 || sum > 1.01 {
+		t.Errorf("Уверенности должны давать в сумме 1, получили %f", sum)
+	}
+
+	if suggestions := c.Suggest("латте", 0, 1); len(suggestions) != 1 || suggestions[0].Category != "Еда" {
+		t.Errorf("Ожидали одну подсказку Еда, получили %+v", suggestions)
+	}
+}
+
+func TestClassifier_RetrainsOnUpdate(t *testing.T) {
+	repo := NewMockRepository()
+	classifier := NewCategoryClassifier()
+	svc := NewExpenseService(repo, WithClassifier(classifier))
+	ctx := context.Background()
+
+	created, _ := svc.CreateExpense(ctx, models.CreateExpenseRequest{
+		Description: "Абонемент в бассейн", Amount: 3000, Category: "Разное", Date: "2024-01-15",
+	})
+	svc.CreateExpense(ctx, models.CreateExpenseRequest{
+		Description: "Хлеб", Amount: 50, Category: "Еда", Date: "2024-01-15",
+	})
+
+	sport := "Спорт"
+	if _, err := svc.UpdateExpense(ctx, created.ID, models.UpdateExpenseRequest{Category: &sport}); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	suggestions, err := svc.SuggestCategories(ctx, "бассейн", 3000, 3)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if suggestions[0].Category != "Спорт" {
+		t.Errorf("После переобучения ожидали Спорт, получили %+v", suggestions)
+	}
+	for _, s := range suggestions {
+		if s.Category == "Разное" {
+			t.Errorf("Старая категория должна была уйти из модели: %+v", suggestions)
+		}
+	}
+
+	if err := svc.DeleteExpense(ctx, created.ID, 0); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if suggestions, _ := svc.SuggestCategories(ctx, "бассейн", 3000, 3); suggestions[0].Category != "Еда" {
+		t.Errorf("После удаления осталась только Еда, получили %+v", suggestions)
+	}
+}
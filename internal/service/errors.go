@@ -0,0 +1,123 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Виды ошибок сервиса. Хендлеры выбирают код ответа по ним через errors.Is,
+// поэтому каждая ошибка ниже (sentinel или тип) относится к одному из видов.
+// Ошибка, не относящаяся ни к одному, считается внутренней
+var (
+	// ErrNotFound - объекта нет или он не виден текущему пользователю
+	ErrNotFound = errors.New("не найдено")
+	// ErrValidation - неверные данные или параметры запроса
+	ErrValidation = errors.New("неверные данные")
+	// ErrConflict - запрос противоречит текущему состоянию
+	ErrConflict = errors.New("конфликт")
+	// ErrForbidden - объект есть, но менять его этому пользователю нельзя
+	ErrForbidden = errors.New("доступ запрещён")
+	// ErrInternal - сбой или неверная настройка сервера, клиент тут ни при чём
+	ErrInternal = errors.New("внутренняя ошибка")
+)
+
+// kindError - sentinel со своим текстом, относящийся к одному из видов
+type kindError struct {
+	kind error
+	msg  string
+}
+
+// newError создаёт sentinel вида kind: errors.Is(err, kind) для него true
+func newError(kind error, msg string) error {
+	return &kindError{kind: kind, msg: msg}
+}
+
+func (e *kindError) Error() string {
+	return e.msg
+}
+
+func (e *kindError) Is(target error) bool {
+	return target == e.kind
+}
+
+// NotFoundError - нет объекта с таким ID
+type NotFoundError struct {
+	Resource string // expense, rule
+	ID       int64
+}
+
+// notFoundMessages - текст ошибки по виду объекта, чтобы не путаться в родах
+var notFoundMessages = map[string]string{
+	"expense": "расход с id=%d не найден",
+	"rule":    "правило с id=%d не найдено",
+}
+
+func (e *NotFoundError) Error() string {
+	if msg, ok := notFoundMessages[e.Resource]; ok {
+		return fmt.Sprintf(msg, e.ID)
+	}
+	return fmt.Sprintf("%s с id=%d не найден", e.Resource, e.ID)
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// notFound переводит "нет строки" из репозитория в NotFoundError,
+// остальные ошибки возвращает как есть
+func notFound(err error, resource string, id int64) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return &NotFoundError{Resource: resource, ID: id}
+	}
+	return err
+}
+
+// FieldError - что не так с одним полем запроса
+// Field - имя как в JSON или в параметрах запроса
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError - данные не прошли проверку, с разбором по полям
+// Err - более точный sentinel (ErrInvalidExpense, ErrInvalidFilter, ...), может быть nil
+type ValidationError struct {
+	Err    error
+	Fields []FieldError
+}
+
+// Invalid - ошибка проверки одного поля
+func Invalid(field, message string) *ValidationError {
+	return &ValidationError{Fields: []FieldError{{Field: field, Message: message}}}
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		parts = append(parts, f.Field+": "+f.Message)
+	}
+	msg := strings.Join(parts, "; ")
+
+	if e.Err == nil {
+		return msg
+	}
+	if msg == "" {
+		return e.Err.Error()
+	}
+	return e.Err.Error() + ": " + msg
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// invalidAs - ошибка проверки одного поля, относящаяся к sentinel'у kind
+func invalidAs(kind error, field, message string) error {
+	return &ValidationError{Err: kind, Fields: []FieldError{{Field: field, Message: message}}}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+)
+
+// failingRepository - репозиторий, у которого отказала БД
+type failingRepository struct {
+	*MockExpenseRepository
+	err error
+}
+
+func (r failingRepository) GetByID(ctx context.Context, id int64) (*models.Expense, error) {
+	return nil, r.err
+}
+
+func TestErrorKinds(t *testing.T) {
+	tests := []struct {
+		err  error
+		kind error
+	}{
+		{ErrInvalidFilter, ErrValidation},
+		{ErrInvalidExpense, ErrValidation},
+		{ErrInvalidBatch, ErrValidation},
+		{ErrVersionConflict, ErrConflict},
+		{ErrBatchAborted, ErrConflict},
+		{ErrPatchTestFailed, ErrConflict},
+		{ErrViewNotFound, ErrNotFound},
+		{ErrNotInTrash, ErrNotFound},
+		{ErrViewForbidden, ErrForbidden},
+		{&NotFoundError{Resource: "rule", ID: 1}, ErrNotFound},
+		{Invalid("amount", "должно быть больше 0"), ErrValidation},
+	}
+
+	for _, tt := range tests {
+		if !errors.Is(tt.err, tt.kind) {
+			t.Errorf("%v: ожидали вид %v", tt.err, tt.kind)
+		}
+	}
+}
+
+func TestGetExpense_NotFoundOrFailure(t *testing.T) {
+	svc := NewExpenseService(NewMockRepository())
+
+	_, err := svc.GetExpense(context.Background(), 42)
+	var notFound *NotFoundError
+	if !errors.As(err, &notFound) || notFound.ID != 42 {
+		t.Fatalf("Ожидали NotFoundError для id=42, получили %v", err)
+	}
+
+	// Отказ БД не должен выглядеть как "не найдено"
+	dbErr := errors.New("connection refused")
+	svc = NewExpenseService(failingRepository{NewMockRepository(), dbErr})
+
+	_, err = svc.GetExpense(context.Background(), 42)
+	if !errors.Is(err, dbErr) || errors.Is(err, ErrNotFound) || errors.Is(err, ErrValidation) {
+		t.Errorf("Ожидали исходную ошибку БД, получили %v", err)
+	}
+}
+
+func TestReplaceExpense_AllFieldErrors(t *testing.T) {
+	svc := NewExpenseService(NewMockRepository())
+
+	_, err := svc.ReplaceExpense(context.Background(), 1, models.ReplaceExpenseRequest{
+		Description: "Кофе",
+		Amount:      -1,
+		Tags:        []string{"ok", ""},
+		Date:        "15.01.2024",
+	})
+
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) || !errors.Is(err, ErrInvalidExpense) {
+		t.Fatalf("Ожидали ValidationError с ErrInvalidExpense, получили %v", err)
+	}
+
+	var fields []string
+	for _, f := range invalid.Fields {
+		fields = append(fields, f.Field)
+	}
+	want := []string{"amount", "category", "tags[1]", "date"}
+	if len(fields) != len(want) {
+		t.Fatalf("Ожидали ошибки полей %v, получили %v", want, fields)
+	}
+	for i := range want {
+		if fields[i] != want[i] {
+			t.Errorf("Ожидали ошибки полей %v, получили %v", want, fields)
+			break
+		}
+	}
+}
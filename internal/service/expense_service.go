@@ -3,9 +3,14 @@ package service
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"strings"
 	"time"
 
+	"github.com/dvoryadkinadv/expense-tracker/internal/filterql"
+	"github.com/dvoryadkinadv/expense-tracker/internal/logging"
 	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // ExpenseRepository описывает интерфейс работы с хранилищем
@@ -14,62 +19,220 @@ type ExpenseRepository interface {
 	Create(ctx context.Context, expense *models.Expense) error
 	GetByID(ctx context.Context, id int64) (*models.Expense, error)
 	GetAll(ctx context.Context, filter models.ExpenseFilter) ([]models.Expense, error)
+	Count(ctx context.Context, filter models.ExpenseFilter) (int, error)
 	Update(ctx context.Context, id int64, req models.UpdateExpenseRequest) (*models.Expense, error)
-	Delete(ctx context.Context, id int64) error
-	GetStats(ctx context.Context) (*models.ExpenseStats, error)
+	Delete(ctx context.Context, id int64, version int) error
+	GetStats(ctx context.Context, filter models.ExpenseFilter) (*models.ExpenseStats, error)
 	GetCategories(ctx context.Context) ([]string, error)
 }
 
+// ErrVersionConflict - расход успел измениться после того, как клиент его прочитал
+var ErrVersionConflict = newError(ErrConflict, "расход был изменён, перечитайте его и повторите")
+
 // ExpenseService содержит бизнес-логику работы с расходами
 // Пока тут всё просто, но в будущем можно добавить валидацию,
 // нотификации, логирование и прочее
 type ExpenseService struct {
-	repo ExpenseRepository
+	repo       ExpenseRepository
+	anomalies  *AnomalyService
+	rules      *RuleService
+	classifier *CategoryClassifier
+	views      *ViewService
+	tx         Transactor
+	batchLimit int
+	audit      *AuditService
+	metrics    ExpenseMetrics
+}
+
+// ExpenseMetrics - бизнес-счётчики для мониторинга (реализует пакет metrics)
+type ExpenseMetrics interface {
+	ExpenseCreated(category string, amount float64)
+}
+
+// Option - необязательная настройка сервиса
+// Так можно подключать дополнительные возможности, не ломая конструктор
+type Option func(*ExpenseService)
+
+// WithAnomalyDetector включает подсказки о необычных расходах при создании
+func WithAnomalyDetector(a *AnomalyService) Option {
+	return func(s *ExpenseService) {
+		s.anomalies = a
+	}
+}
+
+// WithRules включает автокатегоризацию по правилам при создании
+func WithRules(r *RuleService) Option {
+	return func(s *ExpenseService) {
+		s.rules = r
+	}
+}
+
+// WithClassifier подключает подсказки категорий
+// Классификатор дообучается на каждом созданном, изменённом и удалённом расходе
+func WithClassifier(c *CategoryClassifier) Option {
+	return func(s *ExpenseService) {
+		s.classifier = c
+	}
+}
+
+// WithViews позволяет запускать сохранённые представления (filter.ViewID)
+func WithViews(v *ViewService) Option {
+	return func(s *ExpenseService) {
+		s.views = v
+	}
+}
+
+// WithAudit включает журнал изменений: каждое создание, изменение
+// и удаление записывается с автором и разницей по полям
+func WithAudit(a *AuditService) Option {
+	return func(s *ExpenseService) {
+		s.audit = a
+	}
+}
+
+// WithTransactions нужен для атомарных пакетных операций
+func WithTransactions(t Transactor) Option {
+	return func(s *ExpenseService) {
+		s.tx = t
+	}
+}
+
+// WithBatchLimit задаёт максимум операций в одном пакете
+func WithBatchLimit(n int) Option {
+	return func(s *ExpenseService) {
+		if n > 0 {
+			s.batchLimit = n
+		}
+	}
+}
+
+// WithMetrics считает созданные расходы и их суммы по категориям
+func WithMetrics(m ExpenseMetrics) Option {
+	return func(s *ExpenseService) {
+		s.metrics = m
+	}
 }
 
 // NewExpenseService создаёт новый сервис
-func NewExpenseService(repo ExpenseRepository) *ExpenseService {
-	return &ExpenseService{repo: repo}
+func NewExpenseService(repo ExpenseRepository, opts ...Option) *ExpenseService {
+	s := &ExpenseService{repo: repo, batchLimit: DefaultBatchLimit}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // CreateExpense создаёт новый расход
-func (s *ExpenseService) CreateExpense(ctx context.Context, req models.CreateExpenseRequest) (*models.Expense, error) {
-	// Парсим дату
+func (s *ExpenseService) CreateExpense(ctx context.Context, req models.CreateExpenseRequest) (_ *models.Expense, err error) {
+	ctx, span := startSpan(ctx, "ExpenseService.CreateExpense")
+	defer func() { endSpan(span, err) }()
+
+	expense, err := newExpense(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Правила могут поправить категорию, описание и добавить теги
+	if s.rules != nil {
+		if _, err := s.rules.Apply(ctx, expense); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := checkCategory(expense); err != nil {
+		return nil, err
+	}
+
+	err = s.audit.Track(ctx, models.AuditCreate, func(ctx context.Context) (*models.Expense, *models.Expense, error) {
+		return nil, expense, s.repo.Create(ctx, expense)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if s.classifier != nil {
+		afterCommit(ctx, func() { s.classifier.Learn(*expense) })
+	}
+	s.recordCreated(expense)
+
+	// Подсказка об аномалии - это бонус, из-за неё создание не должно падать
+	if s.anomalies != nil {
+		hint, err := s.anomalies.Check(ctx, expense)
+		if err != nil {
+			slog.WarnContext(ctx, "Не удалось проверить расход на аномалии",
+				slog.Int64("expense_id", expense.ID), logging.Err(err))
+		}
+		expense.Anomaly = hint
+	}
+
+	return expense, nil
+}
+
+// recordCreated учитывает созданный расход в метриках
+func (s *ExpenseService) recordCreated(expense *models.Expense) {
+	if s.metrics != nil {
+		s.metrics.ExpenseCreated(expense.Category, expense.Amount)
+	}
+}
+
+// newExpense собирает расход из запроса
+func newExpense(req models.CreateExpenseRequest) (*models.Expense, error) {
 	date, err := time.Parse("2006-01-02", req.Date)
 	if err != nil {
-		return nil, fmt.Errorf("неверный формат даты, используйте YYYY-MM-DD: %w", err)
+		return nil, invalidAs(ErrInvalidExpense, "date", "неверный формат даты, используйте YYYY-MM-DD")
 	}
 
-	expense := &models.Expense{
+	return &models.Expense{
 		Description: req.Description,
 		Amount:      req.Amount,
 		Category:    req.Category,
+		Merchant:    req.Merchant,
+		Tags:        models.Tags(req.Tags),
 		Date:        date,
-	}
+	}, nil
+}
 
-	if err := s.repo.Create(ctx, expense); err != nil {
-		return nil, err
+// checkCategory - категория обязательна, если её не подобрали правила
+func checkCategory(expense *models.Expense) error {
+	if expense.Category == "" {
+		return invalidAs(ErrInvalidExpense, "category", "категория не указана, и ни одно правило её не подобрало")
 	}
-
-	return expense, nil
+	return nil
 }
 
 // GetExpense возвращает расход по ID
-func (s *ExpenseService) GetExpense(ctx context.Context, id int64) (*models.Expense, error) {
+func (s *ExpenseService) GetExpense(ctx context.Context, id int64) (_ *models.Expense, err error) {
+	ctx, span := startSpan(ctx, "ExpenseService.GetExpense", attribute.Int64("expense.id", id))
+	defer func() { endSpan(span, err) }()
+
 	expense, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
 	if expense == nil {
-		return nil, fmt.Errorf("расход с id=%d не найден", id)
+		return nil, &NotFoundError{Resource: "expense", ID: id}
 	}
 
 	return expense, nil
 }
 
-// GetExpenses возвращает список расходов с фильтрацией
-func (s *ExpenseService) GetExpenses(ctx context.Context, filter models.ExpenseFilter) ([]models.Expense, error) {
+// GetExpenses возвращает страницу расходов с фильтрацией
+// Кроме самих расходов отдаёт курсоры на соседние страницы
+// и, если попросили, общее количество
+func (s *ExpenseService) GetExpenses(ctx context.Context, filter models.ExpenseFilter) (_ *models.ExpensePage, err error) {
+	ctx, span := startSpan(ctx, "ExpenseService.GetExpenses", filterAttributes(filter)...)
+	defer func() { endSpan(span, err) }()
+
+	filter, err = s.resolveView(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if filter.ViewID != 0 {
+		// Условия представления видны в спане только после подстановки
+		span.SetAttributes(filterAttributes(filter)...)
+	}
+
 	// Устанавливаем дефолтный лимит, чтобы не выгружать всю базу
 	if filter.Limit <= 0 {
 		filter.Limit = 50
@@ -80,35 +243,283 @@ func (s *ExpenseService) GetExpenses(ctx context.Context, filter models.ExpenseF
 		filter.Limit = 100
 	}
 
-	return s.repo.GetAll(ctx, filter)
+	if len([]rune(filter.Query)) > 200 {
+		return nil, invalidAs(ErrInvalidFilter, "q", "слишком длинный поисковый запрос (максимум 200 символов)")
+	}
+
+	if err := validateFilter(filter); err != nil {
+		return nil, err
+	}
+
+	// Поиск без явной сортировки идёт по релевантности, а по ней keyset не построить
+	relevance := strings.TrimSpace(filter.Query) != "" && filter.Sort == ""
+
+	sort, err := normalizeSort(filter.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	if filter.Cursor != "" {
+		if relevance {
+			return nil, invalidAs(ErrInvalidFilter, "cursor", "курсоры при поиске работают только с явной сортировкой (sort=...)")
+		}
+		keyset, err := decodeCursor(filter.Cursor, sort)
+		if err != nil {
+			return nil, err
+		}
+		filter.Keyset = keyset
+		filter.Offset = 0
+	}
+
+	// Берём на одну запись больше, чтобы понять, есть ли следующая страница
+	limit := filter.Limit
+	fetch := filter
+	fetch.Limit = limit + 1
+
+	items, err := s.repo.GetAll(ctx, fetch)
+	if err != nil {
+		return nil, err
+	}
+
+	backward := filter.Keyset != nil && filter.Keyset.Backward
+	hasMore := len(items) > limit
+	if hasMore {
+		// Назад лишняя запись оказывается в начале (репозиторий уже развернул порядок)
+		if backward {
+			items = items[1:]
+		} else {
+			items = items[:limit]
+		}
+	}
+
+	page := &models.ExpensePage{Items: items, Limit: limit}
+
+	if len(items) > 0 && !relevance {
+		// Следующая страница есть, если вперёд осталось ещё или мы пришли сзади
+		if (!backward && hasMore) || backward {
+			page.NextCursor = encodeCursor(sort, keysetOf(items[len(items)-1], sort, false))
+		}
+		// Предыдущая - если мы уже не в начале списка
+		if (backward && hasMore) || (!backward && (filter.Keyset != nil || filter.Offset > 0)) {
+			page.PrevCursor = encodeCursor(sort, keysetOf(items[0], sort, true))
+		}
+	}
+
+	if filter.WithTotal {
+		total, err := s.repo.Count(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		page.TotalCount = &total
+	}
+
+	span.SetAttributes(attribute.Int("expense.returned", len(items)))
+	return page, nil
+}
+
+// resolveView подставляет условия сохранённого представления, если оно указано
+func (s *ExpenseService) resolveView(ctx context.Context, filter models.ExpenseFilter) (models.ExpenseFilter, error) {
+	if filter.ViewID == 0 {
+		return filter, nil
+	}
+
+	if s.views == nil {
+		return filter, fmt.Errorf("%w: сохранённые представления не подключены", ErrInternal)
+	}
+
+	return s.views.Resolve(ctx, filter)
+}
+
+// validateFilter проверяет значения фильтров списка
+func validateFilter(filter models.ExpenseFilter) error {
+	if filter.AmountMin != nil && filter.AmountMax != nil && *filter.AmountMin > *filter.AmountMax {
+		return invalidAs(ErrInvalidFilter, "amount_min", "amount_min не может быть больше amount_max")
+	}
+
+	if filter.CreatedAfter != "" {
+		if _, err := time.Parse(time.RFC3339, filter.CreatedAfter); err != nil {
+			if _, err := time.Parse("2006-01-02", filter.CreatedAfter); err != nil {
+				return invalidAs(ErrInvalidFilter, "created_after", "нужна дата (YYYY-MM-DD) или RFC3339")
+			}
+		}
+	}
+
+	if len([]rune(filter.DescriptionContains)) > 200 {
+		return invalidAs(ErrInvalidFilter, "description_contains", "слишком длинная строка (максимум 200 символов)")
+	}
+
+	// Разбираем заранее, чтобы ошибка с позицией дошла до клиента как 400
+	if filter.Expression != "" {
+		if _, err := filterql.Parse(filter.Expression); err != nil {
+			return invalidAs(ErrInvalidFilter, "filter", err.Error())
+		}
+	}
+
+	return nil
 }
 
 // UpdateExpense обновляет расход
-func (s *ExpenseService) UpdateExpense(ctx context.Context, id int64, req models.UpdateExpenseRequest) (*models.Expense, error) {
-	// Проверяем, существует ли расход
-	existing, err := s.repo.GetByID(ctx, id)
+// Если в req.Version передана версия, а расход с тех пор изменился,
+// возвращает ErrVersionConflict
+func (s *ExpenseService) UpdateExpense(ctx context.Context, id int64, req models.UpdateExpenseRequest) (_ *models.Expense, err error) {
+	ctx, span := startSpan(ctx, "ExpenseService.UpdateExpense", attribute.Int64("expense.id", id))
+	defer func() { endSpan(span, err) }()
+
+	var before models.Expense
+	var updated *models.Expense
+
+	err = s.audit.Track(ctx, models.AuditUpdate, func(ctx context.Context) (*models.Expense, *models.Expense, error) {
+		// Проверяем, существует ли расход
+		existing, err := s.repo.GetByID(ctx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if existing == nil {
+			return nil, nil, &NotFoundError{Resource: "expense", ID: id}
+		}
+
+		if req.Version != nil && *req.Version != existing.Version {
+			return nil, nil, versionConflict(id, *req.Version, existing.Version)
+		}
+
+		// Копия: репозиторий может вернуть тот же объект, что и обновит
+		before = *existing
+
+		updated, err = s.repo.Update(ctx, id, req)
+		if err != nil {
+			return nil, nil, err
+		}
+		if updated == nil {
+			// Между чтением и записью расход успел поменять кто-то ещё
+			if req.Version != nil {
+				return nil, nil, fmt.Errorf("%w: id=%d", ErrVersionConflict, id)
+			}
+			// Без версии это значит, что расход успели удалить
+			return nil, nil, &NotFoundError{Resource: "expense", ID: id}
+		}
+
+		return &before, updated, nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if existing == nil {
-		return nil, fmt.Errorf("расход с id=%d не найден", id)
+	if s.classifier != nil && updated != nil {
+		// Внутри общей транзакции (например, ReapplyRules) - только после её коммита
+		learned := *updated
+		afterCommit(ctx, func() {
+			s.classifier.Forget(before)
+			s.classifier.Learn(learned)
+		})
+	}
+
+	return updated, nil
+}
+
+// DeleteExpense перемещает расход в корзину
+// version > 0 - удалить, только если расход не менялся с этой версии
+func (s *ExpenseService) DeleteExpense(ctx context.Context, id int64, version int) (err error) {
+	ctx, span := startSpan(ctx, "ExpenseService.DeleteExpense", attribute.Int64("expense.id", id))
+	defer func() { endSpan(span, err) }()
+
+	var deleted models.Expense
+
+	err = s.audit.Track(ctx, models.AuditDelete, func(ctx context.Context) (*models.Expense, *models.Expense, error) {
+		existing, err := s.repo.GetByID(ctx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if existing == nil {
+			return nil, nil, &NotFoundError{Resource: "expense", ID: id}
+		}
+		if version > 0 && version != existing.Version {
+			return nil, nil, versionConflict(id, version, existing.Version)
+		}
+		deleted = *existing
+
+		if err := s.repo.Delete(ctx, id, version); err != nil {
+			if version > 0 {
+				// Расход на месте, значит не совпала версия
+				if current, getErr := s.repo.GetByID(ctx, id); getErr == nil && current != nil {
+					return nil, nil, fmt.Errorf("%w: id=%d", ErrVersionConflict, id)
+				}
+			}
+			return nil, nil, notFound(err, "expense", id)
+		}
+
+		return &deleted, nil, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.classifier != nil {
+		afterCommit(ctx, func() { s.classifier.Forget(deleted) })
 	}
 
-	return s.repo.Update(ctx, id, req)
+	return nil
 }
 
-// DeleteExpense удаляет расход
-func (s *ExpenseService) DeleteExpense(ctx context.Context, id int64) error {
-	return s.repo.Delete(ctx, id)
+func versionConflict(id int64, expected, actual int) error {
+	return fmt.Errorf("%w: id=%d, ожидалась версия %d, текущая %d", ErrVersionConflict, id, expected, actual)
+}
+
+// GetStats возвращает статистику по расходам, подходящим под фильтр
+func (s *ExpenseService) GetStats(ctx context.Context, filter models.ExpenseFilter) (_ *models.ExpenseStats, err error) {
+	ctx, span := startSpan(ctx, "ExpenseService.GetStats", filterAttributes(filter)...)
+	defer func() { endSpan(span, err) }()
+
+	filter, err = s.resolveView(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if filter.ViewID != 0 {
+		// Условия представления видны в спане только после подстановки
+		span.SetAttributes(filterAttributes(filter)...)
+	}
+
+	if err := validateFilter(filter); err != nil {
+		return nil, err
+	}
+
+	stats, err := s.repo.GetStats(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(
+		attribute.Int("expense.stats.count", stats.ExpenseCount),
+		attribute.Int("expense.stats.categories", len(stats.ByCategory)),
+	)
+	return stats, nil
 }
 
-// GetStats возвращает статистику
-func (s *ExpenseService) GetStats(ctx context.Context) (*models.ExpenseStats, error) {
-	return s.repo.GetStats(ctx)
+// SuggestCategories подсказывает категорию по описанию и сумме
+func (s *ExpenseService) SuggestCategories(ctx context.Context, description string, amount float64, limit int) (_ []models.CategorySuggestion, err error) {
+	_, span := startSpan(ctx, "ExpenseService.SuggestCategories")
+	defer func() { endSpan(span, err) }()
+
+	if s.classifier == nil {
+		return nil, fmt.Errorf("%w: подсказки категорий не включены", ErrInternal)
+	}
+
+	if strings.TrimSpace(description) == "" {
+		return nil, Invalid("description", "нужно указать описание")
+	}
+
+	if limit <= 0 || limit > 10 {
+		limit = 3
+	}
+
+	return s.classifier.Suggest(description, amount, limit), nil
 }
 
 // GetCategories возвращает список категорий
-func (s *ExpenseService) GetCategories(ctx context.Context) ([]string, error) {
+func (s *ExpenseService) GetCategories(ctx context.Context) (_ []string, err error) {
+	ctx, span := startSpan(ctx, "ExpenseService.GetCategories")
+	defer func() { endSpan(span, err) }()
+
 	return s.repo.GetCategories(ctx)
 }
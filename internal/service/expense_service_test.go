@@ -2,11 +2,19 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"slices"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 // MockExpenseRepository - мок репозитория для тестов
@@ -27,6 +35,7 @@ func (m *MockExpenseRepository) Create(ctx context.Context, expense *models.Expe
 	m.lastID++
 	expense.ID = m.lastID
 	expense.CreatedAt = time.Now()
+	expense.Version = 1
 	m.expenses[expense.ID] = expense
 	return nil
 }
@@ -39,23 +48,89 @@ func (m *MockExpenseRepository) GetByID(ctx context.Context, id int64) (*models.
 }
 
 func (m *MockExpenseRepository) GetAll(ctx context.Context, filter models.ExpenseFilter) ([]models.Expense, error) {
-	var result []models.Expense
+	result := m.filter(filter)
+
+	// Как в БД: новые сверху, при равной дате - больший id
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].Date.Equal(result[j].Date) {
+			return result[i].Date.After(result[j].Date)
+		}
+		return result[i].ID > result[j].ID
+	})
+
+	if ks := filter.Keyset; ks != nil {
+		var page []models.Expense
+		for _, e := range result {
+			key := e.Date.Format("2006-01-02")
+			after := key < ks.Value || (key == ks.Value && e.ID < ks.ID)
+			before := key > ks.Value || (key == ks.Value && e.ID > ks.ID)
+			if (!ks.Backward && after) || (ks.Backward && before) {
+				page = append(page, e)
+			}
+		}
+		// Назад берём ближайшие к курсору записи
+		if ks.Backward && filter.Limit > 0 && len(page) > filter.Limit {
+			page = page[len(page)-filter.Limit:]
+		}
+		result = page
+	} else if filter.Offset > 0 {
+		if filter.Offset >= len(result) {
+			return []models.Expense{}, nil
+		}
+		result = result[filter.Offset:]
+	}
+
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+	return result, nil
+}
+
+func (m *MockExpenseRepository) Count(ctx context.Context, filter models.ExpenseFilter) (int, error) {
+	return len(m.filter(filter)), nil
+}
+
+// filter - простая фильтрация по категориям и сумме
+func (m *MockExpenseRepository) filter(filter models.ExpenseFilter) []models.Expense {
+	result := []models.Expense{}
 	for _, e := range m.expenses {
-		// Простая фильтрация по категории
 		if filter.Category != "" && e.Category != filter.Category {
 			continue
 		}
+		if len(filter.Categories) > 0 && !slices.Contains(filter.Categories, e.Category) {
+			continue
+		}
+		if slices.Contains(filter.ExcludeCategories, e.Category) {
+			continue
+		}
+		if filter.AmountMin != nil && e.Amount < *filter.AmountMin {
+			continue
+		}
+		if filter.AmountMax != nil && e.Amount > *filter.AmountMax {
+			continue
+		}
 		result = append(result, *e)
 	}
+	return result
+}
+
+func (m *MockExpenseRepository) GetSince(ctx context.Context, since time.Time) ([]models.Expense, error) {
+	var result []models.Expense
+	for _, e := range m.expenses {
+		if !e.Date.Before(since) {
+			result = append(result, *e)
+		}
+	}
 	return result, nil
 }
 
 func (m *MockExpenseRepository) Update(ctx context.Context, id int64, req models.UpdateExpenseRequest) (*models.Expense, error) {
 	expense, ok := m.expenses[id]
-	if !ok {
+	if !ok || (req.Version != nil && *req.Version != expense.Version) {
 		return nil, nil
 	}
 
+	expense.Version++
 	if req.Description != nil {
 		expense.Description = *req.Description
 	}
@@ -65,19 +140,23 @@ func (m *MockExpenseRepository) Update(ctx context.Context, id int64, req models
 	if req.Category != nil {
 		expense.Category = *req.Category
 	}
+	if req.Tags != nil {
+		expense.Tags = models.Tags(*req.Tags)
+	}
 
 	return expense, nil
 }
 
-func (m *MockExpenseRepository) Delete(ctx context.Context, id int64) error {
-	if _, ok := m.expenses[id]; !ok {
-		return errors.New("not found")
+func (m *MockExpenseRepository) Delete(ctx context.Context, id int64, version int) error {
+	expense, ok := m.expenses[id]
+	if !ok || (version > 0 && version != expense.Version) {
+		return sql.ErrNoRows
 	}
 	delete(m.expenses, id)
 	return nil
 }
 
-func (m *MockExpenseRepository) GetStats(ctx context.Context) (*models.ExpenseStats, error) {
+func (m *MockExpenseRepository) GetStats(ctx context.Context, filter models.ExpenseFilter) (*models.ExpenseStats, error) {
 	stats := &models.ExpenseStats{
 		ByCategory: make(map[string]float64),
 	}
@@ -223,8 +302,8 @@ func TestGetExpenses_WithFilter(t *testing.T) {
 		t.Fatalf("Неожиданная ошибка: %v", err)
 	}
 
-	if len(result) != 2 {
-		t.Errorf("Ожидали 2 расхода в категории 'Еда', получили %d", len(result))
+	if len(result.Items) != 2 {
+		t.Errorf("Ожидали 2 расхода в категории 'Еда', получили %d", len(result.Items))
 	}
 }
 
@@ -281,7 +360,7 @@ func TestDeleteExpense_Success(t *testing.T) {
 	created, _ := svc.CreateExpense(ctx, req)
 
 	// Удаляем
-	err := svc.DeleteExpense(ctx, created.ID)
+	err := svc.DeleteExpense(ctx, created.ID, 0)
 
 	if err != nil {
 		t.Fatalf("Неожиданная ошибка: %v", err)
@@ -294,6 +373,76 @@ func TestDeleteExpense_Success(t *testing.T) {
 	}
 }
 
+func TestUpdateExpense_VersionConflict(t *testing.T) {
+	repo := NewMockRepository()
+	svc := NewExpenseService(repo)
+	ctx := context.Background()
+
+	created, _ := svc.CreateExpense(ctx, models.CreateExpenseRequest{
+		Description: "Обед", Amount: 500, Category: "Еда", Date: "2024-01-15",
+	})
+
+	// Первый редактор успевает раньше и поднимает версию
+	first := "Обед с коллегами"
+	v1 := created.Version
+	updated, err := svc.UpdateExpense(ctx, created.ID, models.UpdateExpenseRequest{Description: &first, Version: &v1})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if updated.Version != v1+1 {
+		t.Errorf("Версия должна вырасти: было %d, стало %d", v1, updated.Version)
+	}
+
+	// Второй редактор прислал устаревшую версию
+	second := "Бизнес-ланч"
+	if _, err := svc.UpdateExpense(ctx, created.ID, models.UpdateExpenseRequest{Description: &second, Version: &v1}); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Ожидали ErrVersionConflict, получили %v", err)
+	}
+	if repo.expenses[created.ID].Description != first {
+		t.Errorf("Изменение первого редактора не должно потеряться")
+	}
+
+	if err := svc.DeleteExpense(ctx, created.ID, v1); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Удаление: ожидали ErrVersionConflict, получили %v", err)
+	}
+	if err := svc.DeleteExpense(ctx, created.ID, updated.Version); err != nil {
+		t.Errorf("Удаление с актуальной версией: %v", err)
+	}
+}
+
+func TestUpdateExpense_DeletedConcurrently(t *testing.T) {
+	repo := NewMockRepository()
+	ctx := context.Background()
+
+	created, _ := NewExpenseService(repo).CreateExpense(ctx, models.CreateExpenseRequest{
+		Description: "Обед", Amount: 500, Category: "Еда", Date: "2024-01-15",
+	})
+
+	// Расход удаляют между чтением и записью
+	svc := NewExpenseService(&vanishingRepository{MockExpenseRepository: repo})
+	desc := "Ужин"
+	updated, err := svc.UpdateExpense(ctx, created.ID, models.UpdateExpenseRequest{Description: &desc})
+
+	var notFound *NotFoundError
+	if !errors.As(err, &notFound) || notFound.ID != created.ID {
+		t.Errorf("Ожидали NotFoundError для id=%d, получили %v", created.ID, err)
+	}
+	if updated != nil {
+		t.Errorf("Ожидали nil вместо расхода, получили %+v", updated)
+	}
+}
+
+// vanishingRepository - репозиторий, в котором расход удаляют сразу после чтения
+type vanishingRepository struct {
+	*MockExpenseRepository
+}
+
+func (r *vanishingRepository) GetByID(ctx context.Context, id int64) (*models.Expense, error) {
+	expense, err := r.MockExpenseRepository.GetByID(ctx, id)
+	delete(r.expenses, id)
+	return expense, err
+}
+
 func TestGetStats(t *testing.T) {
 	repo := NewMockRepository()
 	svc := NewExpenseService(repo)
@@ -310,7 +459,7 @@ func TestGetStats(t *testing.T) {
 		svc.CreateExpense(ctx, req)
 	}
 
-	stats, err := svc.GetStats(ctx)
+	stats, err := svc.GetStats(ctx, models.ExpenseFilter{})
 
 	if err != nil {
 		t.Fatalf("Неожиданная ошибка: %v", err)
@@ -348,3 +497,251 @@ func TestGetExpenses_DefaultLimit(t *testing.T) {
 		t.Fatalf("Неожиданная ошибка: %v", err)
 	}
 }
+
+func TestGetExpenses_QueryTooLong(t *testing.T) {
+	repo := NewMockRepository()
+	svc := NewExpenseService(repo)
+	ctx := context.Background()
+
+	filter := models.ExpenseFilter{Query: strings.Repeat("я", 201)}
+	if _, err := svc.GetExpenses(ctx, filter); err == nil {
+		t.Error("Ожидали ошибку для слишком длинного запроса")
+	}
+}
+
+func TestGetExpenses_CursorPagination(t *testing.T) {
+	repo := NewMockRepository()
+	svc := NewExpenseService(repo)
+	ctx := context.Background()
+
+	// 5 расходов, по одному в день
+	for day := 1; day <= 5; day++ {
+		svc.CreateExpense(ctx, models.CreateExpenseRequest{
+			Description: "Расход", Amount: 100, Category: "Еда",
+			Date: time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC).Format("2006-01-02"),
+		})
+	}
+
+	first, err := svc.GetExpenses(ctx, models.ExpenseFilter{Limit: 2, WithTotal: true})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if len(first.Items) != 2 || first.Items[0].ID != 5 || first.NextCursor == "" || first.PrevCursor != "" {
+		t.Fatalf("Первая страница: неожиданный результат %+v", first)
+	}
+	if first.TotalCount == nil || *first.TotalCount != 5 {
+		t.Errorf("TotalCount: ожидали 5, получили %v", first.TotalCount)
+	}
+
+	second, err := svc.GetExpenses(ctx, models.ExpenseFilter{Limit: 2, Cursor: first.NextCursor})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if len(second.Items) != 2 || second.Items[0].ID != 3 || second.PrevCursor == "" {
+		t.Fatalf("Вторая страница: неожиданный результат %+v", second)
+	}
+
+	// Возвращаемся назад - должны получить ту же первую страницу
+	back, err := svc.GetExpenses(ctx, models.ExpenseFilter{Limit: 2, Cursor: second.PrevCursor})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if len(back.Items) != 2 || back.Items[0].ID != 5 || back.Items[1].ID != 4 {
+		t.Errorf("Назад: ожидали расходы 5 и 4, получили %+v", back.Items)
+	}
+	if back.PrevCursor != "" {
+		t.Error("На первой странице не должно быть prev_cursor")
+	}
+
+	last, _ := svc.GetExpenses(ctx, models.ExpenseFilter{Limit: 2, Cursor: second.NextCursor})
+	if len(last.Items) != 1 || last.NextCursor != "" {
+		t.Errorf("Последняя страница: неожиданный результат %+v", last)
+	}
+}
+
+func TestGetExpenses_InvalidCursor(t *testing.T) {
+	repo := NewMockRepository()
+	svc := NewExpenseService(repo)
+
+	_, err := svc.GetExpenses(context.Background(), models.ExpenseFilter{Cursor: "не-курсор"})
+	if !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("Ожидали ErrInvalidFilter, получили %v", err)
+	}
+
+	// Значение в курсоре должно подходить к полю сортировки,
+	// иначе запрос упал бы в БД на приведении типа
+	tests := []struct {
+		sort  string
+		value string
+		valid bool
+	}{
+		{"-date", "2024-01-15", true},
+		{"-date", "15.01.2024", false},
+		{"amount", "250.5", true},
+		{"amount", "много", false},
+		{"amount", "NaN", false},
+		{"created_at", "2024-01-15T10:00:00.123456Z", true},
+		{"created_at", "2024-01-15", false},
+		{"category", "Кафе, рестораны", true},
+	}
+	for _, tt := range tests {
+		cursor := encodeCursor(tt.sort, models.Keyset{Value: tt.value, ID: 1})
+		_, err := decodeCursor(cursor, tt.sort)
+		if tt.valid != (err == nil) {
+			t.Errorf("%s=%q: ожидали valid=%v, получили %v", tt.sort, tt.value, tt.valid, err)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("%s=%q: ожидали ErrInvalidFilter, получили %v", tt.sort, tt.value, err)
+		}
+	}
+}
+
+func TestGetExpenses_AdvancedFilters(t *testing.T) {
+	repo := NewMockRepository()
+	svc := NewExpenseService(repo)
+	ctx := context.Background()
+
+	expenses := []models.CreateExpenseRequest{
+		{Description: "Кофе", Amount: 200, Category: "Еда", Date: "2024-01-15"},
+		{Description: "Такси", Amount: 700, Category: "Транспорт", Date: "2024-01-15"},
+		{Description: "Кино", Amount: 500, Category: "Развлечения", Date: "2024-01-16"},
+		{Description: "Ужин", Amount: 1500, Category: "Еда", Date: "2024-01-16"},
+	}
+	for _, req := range expenses {
+		svc.CreateExpense(ctx, req)
+	}
+
+	min, max := 300.0, 1000.0
+	page, err := svc.GetExpenses(ctx, models.ExpenseFilter{
+		ExcludeCategories: []string{"Развлечения"},
+		AmountMin:         &min,
+		AmountMax:         &max,
+	})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Description != "Такси" {
+		t.Errorf("Ожидали только Такси, получили %+v", page.Items)
+	}
+
+	page, _ = svc.GetExpenses(ctx, models.ExpenseFilter{Categories: []string{"Еда", "Транспорт"}})
+	if len(page.Items) != 3 {
+		t.Errorf("Ожидали 3 расхода из двух категорий, получили %d", len(page.Items))
+	}
+}
+
+func TestGetExpenses_FilterValidation(t *testing.T) {
+	repo := NewMockRepository()
+	svc := NewExpenseService(repo)
+	ctx := context.Background()
+
+	min, max := 500.0, 100.0
+	invalid := []models.ExpenseFilter{
+		{Sort: "password"},
+		{Sort: "-id; DROP TABLE expenses"},
+		{AmountMin: &min, AmountMax: &max},
+		{CreatedAfter: "вчера"},
+	}
+
+	for _, filter := range invalid {
+		if _, err := svc.GetExpenses(ctx, filter); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("Фильтр %+v: ожидали ErrInvalidFilter, получили %v", filter, err)
+		}
+	}
+
+	for _, sort := range []string{"amount", "-amount", "date", "created_at", "-category"} {
+		if _, err := svc.GetExpenses(ctx, models.ExpenseFilter{Sort: sort}); err != nil {
+			t.Errorf("Сортировка %s должна быть допустима, получили %v", sort, err)
+		}
+	}
+}
+
+func TestGetExpenses_CursorFromOtherSort(t *testing.T) {
+	repo := NewMockRepository()
+	svc := NewExpenseService(repo)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		svc.CreateExpense(ctx, models.CreateExpenseRequest{Description: "Расход", Amount: 100, Category: "Еда", Date: "2024-01-15"})
+	}
+
+	page, _ := svc.GetExpenses(ctx, models.ExpenseFilter{Limit: 1, Sort: "-amount"})
+	if page.NextCursor == "" {
+		t.Fatal("Ожидали next_cursor")
+	}
+
+	_, err := svc.GetExpenses(ctx, models.ExpenseFilter{Limit: 1, Cursor: page.NextCursor})
+	if !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("Курсор от другой сортировки должен отклоняться, получили %v", err)
+	}
+}
+
+// recordingMetrics запоминает суммы созданных расходов по категориям
+type recordingMetrics map[string]float64
+
+func (m recordingMetrics) ExpenseCreated(category string, amount float64) {
+	m[category] += amount
+}
+
+func TestCreateExpense_RecordsMetrics(t *testing.T) {
+	repo := NewMockRepository()
+	recorded := recordingMetrics{}
+	svc := NewExpenseService(repo, WithMetrics(recorded), WithTransactions(&mockTransactor{repo: repo}))
+	ctx := context.Background()
+
+	svc.CreateExpense(ctx, models.CreateExpenseRequest{Description: "Кофе", Amount: 200, Category: "Еда", Date: "2024-01-15"})
+	svc.CreateExpense(ctx, models.CreateExpenseRequest{Description: "Без даты", Amount: 999, Category: "Еда", Date: "вчера"})
+
+	_, err := svc.Batch(ctx, models.BatchRequest{Operations: []models.BatchOperation{
+		{Op: models.BatchCreate, Expense: &models.CreateExpenseRequest{Description: "Обед", Amount: 300, Category: "Еда", Date: "2024-01-16"}},
+		{Op: models.BatchCreate, Expense: &models.CreateExpenseRequest{Description: "Такси", Amount: 500, Category: "Транспорт", Date: "2024-01-16"}},
+		{Op: models.BatchDelete, ID: 1},
+	}})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	if recorded["Еда"] != 500 || recorded["Транспорт"] != 500 || len(recorded) != 2 {
+		t.Errorf("Ожидали Еда=500 и Транспорт=500, получили %v", recorded)
+	}
+}
+
+func TestExpenseService_Spans(t *testing.T) {
+	// tracer пакета получен до теста, но глобальный провайдер передаёт его первому заданному
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	svc := NewExpenseService(NewMockRepository())
+	ctx := context.Background()
+
+	svc.CreateExpense(ctx, models.CreateExpenseRequest{Description: "Кофе", Amount: 200, Category: "Еда", Date: "2024-01-15"})
+	svc.GetExpenses(ctx, models.ExpenseFilter{Category: "Еда"})
+	svc.GetExpense(ctx, 999)
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Ожидали 3 спана, получили %d", len(spans))
+	}
+
+	names := []string{spans[0].Name(), spans[1].Name(), spans[2].Name()}
+	want := []string{"ExpenseService.CreateExpense", "ExpenseService.GetExpenses", "ExpenseService.GetExpense"}
+	if !slices.Equal(names, want) {
+		t.Errorf("Ожидали спаны %v, получили %v", want, names)
+	}
+
+	var category string
+	for _, kv := range spans[1].Attributes() {
+		if kv.Key == "expense.filter.category" {
+			category = kv.Value.AsString()
+		}
+	}
+	if category != "Еда" {
+		t.Errorf("Фильтр должен попасть в атрибуты спана, получили %v", spans[1].Attributes())
+	}
+
+	if spans[0].Status().Code == codes.Error || spans[2].Status().Code != codes.Error {
+		t.Errorf("Ошибкой должен быть отмечен только GetExpense(999): %v, %v", spans[0].Status(), spans[2].Status())
+	}
+}
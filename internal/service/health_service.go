@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/logging"
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+)
+
+// Pinger - проверка соединения с БД (*sqlx.DB)
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// SchemaVersioner - версия схемы: применённая и та, которую ждёт бинарник (*migrate.Runner)
+type SchemaVersioner interface {
+	Version(ctx context.Context) (int, error)
+	Latest() int
+}
+
+// WorkerStatus - состояние фоновой задачи (*worker.Periodic)
+type WorkerStatus interface {
+	Name() string
+	Interval() time.Duration
+	Running() bool
+	LastRun() (time.Time, error)
+}
+
+// HealthConfig - настройки проверок готовности
+type HealthConfig struct {
+	// Timeout - сколько ждать каждую проверку
+	Timeout time.Duration
+	// CacheTTL - сколько отдавать прошлый результат
+	// Пробы kubernetes, балансировщика и мониторинга не должны каждый раз ходить в БД
+	CacheTTL time.Duration
+}
+
+// DefaultHealthConfig возвращает настройки по умолчанию
+func DefaultHealthConfig() HealthConfig {
+	return HealthConfig{
+		Timeout:  2 * time.Second,
+		CacheTTL: time.Second,
+	}
+}
+
+// HealthService проверяет, готов ли экземпляр обслуживать запросы
+type HealthService struct {
+	db      Pinger
+	schema  SchemaVersioner
+	workers []WorkerStatus
+	cfg     HealthConfig
+	now     func() time.Time
+
+	// mu держится и на время проверки: одновременные пробы ждут
+	// один общий результат, а не идут в БД каждая сама
+	mu     sync.Mutex
+	cached *models.HealthReport
+}
+
+// NewHealthService создаёт сервис проверок
+// schema может быть nil - тогда версия схемы не проверяется
+func NewHealthService(db Pinger, schema SchemaVersioner, cfg HealthConfig, workers ...WorkerStatus) *HealthService {
+	return &HealthService{db: db, schema: schema, workers: workers, cfg: cfg, now: time.Now}
+}
+
+// Ready проверяет БД, версию схемы и фоновые задачи
+// БД и схема обязательны (fail - не готов), фоновые задачи - нет (degraded):
+// без очистки корзины запросы обслуживать можно
+func (s *HealthService) Ready(ctx context.Context) models.HealthReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached != nil && s.now().Sub(s.cached.CheckedAt) < s.cfg.CacheTTL {
+		return *s.cached
+	}
+
+	// Результат общий для всех, кто ждёт на mu, и уходит в кеш: проба,
+	// отключившаяся раньше времени, не должна оборвать проверку остальным.
+	// Дольше s.cfg.Timeout проверки всё равно не идут
+	report := s.check(context.WithoutCancel(ctx))
+	s.cached = &report
+	return report
+}
+
+// check выполняет все проверки параллельно
+func (s *HealthService) check(ctx context.Context) models.HealthReport {
+	checks := map[string]func(ctx context.Context) models.HealthCheck{
+		"database": s.checkDatabase,
+	}
+	if s.schema != nil {
+		checks["migrations"] = s.checkMigrations
+	}
+	for _, w := range s.workers {
+		checks["worker:"+w.Name()] = func(context.Context) models.HealthCheck { return s.checkWorker(w) }
+	}
+
+	report := models.HealthReport{
+		Status:    models.HealthOK,
+		CheckedAt: s.now(),
+		Checks:    make(map[string]models.HealthCheck, len(checks)),
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+			defer cancel()
+
+			start := time.Now()
+			result := check(ctx)
+			result.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+
+			mu.Lock()
+			report.Checks[name] = result
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	for _, c := range report.Checks {
+		switch {
+		case c.Status == models.HealthFail:
+			report.Status = models.HealthFail
+		case c.Status == models.HealthDegraded && report.Status == models.HealthOK:
+			report.Status = models.HealthDegraded
+		}
+	}
+	return report
+}
+
+// checkDatabase пингует БД
+// /readyz открыт без авторизации, поэтому текст ошибки драйвера (адрес,
+// имя пользователя) идёт только в лог, а в ответ - общее сообщение
+func (s *HealthService) checkDatabase(ctx context.Context) models.HealthCheck {
+	if err := s.db.PingContext(ctx); err != nil {
+		slog.ErrorContext(ctx, "Проверка готовности: БД недоступна", logging.Err(err))
+		return models.HealthCheck{Status: models.HealthFail, Error: "БД недоступна"}
+	}
+	return models.HealthCheck{Status: models.HealthOK}
+}
+
+// checkMigrations сравнивает версию схемы с той, что ждёт бинарник
+// Схема новее - не ошибка: так бывает во время выкладки, пока работают
+// старые экземпляры, а миграции пишутся обратно совместимыми
+func (s *HealthService) checkMigrations(ctx context.Context) models.HealthCheck {
+	version, err := s.schema.Version(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Проверка готовности: не удалось узнать версию схемы", logging.Err(err))
+		return models.HealthCheck{Status: models.HealthFail, Error: "не удалось узнать версию схемы"}
+	}
+
+	expected := s.schema.Latest()
+	result := models.HealthCheck{
+		Status:  models.HealthOK,
+		Details: map[string]interface{}{"version": version, "expected": expected},
+	}
+	switch {
+	case version < expected:
+		result.Status = models.HealthFail
+		result.Error = fmt.Sprintf("схема БД отстаёт: версия %d, нужна %d", version, expected)
+	case version > expected:
+		result.Status = models.HealthDegraded
+		result.Error = fmt.Sprintf("схема БД новее бинарника: версия %d, бинарник знает до %d", version, expected)
+	}
+	return result
+}
+
+// checkWorker - задача запущена, последний прогон без ошибки и не слишком давно
+func (s *HealthService) checkWorker(w WorkerStatus) models.HealthCheck {
+	lastRun, err := w.LastRun()
+
+	result := models.HealthCheck{
+		Status:  models.HealthOK,
+		Details: map[string]interface{}{"interval": w.Interval().String()},
+	}
+	if !lastRun.IsZero() {
+		result.Details["last_run"] = lastRun
+	}
+
+	switch {
+	case !w.Running():
+		result.Status = models.HealthDegraded
+		result.Error = "задача не запущена"
+	case err != nil:
+		// Подробности - в логе воркера, в ответ их не отдаём, как и для БД
+		result.Status = models.HealthDegraded
+		result.Error = "последний прогон завершился ошибкой"
+	// Прогон мог затянуться, поэтому тревожимся только после двух пропущенных интервалов
+	case !lastRun.IsZero() && s.now().Sub(lastRun) > 2*w.Interval():
+		result.Status = models.HealthDegraded
+		result.Error = "задача давно не выполнялась"
+	}
+	return result
+}
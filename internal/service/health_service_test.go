@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+)
+
+type fakePinger struct {
+	err   error
+	calls atomic.Int32
+}
+
+func (p *fakePinger) PingContext(ctx context.Context) error {
+	p.calls.Add(1)
+	return p.err
+}
+
+type fakeSchema struct {
+	version, latest int
+}
+
+func (s fakeSchema) Version(ctx context.Context) (int, error) { return s.version, nil }
+func (s fakeSchema) Latest() int                              { return s.latest }
+
+type fakeWorker struct {
+	running bool
+	lastRun time.Time
+	err     error
+}
+
+func (w fakeWorker) Name() string                { return "trash-purge" }
+func (w fakeWorker) Interval() time.Duration     { return time.Hour }
+func (w fakeWorker) Running() bool               { return w.running }
+func (w fakeWorker) LastRun() (time.Time, error) { return w.lastRun, w.err }
+
+func TestHealthService_Ready(t *testing.T) {
+	now := time.Now()
+	healthy := fakeWorker{running: true, lastRun: now.Add(-time.Minute)}
+
+	tests := []struct {
+		name   string
+		db     error
+		schema fakeSchema
+		worker fakeWorker
+		want   string
+		check  string
+	}{
+		{"всё в порядке", nil, fakeSchema{12, 12}, healthy, models.HealthOK, ""},
+		{"БД недоступна", errors.New("connection refused"), fakeSchema{12, 12}, healthy, models.HealthFail, "database"},
+		{"схема отстаёт", nil, fakeSchema{11, 12}, healthy, models.HealthFail, "migrations"},
+		{"схема новее", nil, fakeSchema{13, 12}, healthy, models.HealthDegraded, "migrations"},
+		{"задача падает", nil, fakeSchema{12, 12}, fakeWorker{running: true, lastRun: now, err: errors.New("timeout")}, models.HealthDegraded, "worker:trash-purge"},
+		{"задача давно не выполнялась", nil, fakeSchema{12, 12}, fakeWorker{running: true, lastRun: now.Add(-3 * time.Hour)}, models.HealthDegraded, "worker:trash-purge"},
+		{"задача остановлена", nil, fakeSchema{12, 12}, fakeWorker{lastRun: now}, models.HealthDegraded, "worker:trash-purge"},
+	}
+
+	for _, tt := range tests {
+		svc := NewHealthService(&fakePinger{err: tt.db}, tt.schema, DefaultHealthConfig(), tt.worker)
+		report := svc.Ready(context.Background())
+
+		if report.Status != tt.want {
+			t.Errorf("%s: ожидали %s, получили %s (%v)", tt.name, tt.want, report.Status, report.Checks)
+		}
+		if len(report.Checks) != 3 {
+			t.Errorf("%s: ожидали 3 проверки, получили %v", tt.name, report.Checks)
+		}
+		if tt.check != "" && report.Checks[tt.check].Error == "" {
+			t.Errorf("%s: проверка %s должна объяснить проблему: %v", tt.name, tt.check, report.Checks[tt.check])
+		}
+	}
+}
+
+func TestHealthService_Cache(t *testing.T) {
+	db := &fakePinger{}
+	svc := NewHealthService(db, nil, HealthConfig{Timeout: time.Second, CacheTTL: time.Minute})
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+
+	svc.Ready(context.Background())
+	svc.Ready(context.Background())
+	if db.calls.Load() != 1 {
+		t.Errorf("Повторная проверка в пределах TTL должна взять кеш, пингов: %d", db.calls.Load())
+	}
+
+	now = now.Add(2 * time.Minute)
+	svc.Ready(context.Background())
+	if db.calls.Load() != 2 {
+		t.Errorf("После TTL проверка должна повториться, пингов: %d", db.calls.Load())
+	}
+}
+
+func TestHealthService_Timeout(t *testing.T) {
+	svc := NewHealthService(slowPinger{}, nil, HealthConfig{Timeout: 10 * time.Millisecond})
+
+	start := time.Now()
+	report := svc.Ready(context.Background())
+
+	if time.Since(start) > time.Second {
+		t.Error("Проверка должна прерываться по таймауту")
+	}
+	if report.Status != models.HealthFail || report.Checks["database"].Error == "" {
+		t.Errorf("Зависшая БД - не готов, получили %v", report)
+	}
+}
+
+// slowPinger отвечает только по отмене контекста, как БД за сломанной сетью
+type slowPinger struct{}
+
+func (slowPinger) PingContext(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestHealthService_IgnoresCallerCancel(t *testing.T) {
+	svc := NewHealthService(ctxPinger{}, nil, HealthConfig{Timeout: time.Second, CacheTTL: time.Minute})
+
+	// Проба отключилась, но общий результат проверки от этого не должен стать fail
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report := svc.Ready(ctx)
+	if report.Status != models.HealthOK {
+		t.Errorf("Отмена вызывающего не должна ломать проверку, получили %v", report)
+	}
+}
+
+func TestHealthService_HidesDatabaseError(t *testing.T) {
+	svc := NewHealthService(&fakePinger{err: errors.New("dial tcp 10.0.0.5:5432: password authentication failed for user \"app\"")}, nil, DefaultHealthConfig())
+
+	report := svc.Ready(context.Background())
+	if got := report.Checks["database"].Error; got != "БД недоступна" {
+		t.Errorf("Ожидали общее сообщение без деталей драйвера, получили %q", got)
+	}
+}
+
+// ctxPinger - БД в порядке, пока не отменён контекст проверки
+type ctxPinger struct{}
+
+func (ctxPinger) PingContext(ctx context.Context) error {
+	return ctx.Err()
+}
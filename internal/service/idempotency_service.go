@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+)
+
+// IdempotencyRepository - хранилище ключей идемпотентности
+type IdempotencyRepository interface {
+	ReserveKey(ctx context.Context, record *models.IdempotencyRecord, staleBefore time.Time) (bool, error)
+	GetKey(ctx context.Context, owner, key string) (*models.IdempotencyRecord, error)
+	CompleteKey(ctx context.Context, record *models.IdempotencyRecord) error
+	ReleaseKey(ctx context.Context, owner, key string) error
+	DeleteExpiredKeys(ctx context.Context, before time.Time) (int64, error)
+}
+
+// DefaultIdempotencyTTL - сколько хранится ответ на запрос с ключом
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// IdempotencyLease - сколько ключ может числиться "в работе"
+// Если процесс упал посреди запроса, ответ не сохранится и ключ никто
+// не освободит; после аренды его можно занять заново. Аренда с запасом
+// длиннее server.write_timeout, чтобы не отобрать ключ у живого запроса
+const IdempotencyLease = 2 * time.Minute
+
+// maxIdempotencyKeyLength - длина колонки key в БД
+const maxIdempotencyKeyLength = 255
+
+var (
+	// ErrInvalidIdempotencyKey - пустой или слишком длинный ключ
+	ErrInvalidIdempotencyKey = newError(ErrValidation, "неверный Idempotency-Key")
+	// ErrIdempotencyKeyReused - с этим ключом уже был другой запрос
+	ErrIdempotencyKeyReused = newError(ErrConflict, "Idempotency-Key уже использован для другого запроса")
+	// ErrIdempotencyInProgress - первый запрос с этим ключом ещё выполняется
+	ErrIdempotencyInProgress = newError(ErrConflict, "запрос с этим Idempotency-Key ещё выполняется")
+)
+
+// IdempotencyService запоминает ответы на запросы с Idempotency-Key,
+// чтобы повтор запроса (например, после обрыва сети) не создавал дубль
+type IdempotencyService struct {
+	repo  IdempotencyRepository
+	ttl   time.Duration
+	lease time.Duration
+	now   func() time.Time
+}
+
+// NewIdempotencyService создаёт сервис ключей
+// ttl <= 0 - DefaultIdempotencyTTL
+func NewIdempotencyService(repo IdempotencyRepository, ttl time.Duration) *IdempotencyService {
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	return &IdempotencyService{repo: repo, ttl: ttl, lease: IdempotencyLease, now: time.Now}
+}
+
+// Begin занимает ключ под запрос с отпечатком fingerprint
+// Если ключ свободен, возвращает (nil, nil) - запрос надо выполнить и вызвать Complete.
+// Если на этот запрос уже есть ответ - возвращает его для повтора.
+// Тот же ключ с другим запросом - ErrIdempotencyKeyReused,
+// первый запрос ещё не закончился - ErrIdempotencyInProgress
+func (s *IdempotencyService) Begin(ctx context.Context, key, fingerprint string) (*models.IdempotencyRecord, error) {
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return nil, invalidAs(ErrInvalidIdempotencyKey, "Idempotency-Key", fmt.Sprintf("ожидается строка длиной от 1 до %d символов", maxIdempotencyKeyLength))
+	}
+
+	now := s.now()
+	record := &models.IdempotencyRecord{
+		Owner:       UserFromContext(ctx),
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.ttl),
+	}
+
+	// Вторая попытка нужна, если ключ освободили между ReserveKey и GetKey
+	for attempt := 0; attempt < 2; attempt++ {
+		reserved, err := s.repo.ReserveKey(ctx, record, now.Add(-s.lease))
+		if err != nil {
+			return nil, err
+		}
+		if reserved {
+			return nil, nil
+		}
+
+		existing, err := s.repo.GetKey(ctx, record.Owner, key)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			continue
+		}
+
+		if existing.Fingerprint != fingerprint {
+			return nil, ErrIdempotencyKeyReused
+		}
+		if !existing.Completed() {
+			return nil, ErrIdempotencyInProgress
+		}
+		return existing, nil
+	}
+
+	return nil, ErrIdempotencyInProgress
+}
+
+// Complete сохраняет ответ на запрос, начатый через Begin
+// Ответы 5xx не сохраняются: ключ освобождается, и запрос можно повторить
+func (s *IdempotencyService) Complete(ctx context.Context, key string, status int, contentType string, body []byte) error {
+	owner := UserFromContext(ctx)
+
+	if status >= 500 {
+		return s.Release(ctx, key)
+	}
+
+	return s.repo.CompleteKey(ctx, &models.IdempotencyRecord{
+		Owner:       owner,
+		Key:         key,
+		StatusCode:  status,
+		ContentType: contentType,
+		Body:        body,
+	})
+}
+
+// Release освобождает ключ, не сохраняя ответа: запрос можно повторить
+// Для запросов, которые не дошли до ответа (паника в обработчике)
+func (s *IdempotencyService) Release(ctx context.Context, key string) error {
+	return s.repo.ReleaseKey(ctx, UserFromContext(ctx), key)
+}
+
+// PurgeExpired удаляет истёкшие ключи
+// Запускается воркером по расписанию
+func (s *IdempotencyService) PurgeExpired(ctx context.Context) (int64, error) {
+	return s.repo.DeleteExpiredKeys(ctx, s.now())
+}
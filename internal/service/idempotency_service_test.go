@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+)
+
+// mockIdempotencyRepository - ключи в памяти, с той же логикой истечения, что в БД
+type mockIdempotencyRepository struct {
+	records map[string]models.IdempotencyRecord
+}
+
+func newMockIdempotencyRepository() *mockIdempotencyRepository {
+	return &mockIdempotencyRepository{records: make(map[string]models.IdempotencyRecord)}
+}
+
+func (m *mockIdempotencyRepository) ReserveKey(ctx context.Context, record *models.IdempotencyRecord, staleBefore time.Time) (bool, error) {
+	id := record.Owner + "/" + record.Key
+	if existing, ok := m.records[id]; ok && existing.ExpiresAt.After(record.CreatedAt) &&
+		(existing.Completed() || !existing.CreatedAt.Before(staleBefore)) {
+		return false, nil
+	}
+	m.records[id] = *record
+	return true, nil
+}
+
+func (m *mockIdempotencyRepository) GetKey(ctx context.Context, owner, key string) (*models.IdempotencyRecord, error) {
+	if r, ok := m.records[owner+"/"+key]; ok {
+		return &r, nil
+	}
+	return nil, nil
+}
+
+func (m *mockIdempotencyRepository) CompleteKey(ctx context.Context, record *models.IdempotencyRecord) error {
+	id := record.Owner + "/" + record.Key
+	r := m.records[id]
+	r.StatusCode, r.ContentType, r.Body = record.StatusCode, record.ContentType, record.Body
+	m.records[id] = r
+	return nil
+}
+
+func (m *mockIdempotencyRepository) ReleaseKey(ctx context.Context, owner, key string) error {
+	if r, ok := m.records[owner+"/"+key]; ok && !r.Completed() {
+		delete(m.records, owner+"/"+key)
+	}
+	return nil
+}
+
+func (m *mockIdempotencyRepository) DeleteExpiredKeys(ctx context.Context, before time.Time) (int64, error) {
+	var n int64
+	for id, r := range m.records {
+		if !r.ExpiresAt.After(before) {
+			delete(m.records, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func TestIdempotency_ReplayAndReuse(t *testing.T) {
+	svc := NewIdempotencyService(newMockIdempotencyRepository(), time.Hour)
+	ctx := WithUser(context.Background(), "anna")
+
+	if saved, err := svc.Begin(ctx, "k1", "body-a"); err != nil || saved != nil {
+		t.Fatalf("Первый запрос должен выполниться: %v, %v", saved, err)
+	}
+
+	// Пока первый запрос не закончился, повтор ждать не будет
+	if _, err := svc.Begin(ctx, "k1", "body-a"); !errors.Is(err, ErrIdempotencyInProgress) {
+		t.Errorf("Ожидали ErrIdempotencyInProgress, получили %v", err)
+	}
+
+	svc.Complete(ctx, "k1", 201, "application/json", []byte(`{"success":true}`))
+
+	saved, err := svc.Begin(ctx, "k1", "body-a")
+	if err != nil || saved == nil || saved.StatusCode != 201 || string(saved.Body) != `{"success":true}` {
+		t.Errorf("Ожидали сохранённый ответ, получили %+v, %v", saved, err)
+	}
+
+	if _, err := svc.Begin(ctx, "k1", "body-b"); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Errorf("Ожидали ErrIdempotencyKeyReused, получили %v", err)
+	}
+
+	// У другого пользователя свои ключи
+	if saved, err := svc.Begin(WithUser(context.Background(), "bob"), "k1", "body-b"); err != nil || saved != nil {
+		t.Errorf("Ключ другого пользователя не должен мешать: %v, %v", saved, err)
+	}
+}
+
+func TestIdempotency_ExpiryAndServerErrors(t *testing.T) {
+	repo := newMockIdempotencyRepository()
+	svc := NewIdempotencyService(repo, time.Hour)
+	now := time.Date(2026, 5, 17, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// Ответ 5xx не сохраняется: запрос можно повторить с тем же ключом
+	svc.Begin(ctx, "k1", "body-a")
+	svc.Complete(ctx, "k1", 503, "application/json", nil)
+	if saved, err := svc.Begin(ctx, "k1", "body-a"); err != nil || saved != nil {
+		t.Errorf("После 5xx ключ должен освободиться: %v, %v", saved, err)
+	}
+	svc.Complete(ctx, "k1", 201, "application/json", nil)
+
+	// После истечения ключ можно взять и под другой запрос
+	now = now.Add(2 * time.Hour)
+	if saved, err := svc.Begin(ctx, "k1", "body-b"); err != nil || saved != nil {
+		t.Errorf("Истёкший ключ должен освободиться: %v, %v", saved, err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if purged, _ := svc.PurgeExpired(ctx); purged != 1 || len(repo.records) != 0 {
+		t.Errorf("Ожидали очистку одного ключа, удалено %d", purged)
+	}
+
+	if _, err := svc.Begin(ctx, "", "body-a"); !errors.Is(err, ErrInvalidIdempotencyKey) {
+		t.Errorf("Ожидали ErrInvalidIdempotencyKey, получили %v", err)
+	}
+}
+
+func TestIdempotency_StaleReservation(t *testing.T) {
+	repo := newMockIdempotencyRepository()
+	svc := NewIdempotencyService(repo, time.Hour)
+	now := time.Date(2026, 5, 17, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// Процесс упал посреди запроса: ответа нет, ключ никто не освободил
+	svc.Begin(ctx, "k1", "body-a")
+
+	now = now.Add(IdempotencyLease / 2)
+	if _, err := svc.Begin(ctx, "k1", "body-a"); !errors.Is(err, ErrIdempotencyInProgress) {
+		t.Errorf("До конца аренды ожидали ErrIdempotencyInProgress, получили %v", err)
+	}
+
+	now = now.Add(IdempotencyLease)
+	if saved, err := svc.Begin(ctx, "k1", "body-a"); err != nil || saved != nil {
+		t.Errorf("После аренды ключ должен освободиться: %v, %v", saved, err)
+	}
+
+	// Готовый ответ аренда не трогает, а Release его не удаляет
+	svc.Complete(ctx, "k1", 201, "application/json", nil)
+	now = now.Add(2 * IdempotencyLease)
+	svc.Release(ctx, "k1")
+	if saved, err := svc.Begin(ctx, "k1", "body-a"); err != nil || saved == nil {
+		t.Errorf("Ожидали сохранённый ответ, получили %v, %v", saved, err)
+	}
+}
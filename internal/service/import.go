@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+)
+
+// maxImportRows - сколько строк можно загрузить одним файлом
+const maxImportRows = 1000
+
+// ErrInvalidImport - файл импорта не прошёл проверку, ничего не загружено
+var ErrInvalidImport = newError(ErrValidation, "файл импорта не прошёл проверку")
+
+// importColumns - колонки CSV; обязательные - description, amount, date
+var importColumns = map[string]bool{
+	"description": true,
+	"amount":      true,
+	"category":    false,
+	"merchant":    false,
+	"tags":        false,
+	"date":        true,
+}
+
+// Import загружает расходы из CSV
+// Первая строка - заголовок с именами колонок (description, amount, category,
+// merchant, tags, date) в любом порядке; теги - через ";".
+// Загрузка - атомарный пакет создания: правила автокатегоризации, журнал
+// и подсказки работают как при обычном создании, а при ошибке в любой
+// строке не загружается ничего
+func (s *ExpenseService) Import(ctx context.Context, r io.Reader) (*models.BatchResult, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, invalidAs(ErrInvalidImport, "body", "пустой файл")
+		}
+		return nil, invalidAs(ErrInvalidImport, "body", err.Error())
+	}
+
+	columns, err := importHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var ops []models.BatchOperation
+	var fields []FieldError
+	for row := 0; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, invalidAs(ErrInvalidImport, "body", err.Error())
+		}
+		if len(ops) == maxImportRows {
+			return nil, invalidAs(ErrInvalidImport, "body", fmt.Sprintf("слишком много строк (максимум %d)", maxImportRows))
+		}
+
+		req, rowFields := importRow(columns, record, fmt.Sprintf("rows[%d].", row))
+		fields = append(fields, rowFields...)
+		ops = append(ops, models.BatchOperation{Op: models.BatchCreate, Expense: &req})
+	}
+
+	if len(fields) > 0 {
+		return nil, &ValidationError{Err: ErrInvalidImport, Fields: fields}
+	}
+	if len(ops) == 0 {
+		return nil, invalidAs(ErrInvalidImport, "body", "в файле нет строк с расходами")
+	}
+
+	atomic := true
+	result, err := s.runBatch(ctx, "ExpenseService.Import", models.BatchRequest{Atomic: &atomic, Operations: ops}, maxImportRows)
+
+	// Ошибки пакета (например, категорию не подобрало ни одно правило) - по строкам файла
+	var invalid *ValidationError
+	if errors.As(err, &invalid) {
+		invalid.Err = ErrInvalidImport
+		for i := range invalid.Fields {
+			invalid.Fields[i].Field = strings.Replace(invalid.Fields[i].Field, "operations[", "rows[", 1)
+		}
+	}
+	return result, err
+}
+
+// importHeader - номер колонки по имени
+func importHeader(header []string) (map[string]int, error) {
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// Excel сохраняет CSV в UTF-8 с BOM в начале первой колонки
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, ok := importColumns[name]; !ok {
+			return nil, invalidAs(ErrInvalidImport, "header", fmt.Sprintf("неизвестная колонка %q", name))
+		}
+		columns[name] = i
+	}
+
+	for name, required := range importColumns {
+		if _, ok := columns[name]; required && !ok {
+			return nil, invalidAs(ErrInvalidImport, "header", fmt.Sprintf("нет колонки %q", name))
+		}
+	}
+	return columns, nil
+}
+
+// importRow собирает запрос на создание из строки CSV и проверяет его
+func importRow(columns map[string]int, record []string, prefix string) (models.CreateExpenseRequest, []FieldError) {
+	value := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	req := models.CreateExpenseRequest{
+		Description: value("description"),
+		Category:    value("category"),
+		Merchant:    value("merchant"),
+		Date:        value("date"),
+	}
+	if tags := value("tags"); tags != "" {
+		for _, tag := range strings.Split(tags, ";") {
+			req.Tags = append(req.Tags, strings.TrimSpace(tag))
+		}
+	}
+
+	var fields []FieldError
+	amount, err := strconv.ParseFloat(strings.Replace(value("amount"), ",", ".", 1), 64)
+	if err != nil {
+		fields = append(fields, FieldError{Field: prefix + "amount", Message: "нужно число"})
+		amount = 1 // чтобы проверка ниже не сообщала о той же колонке второй раз
+	}
+	req.Amount = amount
+
+	fields = append(fields, expenseFieldErrors(models.ReplaceExpenseRequest{
+		Description: req.Description,
+		Amount:      req.Amount,
+		Category:    req.Category,
+		Merchant:    req.Merchant,
+		Tags:        req.Tags,
+		Date:        req.Date,
+	}, prefix, false)...)
+
+	return req, fields
+}
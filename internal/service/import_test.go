@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+)
+
+func TestImport_AppliesRules(t *testing.T) {
+	repo := NewMockRepository()
+	rules := NewRuleService(&mockRuleRepository{})
+	svc := NewExpenseService(repo, WithRules(rules), WithTransactions(&mockTransactor{repo: repo}))
+	ctx := context.Background()
+
+	rules.CreateRule(ctx, models.RuleRequest{
+		Name:       "Кофейни",
+		Conditions: models.RuleConditions{DescriptionRegex: `(?i)кофе`},
+		Actions:    models.RuleActions{SetCategory: "Еда"},
+	})
+
+	csv := "\ufeffDate,Description,Amount,Category,Tags\n" +
+		"2024-01-15,Кофе с собой,\"250,50\",,утро;работа\n" +
+		"2024-01-16,Такси,300,Транспорт,\n"
+
+	result, err := svc.Import(ctx, strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if result.Succeeded != 2 {
+		t.Fatalf("Ожидали 2 загруженных строки, получили %+v", result)
+	}
+
+	coffee := repo.expenses[1]
+	if coffee.Category != "Еда" || coffee.Amount != 250.5 || len(coffee.Tags) != 2 {
+		t.Errorf("Неожиданный расход: %+v", coffee)
+	}
+}
+
+func TestImport_RejectsWholeFile(t *testing.T) {
+	repo := NewMockRepository()
+	svc := NewExpenseService(repo, WithTransactions(&mockTransactor{repo: repo}))
+
+	tests := []struct {
+		name  string
+		csv   string
+		field string
+	}{
+		{"неизвестная колонка", "description,amount,date,price\n", "header"},
+		{"нет обязательной колонки", "description,date\n", "header"},
+		{"сумма не число", "description,amount,category,date\nКофе,250,Еда,2024-01-15\nОбед,много,Еда,2024-01-15\n", "rows[1].amount"},
+		{"категорию не подобрать", "description,amount,date\nКофе,250,2024-01-15\n", "rows[0]"},
+		{"пустой файл", "", "body"},
+	}
+
+	for _, tt := range tests {
+		_, err := svc.Import(context.Background(), strings.NewReader(tt.csv))
+
+		var invalid *ValidationError
+		if !errors.As(err, &invalid) || !errors.Is(err, ErrInvalidImport) {
+			t.Errorf("%s: ожидали ErrInvalidImport, получили %v", tt.name, err)
+			continue
+		}
+		if len(invalid.Fields) != 1 || invalid.Fields[0].Field != tt.field {
+			t.Errorf("%s: ожидали ошибку поля %s, получили %+v", tt.name, tt.field, invalid.Fields)
+		}
+	}
+
+	if len(repo.expenses) != 0 {
+		t.Errorf("При ошибке ничего не должно загрузиться, загружено %d", len(repo.expenses))
+	}
+}
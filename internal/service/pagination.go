@@ -0,0 +1,111 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+)
+
+// cursorPayload - что лежит внутри курсора
+// Клиенту курсор отдаём как непрозрачную строку: формат может меняться
+type cursorPayload struct {
+	Sort     string `json:"s"`
+	Value    string `json:"v"`
+	ID       int64  `json:"id"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+// ErrInvalidFilter - ошибка в параметрах списка (курсор, поиск и т.п.)
+// По ней хэндлер понимает, что виноват запрос, а не сервер
+var ErrInvalidFilter = newError(ErrValidation, "неверные параметры списка")
+
+var errInvalidCursor = invalidAs(ErrInvalidFilter, "cursor", "неверный курсор")
+
+// encodeCursor упаковывает позицию в base64url(JSON)
+// Сортировку кладём внутрь: курсор от одной сортировки в другой бессмыслен
+func encodeCursor(sort string, ks models.Keyset) string {
+	data, _ := json.Marshal(cursorPayload{Sort: sort, Value: ks.Value, ID: ks.ID, Backward: ks.Backward})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor разбирает курсор, присланный клиентом
+func decodeCursor(cursor, sort string) (*models.Keyset, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	var payload cursorPayload
+	if err := json.Unmarshal(data, &payload); err != nil || payload.ID <= 0 {
+		return nil, errInvalidCursor
+	}
+
+	if payload.Sort != sort {
+		return nil, invalidAs(ErrInvalidFilter, "cursor", fmt.Sprintf("курсор получен для другой сортировки (%s)", payload.Sort))
+	}
+
+	// Значение уходит в SQL с приведением к типу колонки: кривое
+	// значение должно быть ошибкой клиента, а не ошибкой запроса к БД
+	if !validCursorValue(sort, payload.Value) {
+		return nil, errInvalidCursor
+	}
+
+	return &models.Keyset{Value: payload.Value, ID: payload.ID, Backward: payload.Backward}, nil
+}
+
+// validCursorValue проверяет значение курсора по полю сортировки
+// Форматы - те же, что пишет keysetOf
+func validCursorValue(sort, value string) bool {
+	var err error
+	switch strings.TrimPrefix(sort, "-") {
+	case "amount":
+		var amount float64
+		amount, err = strconv.ParseFloat(value, 64)
+		if err == nil && (math.IsNaN(amount) || math.IsInf(amount, 0)) {
+			return false
+		}
+	case "created_at":
+		_, err = time.Parse(time.RFC3339Nano, value)
+	case "category":
+		return true
+	default:
+		_, err = time.Parse("2006-01-02", value)
+	}
+	return err == nil
+}
+
+// keysetOf - позиция расхода в сортировке по (поле, id)
+func keysetOf(e models.Expense, sort string, backward bool) models.Keyset {
+	var value string
+	switch strings.TrimPrefix(sort, "-") {
+	case "amount":
+		value = strconv.FormatFloat(e.Amount, 'f', -1, 64)
+	case "created_at":
+		value = e.CreatedAt.Format(time.RFC3339Nano)
+	case "category":
+		value = e.Category
+	default:
+		value = e.Date.Format("2006-01-02")
+	}
+
+	return models.Keyset{Value: value, ID: e.ID, Backward: backward}
+}
+
+// normalizeSort проверяет поле сортировки по белому списку
+func normalizeSort(sort string) (string, error) {
+	if sort == "" {
+		return models.DefaultSort, nil
+	}
+
+	if !models.SortFields[strings.TrimPrefix(sort, "-")] {
+		return "", invalidAs(ErrInvalidFilter, "sort", fmt.Sprintf("неизвестное поле сортировки %q, допустимо: date, amount, created_at, category (с \"-\" - по убыванию)", sort))
+	}
+
+	return sort, nil
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/jsonpatch"
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// PatchFormat - формат изменений для PATCH
+type PatchFormat string
+
+const (
+	// MergePatch - application/merge-patch+json (RFC 7396)
+	MergePatch PatchFormat = "merge-patch"
+	// JSONPatch - application/json-patch+json (RFC 6902)
+	JSONPatch PatchFormat = "json-patch"
+)
+
+var (
+	// ErrInvalidExpense - расход после замены или патча не проходит проверку
+	ErrInvalidExpense = newError(ErrValidation, "неверные данные расхода")
+	// ErrInvalidPatch - патч не разбирается или ссылается на несуществующие поля
+	ErrInvalidPatch = newError(ErrValidation, "неверный патч")
+	// ErrPatchTestFailed - операция test в JSON Patch не совпала с расходом
+	ErrPatchTestFailed = newError(ErrConflict, "расход не прошёл проверку test из патча")
+)
+
+// ReplaceExpense заменяет расход целиком (PUT)
+// Необязательные поля, которых нет в запросе, очищаются
+func (s *ExpenseService) ReplaceExpense(ctx context.Context, id int64, req models.ReplaceExpenseRequest) (_ *models.Expense, err error) {
+	ctx, span := startSpan(ctx, "ExpenseService.ReplaceExpense", attribute.Int64("expense.id", id))
+	defer func() { endSpan(span, err) }()
+
+	if err := validateReplace(req); err != nil {
+		return nil, err
+	}
+
+	return s.UpdateExpense(ctx, id, req.Changes())
+}
+
+// PatchExpense применяет к расходу merge patch или JSON Patch
+// Патч применяется к документу из ReplaceExpenseRequest (description, amount,
+// category, merchant, tags, date), результат сохраняется как замена.
+// version > 0 - ожидаемая версия из If-Match; без неё расход всё равно
+// не перезапишется, если изменится между чтением и записью
+func (s *ExpenseService) PatchExpense(ctx context.Context, id int64, format PatchFormat, patch []byte, version int) (_ *models.Expense, err error) {
+	ctx, span := startSpan(ctx, "ExpenseService.PatchExpense",
+		attribute.Int64("expense.id", id), attribute.String("patch.format", string(format)))
+	defer func() { endSpan(span, err) }()
+
+	existing, err := s.GetExpense(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if version > 0 && version != existing.Version {
+		return nil, versionConflict(id, version, existing.Version)
+	}
+
+	doc, err := json.Marshal(models.NewReplaceExpenseRequest(*existing))
+	if err != nil {
+		return nil, err
+	}
+
+	var patched []byte
+	switch format {
+	case MergePatch:
+		patched, err = jsonpatch.MergePatch(doc, patch)
+	case JSONPatch:
+		patched, err = jsonpatch.Apply(doc, patch)
+	default:
+		return nil, fmt.Errorf("%w: неизвестный формат %q", ErrInvalidPatch, format)
+	}
+	if err != nil {
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			return nil, fmt.Errorf("%w: %v", ErrPatchTestFailed, err)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	var req models.ReplaceExpenseRequest
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	req.Version = &existing.Version
+	return s.ReplaceExpense(ctx, id, req)
+}
+
+// validateReplace - те же проверки, что в тегах binding у ReplaceExpenseRequest
+// Нужны здесь, потому что после патча запрос собирается не Gin'ом.
+// Собирает ошибки по всем полям сразу, а не только первую
+func validateReplace(req models.ReplaceExpenseRequest) error {
+	if fields := expenseFieldErrors(req, "", true); len(fields) > 0 {
+		return &ValidationError{Err: ErrInvalidExpense, Fields: fields}
+	}
+	return nil
+}
+
+// expenseFieldErrors - проверки полей расхода; prefix дописывается к именам полей
+// Категорию при создании можно не указывать: её подберут правила
+func expenseFieldErrors(req models.ReplaceExpenseRequest, prefix string, categoryRequired bool) []FieldError {
+	var fields []FieldError
+	fail := func(field, message string) {
+		fields = append(fields, FieldError{Field: prefix + field, Message: message})
+	}
+
+	switch n := utf8.RuneCountInString(req.Description); {
+	case n == 0:
+		fail("description", "описание обязательно")
+	case n > 500:
+		fail("description", "описание длиннее 500 символов")
+	}
+
+	if req.Amount <= 0 {
+		fail("amount", "сумма должна быть больше нуля")
+	}
+
+	switch n := utf8.RuneCountInString(req.Category); {
+	case n == 0 && categoryRequired:
+		fail("category", "категория обязательна")
+	case n > 100:
+		fail("category", "категория длиннее 100 символов")
+	}
+
+	if utf8.RuneCountInString(req.Merchant) > 200 {
+		fail("merchant", "продавец длиннее 200 символов")
+	}
+
+	if len(req.Tags) > 20 {
+		fail("tags", "тегов больше 20")
+	}
+	for i, tag := range req.Tags {
+		if n := utf8.RuneCountInString(tag); n == 0 || n > 50 {
+			fail(fmt.Sprintf("tags[%d]", i), fmt.Sprintf("тег %q: длина должна быть от 1 до 50 символов", tag))
+		}
+	}
+
+	if _, err := time.Parse("2006-01-02", req.Date); err != nil {
+		fail("date", "неверный формат даты, используйте YYYY-MM-DD")
+	}
+
+	return fields
+}
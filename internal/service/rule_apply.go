@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// reapplyPageSize - по сколько расходов читаем при повторном применении правил
+const reapplyPageSize = 500
+
+// ReapplyRules прогоняет правила по уже сохранённым расходам
+// С DryRun ничего не сохраняет, а только показывает, что изменится.
+// Изменения идут через UpdateExpense - с журналом, проверкой версии и
+// обновлением подсказок категорий - и одной транзакцией: если расход
+// успели изменить или запись не удалась, не применяется ничего.
+// Подсказки категорий дообучаются только после коммита
+func (s *ExpenseService) ReapplyRules(ctx context.Context, req models.ApplyRulesRequest) (_ *models.ApplyRulesResult, err error) {
+	ctx, span := startSpan(ctx, "ExpenseService.ReapplyRules", attribute.Bool("rules.dry_run", req.DryRun))
+	defer func() { endSpan(span, err) }()
+
+	for _, d := range []struct{ field, value string }{{"date_from", req.DateFrom}, {"date_to", req.DateTo}} {
+		if d.value == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", d.value); err != nil {
+			return nil, Invalid(d.field, "неверный формат даты, используйте YYYY-MM-DD")
+		}
+	}
+
+	if s.rules == nil {
+		return nil, fmt.Errorf("%w: правила не подключены", ErrInternal)
+	}
+
+	rules, err := s.rules.ListRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ApplyRulesResult{
+		DryRun:  req.DryRun,
+		Changes: []models.RuleChange{},
+	}
+
+	run := func(ctx context.Context) error {
+		// Страницы - по keyset в сортировке по дате: правила дату не меняют,
+		// так что исправленные расходы не сдвигают следующие страницы,
+		// даже если из-за новой категории они выпали из фильтра
+		filter := models.ExpenseFilter{
+			Category: req.Category,
+			DateFrom: req.DateFrom,
+			DateTo:   req.DateTo,
+			Sort:     models.DefaultSort,
+			Limit:    reapplyPageSize,
+		}
+
+		for {
+			page, err := s.repo.GetAll(ctx, filter)
+			if err != nil {
+				return err
+			}
+
+			for _, expense := range page {
+				result.Checked++
+
+				change, ok := s.rules.change(expense, rules)
+				if !ok {
+					continue
+				}
+
+				if !req.DryRun {
+					tags := []string(change.After.Tags)
+					version := expense.Version
+					update := models.UpdateExpenseRequest{
+						Description: &change.After.Description,
+						Category:    &change.After.Category,
+						Tags:        &tags,
+						Version:     &version,
+					}
+					if _, err := s.UpdateExpense(ctx, expense.ID, update); err != nil {
+						return err
+					}
+				}
+
+				result.Changes = append(result.Changes, change)
+			}
+
+			if len(page) < reapplyPageSize {
+				return nil
+			}
+			keyset := keysetOf(page[len(page)-1], filter.Sort, false)
+			filter.Keyset = &keyset
+		}
+	}
+
+	if req.DryRun || s.tx == nil {
+		err = run(ctx)
+	} else {
+		err = s.withinTx(ctx, run)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("rules.checked", result.Checked), attribute.Int("rules.changed", len(result.Changes)))
+	return result, nil
+}
@@ -0,0 +1,326 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+)
+
+// RuleRepository описывает хранилище правил
+type RuleRepository interface {
+	CreateRule(ctx context.Context, rule *models.Rule) error
+	GetRule(ctx context.Context, id int64) (*models.Rule, error)
+	ListRules(ctx context.Context) ([]models.Rule, error)
+	UpdateRule(ctx context.Context, rule *models.Rule) error
+	DeleteRule(ctx context.Context, id int64) error
+}
+
+// maxDescriptionLength - столько символов допускает описание расхода
+const maxDescriptionLength = 500
+
+// RuleService управляет правилами и применяет их к расходам
+type RuleService struct {
+	rules RuleRepository
+
+	// Скомпилированные регулярки, чтобы не компилировать их на каждый расход
+	regexps sync.Map
+}
+
+// NewRuleService создаёт новый сервис правил
+func NewRuleService(rules RuleRepository) *RuleService {
+	return &RuleService{rules: rules}
+}
+
+// CreateRule создаёт правило
+func (s *RuleService) CreateRule(ctx context.Context, req models.RuleRequest) (*models.Rule, error) {
+	rule := ruleFromRequest(req)
+	if err := s.validate(rule); err != nil {
+		return nil, err
+	}
+
+	if err := s.rules.CreateRule(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+// GetRule возвращает правило по ID
+func (s *RuleService) GetRule(ctx context.Context, id int64) (*models.Rule, error) {
+	rule, err := s.rules.GetRule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if rule == nil {
+		return nil, &NotFoundError{Resource: "rule", ID: id}
+	}
+
+	return rule, nil
+}
+
+// ListRules возвращает все правила в порядке применения
+func (s *RuleService) ListRules(ctx context.Context) ([]models.Rule, error) {
+	return s.rules.ListRules(ctx)
+}
+
+// UpdateRule заменяет правило целиком
+func (s *RuleService) UpdateRule(ctx context.Context, id int64, req models.RuleRequest) (*models.Rule, error) {
+	rule := ruleFromRequest(req)
+	rule.ID = id
+	if err := s.validate(rule); err != nil {
+		return nil, err
+	}
+
+	if err := s.rules.UpdateRule(ctx, rule); err != nil {
+		return nil, notFound(err, "rule", id)
+	}
+
+	return rule, nil
+}
+
+// DeleteRule удаляет правило
+func (s *RuleService) DeleteRule(ctx context.Context, id int64) error {
+	return notFound(s.rules.DeleteRule(ctx, id), "rule", id)
+}
+
+// Apply применяет включённые правила к расходу (меняет его на месте)
+// Возвращает ID сработавших правил
+func (s *RuleService) Apply(ctx context.Context, expense *models.Expense) ([]int64, error) {
+	rules, err := s.rules.ListRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.applyRules(expense, rules), nil
+}
+
+// ApplyEach применяет правила к нескольким расходам, читая правила один раз
+func (s *RuleService) ApplyEach(ctx context.Context, expenses []*models.Expense) error {
+	rules, err := s.rules.ListRules(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, expense := range expenses {
+		s.applyRules(expense, rules)
+	}
+
+	return nil
+}
+
+// change применяет правила к копии расхода и описывает, что поменялось
+// false - правила расход не меняют
+func (s *RuleService) change(expense models.Expense, rules []models.Rule) (models.RuleChange, bool) {
+	// Теги - свои, чтобы append в движке не задел массив вызывающего
+	expense.Tags = append(models.Tags(nil), expense.Tags...)
+
+	before := subjectOf(&expense)
+	applied := s.applyRules(&expense, rules)
+	after := subjectOf(&expense)
+
+	if sameSubject(before, after) {
+		return models.RuleChange{}, false
+	}
+
+	return models.RuleChange{
+		ExpenseID: expense.ID,
+		RuleIDs:   applied,
+		Before:    before,
+		After:     after,
+	}, true
+}
+
+// applyRules - сам движок
+// Правила идут по убыванию приоритета. Категорию и описание меняет только
+// первое сработавшее правило, которое их задаёт - так более приоритетное
+// правило нельзя случайно перебить. Теги накапливаются
+func (s *RuleService) applyRules(expense *models.Expense, rules []models.Rule) []int64 {
+	sorted := append([]models.Rule(nil), rules...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Priority != sorted[j].Priority {
+			return sorted[i].Priority > sorted[j].Priority
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	applied := []int64{}
+	categorySet, descriptionSet := false, false
+
+	for _, rule := range sorted {
+		if !rule.Enabled {
+			continue
+		}
+
+		re, ok := s.matches(rule.Conditions, expense)
+		if !ok {
+			continue
+		}
+
+		actions := rule.Actions
+
+		if actions.RewriteDescription != "" && !descriptionSet {
+			expense.Description = rewrite(re, actions.RewriteDescription, expense.Description)
+			descriptionSet = true
+		}
+
+		if actions.SetCategory != "" && !categorySet {
+			expense.Category = actions.SetCategory
+			categorySet = true
+		}
+
+		for _, tag := range actions.AddTags {
+			if !expense.Tags.Has(tag) {
+				expense.Tags = append(expense.Tags, tag)
+			}
+		}
+
+		applied = append(applied, rule.ID)
+		if rule.Stop {
+			break
+		}
+	}
+
+	return applied
+}
+
+// matches проверяет условия правила
+// Возвращает регулярку описания (если есть), чтобы подставить группы при переписывании
+func (s *RuleService) matches(cond models.RuleConditions, expense *models.Expense) (*regexp.Regexp, bool) {
+	if cond.AmountMin != nil && expense.Amount < *cond.AmountMin {
+		return nil, false
+	}
+
+	if cond.AmountMax != nil && expense.Amount > *cond.AmountMax {
+		return nil, false
+	}
+
+	if cond.Merchant != "" &&
+		!strings.Contains(strings.ToLower(expense.Merchant), strings.ToLower(cond.Merchant)) {
+		return nil, false
+	}
+
+	if cond.DescriptionRegex == "" {
+		return nil, true
+	}
+
+	re, err := s.compile(cond.DescriptionRegex)
+	if err != nil {
+		// Такие правила не сохраняются, но на всякий случай просто пропускаем
+		return nil, false
+	}
+
+	return re, re.MatchString(expense.Description)
+}
+
+// compile компилирует регулярку с кэшированием
+func (s *RuleService) compile(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := s.regexps.Load(pattern); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	s.regexps.Store(pattern, re)
+	return re, nil
+}
+
+// validate проверяет правило перед сохранением
+func (s *RuleService) validate(rule *models.Rule) error {
+	cond, actions := rule.Conditions, rule.Actions
+
+	if cond.DescriptionRegex != "" {
+		if _, err := s.compile(cond.DescriptionRegex); err != nil {
+			return Invalid("conditions.description_regex", "неверное регулярное выражение: "+err.Error())
+		}
+	}
+
+	if cond.AmountMin != nil && cond.AmountMax != nil && *cond.AmountMin > *cond.AmountMax {
+		return Invalid("conditions.amount_min", "amount_min не может быть больше amount_max")
+	}
+
+	if actions.SetCategory == "" && len(actions.AddTags) == 0 && actions.RewriteDescription == "" {
+		return Invalid("actions", "у правила должно быть хотя бы одно действие")
+	}
+
+	if utf8.RuneCountInString(actions.SetCategory) > 100 {
+		return Invalid("actions.set_category", "слишком длинная категория (максимум 100 символов)")
+	}
+
+	if utf8.RuneCountInString(actions.RewriteDescription) > maxDescriptionLength {
+		return Invalid("actions.rewrite_description", "слишком длинное описание (максимум 500 символов)")
+	}
+
+	for i, tag := range actions.AddTags {
+		if tag == "" || utf8.RuneCountInString(tag) > 50 {
+			return Invalid(fmt.Sprintf("actions.add_tags[%d]", i), "тег должен быть от 1 до 50 символов")
+		}
+	}
+
+	return nil
+}
+
+// rewrite подставляет группы регулярки в шаблон нового описания
+// Группы могут раздуть описание сверх лимита - тогда обрезаю его,
+// а если вышла пустая строка, оставляю старое описание
+func rewrite(re *regexp.Regexp, template, description string) string {
+	result := template
+	if re != nil {
+		if match := re.FindStringSubmatchIndex(description); match != nil {
+			result = string(re.ExpandString(nil, template, description, match))
+		}
+	}
+
+	if strings.TrimSpace(result) == "" {
+		return description
+	}
+	if utf8.RuneCountInString(result) > maxDescriptionLength {
+		result = string([]rune(result)[:maxDescriptionLength])
+	}
+	return result
+}
+
+func ruleFromRequest(req models.RuleRequest) *models.Rule {
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+
+	return &models.Rule{
+		Name:       req.Name,
+		Priority:   req.Priority,
+		Enabled:    enabled,
+		Stop:       req.Stop,
+		Conditions: req.Conditions,
+		Actions:    req.Actions,
+	}
+}
+
+func subjectOf(e *models.Expense) models.RuleSubject {
+	return models.RuleSubject{
+		Description: e.Description,
+		Category:    e.Category,
+		Tags:        append(models.Tags{}, e.Tags...),
+	}
+}
+
+func sameSubject(a, b models.RuleSubject) bool {
+	if a.Description != b.Description || a.Category != b.Category || len(a.Tags) != len(b.Tags) {
+		return false
+	}
+	for i := range a.Tags {
+		if a.Tags[i] != b.Tags[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+)
+
+// mockRuleRepository - правила в памяти
+type mockRuleRepository struct {
+	rules  []models.Rule
+	lastID int64
+}
+
+func (m *mockRuleRepository) CreateRule(ctx context.Context, rule *models.Rule) error {
+	m.lastID++
+	rule.ID = m.lastID
+	m.rules = append(m.rules, *rule)
+	return nil
+}
+
+func (m *mockRuleRepository) GetRule(ctx context.Context, id int64) (*models.Rule, error) {
+	for _, r := range m.rules {
+		if r.ID == id {
+			return &r, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockRuleRepository) ListRules(ctx context.Context) ([]models.Rule, error) {
+	return m.rules, nil
+}
+
+func (m *mockRuleRepository) UpdateRule(ctx context.Context, rule *models.Rule) error {
+	for i, r := range m.rules {
+		if r.ID == rule.ID {
+			m.rules[i] = *rule
+			return nil
+		}
+	}
+	return fmt.Errorf("правило с id=%d не найдено: %w", rule.ID, sql.ErrNoRows)
+}
+
+func (m *mockRuleRepository) DeleteRule(ctx context.Context, id int64) error {
+	for i, r := range m.rules {
+		if r.ID == id {
+			m.rules = append(m.rules[:i], m.rules[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("правило с id=%d не найдено: %w", id, sql.ErrNoRows)
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func TestApplyRules_PriorityAndTags(t *testing.T) {
+	svc := NewRuleService(&mockRuleRepository{})
+
+	rules := []models.Rule{
+		{ID: 1, Priority: 0, Enabled: true,
+			Conditions: models.RuleConditions{DescriptionRegex: `(?i)такси`},
+			Actions:    models.RuleActions{SetCategory: "Транспорт", AddTags: []string{"поездки"}}},
+		{ID: 2, Priority: 10, Enabled: true,
+			Conditions: models.RuleConditions{Merchant: "yandex", AmountMin: floatPtr(1000)},
+			Actions:    models.RuleActions{SetCategory: "Командировки", AddTags: []string{"работа"}}},
+		{ID: 3, Priority: 100, Enabled: false,
+			Actions: models.RuleActions{SetCategory: "Выключено"}},
+	}
+
+	expense := &models.Expense{Description: "Такси в аэропорт", Amount: 1500, Merchant: "Yandex Go"}
+	applied := svc.applyRules(expense, rules)
+
+	// Правило 2 приоритетнее, поэтому категория его, а теги от обоих
+	if expense.Category != "Командировки" {
+		t.Errorf("Category: ожидали Командировки, получили %s", expense.Category)
+	}
+	if !expense.Tags.Has("поездки") || !expense.Tags.Has("работа") {
+		t.Errorf("Ожидали теги от обоих правил, получили %v", expense.Tags)
+	}
+	if len(applied) != 2 || applied[0] != 2 || applied[1] != 1 {
+		t.Errorf("Ожидали сработавшие правила [2 1], получили %v", applied)
+	}
+}
+
+func TestApplyRules_StopAndRewrite(t *testing.T) {
+	svc := NewRuleService(&mockRuleRepository{})
+
+	rules := []models.Rule{
+		{ID: 1, Priority: 5, Enabled: true, Stop: true,
+			Conditions: models.RuleConditions{DescriptionRegex: `^CARD \d+ (?P<shop>.+)$`},
+			Actions:    models.RuleActions{RewriteDescription: "Покупка: ${shop}"}},
+		{ID: 2, Priority: 1, Enabled: true,
+			Actions: models.RuleActions{SetCategory: "Разное"}},
+	}
+
+	expense := &models.Expense{Description: "CARD 1234 PYATEROCHKA", Amount: 200}
+	svc.applyRules(expense, rules)
+
+	if expense.Description != "Покупка: PYATEROCHKA" {
+		t.Errorf("Description: получили %q", expense.Description)
+	}
+	if expense.Category != "" {
+		t.Errorf("После stop правила ниже не должны применяться, категория %q", expense.Category)
+	}
+}
+
+func TestCreateRule_Validation(t *testing.T) {
+	svc := NewRuleService(&mockRuleRepository{})
+	ctx := context.Background()
+
+	_, err := svc.CreateRule(ctx, models.RuleRequest{
+		Name:       "Кривая регулярка",
+		Conditions: models.RuleConditions{DescriptionRegex: "(("},
+		Actions:    models.RuleActions{SetCategory: "Еда"},
+	})
+	if err == nil {
+		t.Error("Ожидали ошибку для неверной регулярки")
+	}
+
+	_, err = svc.CreateRule(ctx, models.RuleRequest{Name: "Без действий"})
+	if err == nil {
+		t.Error("Ожидали ошибку для правила без действий")
+	}
+
+	rule, err := svc.CreateRule(ctx, models.RuleRequest{
+		Name:    "Кофе",
+		Actions: models.RuleActions{SetCategory: "Еда"},
+	})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if !rule.Enabled {
+		t.Error("Правило по умолчанию должно быть включено")
+	}
+
+	// Лимиты - в символах, а не байтах: кириллица укладывается
+	if _, err := svc.CreateRule(ctx, models.RuleRequest{
+		Name:    "Длинная категория",
+		Actions: models.RuleActions{SetCategory: strings.Repeat("ж", 100), AddTags: []string{strings.Repeat("т", 50)}},
+	}); err != nil {
+		t.Errorf("100 символов кириллицы в категории должны проходить: %v", err)
+	}
+	if _, err := svc.CreateRule(ctx, models.RuleRequest{
+		Name:    "Слишком длинный тег",
+		Actions: models.RuleActions{AddTags: []string{strings.Repeat("т", 51)}},
+	}); err == nil {
+		t.Error("Ожидали ошибку для тега длиннее 50 символов")
+	}
+}
+
+func TestApplyRules_RewriteWithinDescriptionLimit(t *testing.T) {
+	svc := NewRuleService(&mockRuleRepository{})
+
+	rules := []models.Rule{
+		{ID: 1, Enabled: true,
+			Conditions: models.RuleConditions{DescriptionRegex: `^(.+)$`},
+			Actions:    models.RuleActions{RewriteDescription: "$1 $1"}},
+	}
+
+	expense := &models.Expense{Description: strings.Repeat("я", 400), Amount: 200}
+	svc.applyRules(expense, rules)
+
+	if n := utf8.RuneCountInString(expense.Description); n != maxDescriptionLength {
+		t.Errorf("Ожидали описание, обрезанное до %d символов, получили %d", maxDescriptionLength, n)
+	}
+	if !utf8.ValidString(expense.Description) {
+		t.Error("Обрезка не должна резать символ посередине")
+	}
+}
+
+func TestCreateExpense_WithRules(t *testing.T) {
+	repo := NewMockRepository()
+	rules := NewRuleService(&mockRuleRepository{})
+	svc := NewExpenseService(repo, WithRules(rules))
+	ctx := context.Background()
+
+	rules.CreateRule(ctx, models.RuleRequest{
+		Name:       "Кофейни",
+		Conditions: models.RuleConditions{DescriptionRegex: `(?i)кофе`},
+		Actions:    models.RuleActions{SetCategory: "Еда"},
+	})
+
+	expense, err := svc.CreateExpense(ctx, models.CreateExpenseRequest{
+		Description: "Кофе с собой", Amount: 250, Date: "2024-01-15",
+	})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if expense.Category != "Еда" {
+		t.Errorf("Category: ожидали Еда, получили %s", expense.Category)
+	}
+
+	// Без категории и без подходящего правила - ошибка
+	_, err = svc.CreateExpense(ctx, models.CreateExpenseRequest{
+		Description: "Непонятно что", Amount: 100, Date: "2024-01-15",
+	})
+	if err == nil {
+		t.Error("Ожидали ошибку, когда категорию подобрать не удалось")
+	}
+}
+
+func TestReapplyRules_DryRun(t *testing.T) {
+	repo := NewMockRepository()
+	rules := NewRuleService(&mockRuleRepository{})
+	svc := NewExpenseService(repo, WithRules(rules), WithTransactions(&mockTransactor{repo: repo}))
+	ctx := context.Background()
+
+	svc.CreateExpense(ctx, models.CreateExpenseRequest{Description: "Метро", Amount: 60, Category: "Разное", Date: "2024-01-15"})
+	svc.CreateExpense(ctx, models.CreateExpenseRequest{Description: "Хлеб", Amount: 50, Category: "Еда", Date: "2024-01-15"})
+
+	rules.CreateRule(ctx, models.RuleRequest{
+		Name:       "Метро",
+		Conditions: models.RuleConditions{DescriptionRegex: `Метро`},
+		Actions:    models.RuleActions{SetCategory: "Транспорт"},
+	})
+
+	preview, err := svc.ReapplyRules(ctx, models.ApplyRulesRequest{DryRun: true})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if preview.Checked != 2 || len(preview.Changes) != 1 {
+		t.Fatalf("Ожидали 1 изменение из 2 расходов, получили %d из %d", len(preview.Changes), preview.Checked)
+	}
+	if preview.Changes[0].After.Category != "Транспорт" {
+		t.Errorf("After.Category: получили %s", preview.Changes[0].After.Category)
+	}
+
+	// Предпросмотр ничего не меняет
+	if e, _ := repo.GetByID(ctx, preview.Changes[0].ExpenseID); e.Category != "Разное" {
+		t.Errorf("Dry run не должен менять расход, категория %s", e.Category)
+	}
+
+	if _, err := svc.ReapplyRules(ctx, models.ApplyRulesRequest{}); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if e, _ := repo.GetByID(ctx, preview.Changes[0].ExpenseID); e.Category != "Транспорт" {
+		t.Errorf("После применения ожидали Транспорт, получили %s", e.Category)
+	}
+}
+
+func TestReapplyRules_VersionConflictRollsBack(t *testing.T) {
+	repo := NewMockRepository()
+	rules := NewRuleService(&mockRuleRepository{})
+	svc := NewExpenseService(repo, WithRules(rules), WithTransactions(&mockTransactor{repo: repo}))
+	ctx := context.Background()
+
+	first, _ := svc.CreateExpense(ctx, models.CreateExpenseRequest{Description: "Метро", Amount: 60, Category: "Разное", Date: "2024-01-15"})
+	second, _ := svc.CreateExpense(ctx, models.CreateExpenseRequest{Description: "Метро", Amount: 60, Category: "Разное", Date: "2024-01-16"})
+
+	rules.CreateRule(ctx, models.RuleRequest{
+		Name:       "Метро",
+		Conditions: models.RuleConditions{DescriptionRegex: `Метро`},
+		Actions:    models.RuleActions{SetCategory: "Транспорт"},
+	})
+
+	classifier := NewCategoryClassifier()
+	classifier.Learn(*first)
+	classifier.Learn(*second)
+
+	// Расход изменили между чтением страницы и записью
+	conflicting := &conflictRepository{MockExpenseRepository: repo, id: first.ID}
+	svc = NewExpenseService(conflicting, WithRules(rules), WithClassifier(classifier),
+		WithTransactions(&mockTransactor{repo: repo}))
+
+	if _, err := svc.ReapplyRules(ctx, models.ApplyRulesRequest{}); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("Ожидали ErrVersionConflict, получили %v", err)
+	}
+
+	// Транзакция откатилась целиком - второй расход тоже не изменён
+	if e, _ := repo.GetByID(ctx, second.ID); e.Category != "Разное" {
+		t.Errorf("Ожидали откат, категория второго расхода %s", e.Category)
+	}
+
+	// Классификатор не должен выучить то, что откатилось
+	if classifier.docs["Разное"] != 2 || classifier.docs["Транспорт"] != 0 {
+		t.Errorf("Классификатор изменился после отката: %v", classifier.docs)
+	}
+}
+
+// conflictRepository - репозиторий, в котором расход id успевают изменить
+// до записи: версия из GetAll устаревает
+type conflictRepository struct {
+	*MockExpenseRepository
+	id int64
+}
+
+func (r *conflictRepository) GetAll(ctx context.Context, filter models.ExpenseFilter) ([]models.Expense, error) {
+	expenses, err := r.MockExpenseRepository.GetAll(ctx, filter)
+	for i := range expenses {
+		if expenses[i].ID == r.id {
+			expenses[i].Version--
+		}
+	}
+	return expenses, err
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer - спаны методов сервиса
+// Глобальный провайдер подключается в main; до этого (и в тестах) спаны ничего не стоят
+var tracer = otel.Tracer("github.com/dvoryadkinadv/expense-tracker/internal/service")
+
+// startSpan начинает спан метода сервиса: ExpenseService.GetStats
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan закрывает спан, отмечая ошибку, если она есть
+// Вызывается через defer с именованным err: defer func() { endSpan(span, err) }()
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// filterAttributes - заданные параметры фильтра как атрибуты спана
+// Пустые пропускаем, чтобы в трассировке было видно, чем запрос отличается
+func filterAttributes(f models.ExpenseFilter) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	str := func(key, value string) {
+		if value != "" {
+			attrs = append(attrs, attribute.String("expense.filter."+key, value))
+		}
+	}
+	list := func(key string, values []string) {
+		if len(values) > 0 {
+			attrs = append(attrs, attribute.String("expense.filter."+key, strings.Join(values, ",")))
+		}
+	}
+	amount := func(key string, value *float64) {
+		if value != nil {
+			attrs = append(attrs, attribute.Float64("expense.filter."+key, *value))
+		}
+	}
+
+	str("q", f.Query)
+	str("category", f.Category)
+	list("categories", f.Categories)
+	list("exclude_categories", f.ExcludeCategories)
+	str("date_from", f.DateFrom)
+	str("date_to", f.DateTo)
+	amount("amount_min", f.AmountMin)
+	amount("amount_max", f.AmountMax)
+	str("created_after", f.CreatedAfter)
+	str("description_contains", f.DescriptionContains)
+	str("expression", f.Expression)
+	str("sort", f.Sort)
+	if f.ViewID != 0 {
+		attrs = append(attrs, attribute.Int64("expense.filter.view_id", f.ViewID))
+	}
+	if f.Limit != 0 {
+		attrs = append(attrs, attribute.Int("expense.filter.limit", f.Limit))
+	}
+	if f.Offset != 0 {
+		attrs = append(attrs, attribute.Int("expense.filter.offset", f.Offset))
+	}
+	if f.Cursor != "" {
+		attrs = append(attrs, attribute.Bool("expense.filter.cursor", true))
+	}
+
+	return attrs
+}
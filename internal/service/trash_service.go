@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+)
+
+// TrashRepository - доступ к удалённым расходам
+type TrashRepository interface {
+	ListDeleted(ctx context.Context, limit, offset int) ([]models.Expense, error)
+	Restore(ctx context.Context, id int64) (*models.Expense, error)
+	Purge(ctx context.Context, id int64) (*models.Expense, error)
+	PurgeDeletedBefore(ctx context.Context, before time.Time, limit int) ([]models.Expense, error)
+}
+
+// ErrNotInTrash - в корзине нет такого расхода
+var ErrNotInTrash = newError(ErrNotFound, "расхода нет в корзине")
+
+// purgeBatchSize - сколько расходов очищаем по сроку за одну транзакцию
+const purgeBatchSize = 500
+
+// retentionActor - автор записей в журнале об очистке по сроку
+const retentionActor = "trash-retention"
+
+// DefaultTrashRetention - сколько удалённые расходы лежат в корзине
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// TrashService - корзина: просмотр, восстановление и очистка
+type TrashService struct {
+	repo       TrashRepository
+	retention  time.Duration
+	classifier *CategoryClassifier
+	audit      *AuditService
+	now        func() time.Time
+}
+
+// NewTrashService создаёт сервис корзины
+// retention <= 0 - автоматическая очистка выключена
+// classifier и audit могут быть nil; если classifier есть, восстановленные
+// расходы снова идут в обучение, если есть audit - восстановление и очистка попадают в журнал
+func NewTrashService(repo TrashRepository, retention time.Duration, classifier *CategoryClassifier, audit *AuditService) *TrashService {
+	return &TrashService{repo: repo, retention: retention, classifier: classifier, audit: audit, now: time.Now}
+}
+
+// List возвращает содержимое корзины, по умолчанию 50 штук (максимум 100)
+func (s *TrashService) List(ctx context.Context, limit, offset int) ([]models.Expense, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	return s.repo.ListDeleted(ctx, limit, offset)
+}
+
+// Restore возвращает расход из корзины
+func (s *TrashService) Restore(ctx context.Context, id int64) (*models.Expense, error) {
+	var expense *models.Expense
+
+	err := s.audit.Track(ctx, models.AuditRestore, func(ctx context.Context) (*models.Expense, *models.Expense, error) {
+		var err error
+		expense, err = s.repo.Restore(ctx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		if expense == nil {
+			return nil, nil, fmt.Errorf("%w: id=%d", ErrNotInTrash, id)
+		}
+		return nil, expense, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if s.classifier != nil {
+		s.classifier.Learn(*expense)
+	}
+
+	return expense, nil
+}
+
+// Purge удаляет расход из корзины насовсем
+func (s *TrashService) Purge(ctx context.Context, id int64) error {
+	return s.audit.Track(ctx, models.AuditPurge, func(ctx context.Context) (*models.Expense, *models.Expense, error) {
+		purged, err := s.repo.Purge(ctx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		if purged == nil {
+			return nil, nil, fmt.Errorf("%w: id=%d", ErrNotInTrash, id)
+		}
+		return purged, nil, nil
+	})
+}
+
+// PurgeExpired удаляет то, что лежит в корзине дольше срока хранения
+// Запускается воркером по расписанию. Как и при ручной очистке, каждый
+// расход попадает в журнал; удаляем порциями, каждая - своей транзакцией
+func (s *TrashService) PurgeExpired(ctx context.Context) (int64, error) {
+	if s.retention <= 0 {
+		return 0, nil
+	}
+
+	ctx = WithUser(ctx, retentionActor)
+	before := s.now().Add(-s.retention)
+
+	var purged int64
+	for {
+		var n int
+		err := s.audit.TrackRemoved(ctx, models.AuditPurge, func(ctx context.Context) ([]models.Expense, error) {
+			expenses, err := s.repo.PurgeDeletedBefore(ctx, before, purgeBatchSize)
+			n = len(expenses)
+			return expenses, err
+		})
+		if err != nil {
+			return purged, err
+		}
+
+		purged += int64(n)
+		if n < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
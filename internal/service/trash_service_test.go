@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+)
+
+// mockTrashRepository - корзина в памяти
+type mockTrashRepository struct {
+	deleted map[int64]models.Expense
+}
+
+func newMockTrashRepository(deleted ...models.Expense) *mockTrashRepository {
+	m := &mockTrashRepository{deleted: make(map[int64]models.Expense)}
+	for _, e := range deleted {
+		m.deleted[e.ID] = e
+	}
+	return m
+}
+
+func (m *mockTrashRepository) ListDeleted(ctx context.Context, limit, offset int) ([]models.Expense, error) {
+	result := []models.Expense{}
+	for _, e := range m.deleted {
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+func (m *mockTrashRepository) Restore(ctx context.Context, id int64) (*models.Expense, error) {
+	e, ok := m.deleted[id]
+	if !ok {
+		return nil, nil
+	}
+	delete(m.deleted, id)
+	e.DeletedAt = nil
+	return &e, nil
+}
+
+func (m *mockTrashRepository) Purge(ctx context.Context, id int64) (*models.Expense, error) {
+	e, ok := m.deleted[id]
+	if !ok {
+		return nil, nil
+	}
+	delete(m.deleted, id)
+	return &e, nil
+}
+
+func (m *mockTrashRepository) PurgeDeletedBefore(ctx context.Context, before time.Time, limit int) ([]models.Expense, error) {
+	var purged []models.Expense
+	for id, e := range m.deleted {
+		if len(purged) < limit && e.DeletedAt.Before(before) {
+			delete(m.deleted, id)
+			purged = append(purged, e)
+		}
+	}
+	return purged, nil
+}
+
+func deletedAt(t time.Time) *time.Time {
+	return &t
+}
+
+func TestTrash_RestoreAndPurge(t *testing.T) {
+	now := time.Now()
+	repo := newMockTrashRepository(
+		models.Expense{ID: 1, Description: "Такси", Category: "Транспорт", DeletedAt: deletedAt(now)},
+		models.Expense{ID: 2, Description: "Кофе", Category: "Еда", DeletedAt: deletedAt(now)},
+	)
+	classifier := NewCategoryClassifier()
+	svc := NewTrashService(repo, DefaultTrashRetention, classifier, nil)
+	ctx := context.Background()
+
+	restored, err := svc.Restore(ctx, 1)
+	if err != nil || restored.DeletedAt != nil {
+		t.Fatalf("Ожидали восстановленный расход, получили %+v, %v", restored, err)
+	}
+	if got := classifier.Suggest("такси", 0, 1); len(got) == 0 || got[0].Category != "Транспорт" {
+		t.Errorf("Восстановленный расход должен снова участвовать в подсказках, получили %v", got)
+	}
+
+	if _, err := svc.Restore(ctx, 1); !errors.Is(err, ErrNotInTrash) {
+		t.Errorf("Повторное восстановление: ожидали ErrNotInTrash, получили %v", err)
+	}
+
+	if err := svc.Purge(ctx, 2); err != nil {
+		t.Errorf("Неожиданная ошибка: %v", err)
+	}
+	if err := svc.Purge(ctx, 2); !errors.Is(err, ErrNotInTrash) {
+		t.Errorf("Повторная очистка: ожидали ErrNotInTrash, получили %v", err)
+	}
+}
+
+func TestTrash_PurgeExpired(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := newMockTrashRepository(
+		models.Expense{ID: 1, DeletedAt: deletedAt(now.AddDate(0, 0, -31))},
+		models.Expense{ID: 2, DeletedAt: deletedAt(now.AddDate(0, 0, -29))},
+	)
+
+	disabled := NewTrashService(repo, 0, nil, nil)
+	if n, _ := disabled.PurgeExpired(context.Background()); n != 0 || len(repo.deleted) != 2 {
+		t.Errorf("С нулевым сроком очистка должна быть выключена, удалено %d", n)
+	}
+
+	svc := NewTrashService(repo, DefaultTrashRetention, nil, nil)
+	svc.now = func() time.Time { return now }
+
+	n, err := svc.PurgeExpired(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("Ожидали удаление одного расхода, получили %d, %v", n, err)
+	}
+	if _, ok := repo.deleted[2]; !ok {
+		t.Error("Расход младше срока хранения должен остаться в корзине")
+	}
+}
+
+func TestTrash_PurgeExpiredWritesAudit(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := newMockTrashRepository()
+	for id := int64(1); id <= purgeBatchSize+1; id++ {
+		repo.deleted[id] = models.Expense{ID: id, Description: "Кофе", Amount: 200, Category: "Еда", DeletedAt: deletedAt(now.AddDate(0, 0, -31))}
+	}
+
+	auditRepo := &mockAuditRepository{}
+	svc := NewTrashService(repo, DefaultTrashRetention, nil, NewAuditService(auditRepo, nil))
+	svc.now = func() time.Time { return now }
+
+	n, err := svc.PurgeExpired(context.Background())
+	if err != nil || n != purgeBatchSize+1 {
+		t.Fatalf("Ожидали удаление %d расходов, получили %d, %v", purgeBatchSize+1, n, err)
+	}
+
+	if len(auditRepo.entries) != purgeBatchSize+1 {
+		t.Fatalf("Ожидали запись в журнале на каждый расход, получили %d", len(auditRepo.entries))
+	}
+	entry := auditRepo.entries[0]
+	if entry.Action != models.AuditPurge || entry.Actor != retentionActor || entry.Changes["category"].Before != "Еда" {
+		t.Errorf("Неожиданная запись: %+v", entry)
+	}
+}
@@ -0,0 +1,19 @@
+package service
+
+import "context"
+
+// userKey - ключ пользователя в контексте запроса
+type userKey struct{}
+
+// WithUser кладёт идентификатор пользователя в контекст
+// Аутентификации пока нет, идентификатор приходит в заголовке X-User-ID
+func WithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFromContext возвращает пользователя из контекста
+// Пустая строка - запрос без пользователя, такие запросы считаются одним общим владельцем
+func UserFromContext(ctx context.Context) string {
+	user, _ := ctx.Value(userKey{}).(string)
+	return user
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+)
+
+// ViewRepository описывает хранилище сохранённых представлений
+type ViewRepository interface {
+	CreateView(ctx context.Context, view *models.SavedView) error
+	GetView(ctx context.Context, id int64) (*models.SavedView, error)
+	ListViews(ctx context.Context, owner string) ([]models.SavedView, error)
+	UpdateView(ctx context.Context, view *models.SavedView) error
+	DeleteView(ctx context.Context, id int64) error
+}
+
+var (
+	// ErrViewNotFound - представления нет или оно чужое и не общее
+	ErrViewNotFound = newError(ErrNotFound, "представление не найдено")
+	// ErrViewForbidden - общее представление видно всем, а менять его может только владелец
+	ErrViewForbidden = newError(ErrForbidden, "менять представление может только владелец")
+)
+
+// ViewService управляет сохранёнными представлениями
+type ViewService struct {
+	repo ViewRepository
+	now  func() time.Time // для тестов относительных периодов
+}
+
+// NewViewService создаёт новый сервис представлений
+func NewViewService(repo ViewRepository) *ViewService {
+	return &ViewService{repo: repo, now: time.Now}
+}
+
+// CreateView сохраняет представление от имени текущего пользователя
+func (s *ViewService) CreateView(ctx context.Context, req models.SavedViewRequest) (*models.SavedView, error) {
+	view := viewFromRequest(req)
+	view.Owner = UserFromContext(ctx)
+
+	if err := s.validate(view); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateView(ctx, view); err != nil {
+		return nil, err
+	}
+
+	return view, nil
+}
+
+// GetView возвращает представление, если оно своё или общее
+func (s *ViewService) GetView(ctx context.Context, id int64) (*models.SavedView, error) {
+	view, err := s.repo.GetView(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Чужое личное представление для остальных просто не существует
+	if view == nil || (!view.Shared && view.Owner != UserFromContext(ctx)) {
+		return nil, fmt.Errorf("%w: id=%d", ErrViewNotFound, id)
+	}
+
+	return view, nil
+}
+
+// ListViews возвращает свои и общие представления
+func (s *ViewService) ListViews(ctx context.Context) ([]models.SavedView, error) {
+	return s.repo.ListViews(ctx, UserFromContext(ctx))
+}
+
+// UpdateView заменяет представление целиком
+func (s *ViewService) UpdateView(ctx context.Context, id int64, req models.SavedViewRequest) (*models.SavedView, error) {
+	if _, err := s.ownView(ctx, id); err != nil {
+		return nil, err
+	}
+
+	view := viewFromRequest(req)
+	view.ID = id
+	if err := s.validate(view); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateView(ctx, view); err != nil {
+		return nil, viewNotFound(err, id)
+	}
+
+	return view, nil
+}
+
+// DeleteView удаляет представление
+func (s *ViewService) DeleteView(ctx context.Context, id int64) error {
+	if _, err := s.ownView(ctx, id); err != nil {
+		return err
+	}
+
+	return viewNotFound(s.repo.DeleteView(ctx, id), id)
+}
+
+// viewNotFound - представление удалили между проверкой владельца и записью
+func viewNotFound(err error, id int64) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: id=%d", ErrViewNotFound, id)
+	}
+	return err
+}
+
+// Resolve подставляет условия представления в фильтр
+// Пагинация остаётся из запроса, сортировка - из запроса, если она там есть
+func (s *ViewService) Resolve(ctx context.Context, filter models.ExpenseFilter) (models.ExpenseFilter, error) {
+	view, err := s.GetView(ctx, filter.ViewID)
+	if err != nil {
+		return filter, err
+	}
+
+	resolved := view.Filter.Apply(filter)
+	if filter.Sort == "" {
+		resolved.Sort = view.Sort
+	}
+
+	if view.Filter.Period != "" {
+		resolved.DateFrom, resolved.DateTo, err = periodRange(view.Filter.Period, s.now())
+		if err != nil {
+			return filter, err
+		}
+	}
+
+	return resolved, nil
+}
+
+// ownView возвращает представление, если текущий пользователь - его владелец
+func (s *ViewService) ownView(ctx context.Context, id int64) (*models.SavedView, error) {
+	view, err := s.GetView(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if view.Owner != UserFromContext(ctx) {
+		return nil, ErrViewForbidden
+	}
+
+	return view, nil
+}
+
+func (s *ViewService) validate(view *models.SavedView) error {
+	view.Name = strings.TrimSpace(view.Name)
+	if view.Name == "" {
+		return Invalid("name", "название представления не может быть пустым")
+	}
+
+	if view.Sort != "" {
+		if _, err := normalizeSort(view.Sort); err != nil {
+			return err
+		}
+	}
+
+	for _, col := range view.Columns {
+		if !models.ViewColumnNames[col] {
+			return invalidAs(ErrInvalidFilter, "columns", fmt.Sprintf("неизвестная колонка %q", col))
+		}
+	}
+
+	if view.Filter.Period != "" {
+		if view.Filter.DateFrom != "" || view.Filter.DateTo != "" {
+			return invalidAs(ErrInvalidFilter, "filter.period", "period нельзя сочетать с date_from/date_to")
+		}
+		if _, _, err := periodRange(view.Filter.Period, s.now()); err != nil {
+			return err
+		}
+	}
+
+	return validateFilter(view.Filter.Apply(models.ExpenseFilter{}))
+}
+
+func viewFromRequest(req models.SavedViewRequest) *models.SavedView {
+	columns := models.ViewColumns(req.Columns)
+	if columns == nil {
+		columns = models.ViewColumns{}
+	}
+
+	return &models.SavedView{
+		Name:    req.Name,
+		Shared:  req.Shared,
+		Filter:  req.Filter,
+		Sort:    req.Sort,
+		Columns: columns,
+	}
+}
+
+// periodRange превращает относительный период в даты (включительно)
+func periodRange(period string, now time.Time) (from, to string, err error) {
+	const layout = "2006-01-02"
+
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	quarterStart := time.Date(y, m-(m-1)%3, 1, 0, 0, 0, 0, now.Location())
+
+	var start, end time.Time
+	switch period {
+	case "this_month":
+		start = time.Date(y, m, 1, 0, 0, 0, 0, now.Location())
+		end = start.AddDate(0, 1, -1)
+	case "last_month":
+		start = time.Date(y, m-1, 1, 0, 0, 0, 0, now.Location())
+		end = start.AddDate(0, 1, -1)
+	case "this_quarter":
+		start = quarterStart
+		end = start.AddDate(0, 3, -1)
+	case "last_quarter":
+		start = quarterStart.AddDate(0, -3, 0)
+		end = quarterStart.AddDate(0, 0, -1)
+	case "this_year":
+		start = time.Date(y, 1, 1, 0, 0, 0, 0, now.Location())
+		end = time.Date(y, 12, 31, 0, 0, 0, 0, now.Location())
+	case "last_30_days":
+		start = today.AddDate(0, 0, -29)
+		end = today
+	default:
+		return "", "", invalidAs(ErrInvalidFilter, "filter.period", fmt.Sprintf("неизвестный период %q, допустимо: this_month, last_month, this_quarter, last_quarter, this_year, last_30_days", period))
+	}
+
+	return start.Format(layout), end.Format(layout), nil
+}
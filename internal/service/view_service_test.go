@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/models"
+)
+
+// mockViewRepository - представления в памяти
+type mockViewRepository struct {
+	views  map[int64]models.SavedView
+	lastID int64
+}
+
+func newMockViewRepository() *mockViewRepository {
+	return &mockViewRepository{views: make(map[int64]models.SavedView)}
+}
+
+func (m *mockViewRepository) CreateView(ctx context.Context, view *models.SavedView) error {
+	m.lastID++
+	view.ID = m.lastID
+	m.views[view.ID] = *view
+	return nil
+}
+
+func (m *mockViewRepository) GetView(ctx context.Context, id int64) (*models.SavedView, error) {
+	if v, ok := m.views[id]; ok {
+		return &v, nil
+	}
+	return nil, nil
+}
+
+func (m *mockViewRepository) ListViews(ctx context.Context, owner string) ([]models.SavedView, error) {
+	result := []models.SavedView{}
+	for _, v := range m.views {
+		if v.Owner == owner || v.Shared {
+			result = append(result, v)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockViewRepository) UpdateView(ctx context.Context, view *models.SavedView) error {
+	existing, ok := m.views[view.ID]
+	if !ok {
+		return fmt.Errorf("представление с id=%d не найдено: %w", view.ID, sql.ErrNoRows)
+	}
+	view.Owner = existing.Owner
+	m.views[view.ID] = *view
+	return nil
+}
+
+func (m *mockViewRepository) DeleteView(ctx context.Context, id int64) error {
+	delete(m.views, id)
+	return nil
+}
+
+func TestViews_OwnershipAndSharing(t *testing.T) {
+	svc := NewViewService(newMockViewRepository())
+	alice := WithUser(context.Background(), "alice")
+	bob := WithUser(context.Background(), "bob")
+
+	private, err := svc.CreateView(alice, models.SavedViewRequest{Name: "Личное"})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	shared, _ := svc.CreateView(alice, models.SavedViewRequest{Name: "Общее", Shared: true})
+
+	if private.Owner != "alice" {
+		t.Errorf("Владелец: ожидали alice, получили %q", private.Owner)
+	}
+
+	// Чужое личное не видно вовсе
+	if _, err := svc.GetView(bob, private.ID); !errors.Is(err, ErrViewNotFound) {
+		t.Errorf("Ожидали ErrViewNotFound, получили %v", err)
+	}
+
+	views, _ := svc.ListViews(bob)
+	if len(views) != 1 || views[0].ID != shared.ID {
+		t.Errorf("Bob должен видеть только общее представление, получили %v", views)
+	}
+
+	// Общее видно, но менять его нельзя
+	if _, err := svc.GetView(bob, shared.ID); err != nil {
+		t.Errorf("Общее представление должно быть видно: %v", err)
+	}
+	if _, err := svc.UpdateView(bob, shared.ID, models.SavedViewRequest{Name: "Моё"}); !errors.Is(err, ErrViewForbidden) {
+		t.Errorf("Ожидали ErrViewForbidden, получили %v", err)
+	}
+	if err := svc.DeleteView(bob, shared.ID); !errors.Is(err, ErrViewForbidden) {
+		t.Errorf("Ожидали ErrViewForbidden, получили %v", err)
+	}
+
+	updated, err := svc.UpdateView(alice, shared.ID, models.SavedViewRequest{Name: "Переименовано", Shared: true})
+	if err != nil || updated.Name != "Переименовано" || updated.Owner != "alice" {
+		t.Errorf("Владелец должен мочь менять представление: %v, %+v", err, updated)
+	}
+}
+
+func TestViews_Validation(t *testing.T) {
+	svc := NewViewService(newMockViewRepository())
+	ctx := context.Background()
+
+	tests := []models.SavedViewRequest{
+		{Name: "   "},
+		{Name: "Сорт", Sort: "-secret"},
+		{Name: "Колонки", Columns: []string{"amount", "password"}},
+		{Name: "Период", Filter: models.ViewFilter{Period: "next_century"}},
+		{Name: "Период и даты", Filter: models.ViewFilter{Period: "this_month", DateFrom: "2026-01-01"}},
+		{Name: "Выражение", Filter: models.ViewFilter{Expression: "amount>много"}},
+	}
+
+	for _, req := range tests {
+		if _, err := svc.CreateView(ctx, req); err == nil {
+			t.Errorf("%q: ожидали ошибку валидации", req.Name)
+		}
+	}
+}
+
+func TestViews_Resolve(t *testing.T) {
+	svc := NewViewService(newMockViewRepository())
+	svc.now = func() time.Time { return time.Date(2026, 5, 17, 12, 0, 0, 0, time.UTC) }
+	ctx := context.Background()
+
+	view, err := svc.CreateView(ctx, models.SavedViewRequest{
+		Name: "Бизнес-ланчи за квартал",
+		Filter: models.ViewFilter{
+			Category:   "Еда",
+			Expression: "tag:work",
+			Period:     "this_quarter",
+		},
+		Sort: "-amount",
+	})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	// Условия из запроса заменяются, пагинация остаётся
+	filter, err := svc.Resolve(ctx, models.ExpenseFilter{ViewID: view.ID, Category: "Транспорт", Limit: 10})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	if filter.Category != "Еда" || filter.Expression != "tag:work" || filter.Limit != 10 {
+		t.Errorf("Неожиданный фильтр: %+v", filter)
+	}
+	if filter.DateFrom != "2026-04-01" || filter.DateTo != "2026-06-30" {
+		t.Errorf("Квартал: получили %s - %s", filter.DateFrom, filter.DateTo)
+	}
+	if filter.Sort != "-amount" {
+		t.Errorf("Сортировка из представления: получили %q", filter.Sort)
+	}
+
+	// Явная сортировка из запроса важнее
+	filter, _ = svc.Resolve(ctx, models.ExpenseFilter{ViewID: view.ID, Sort: "date"})
+	if filter.Sort != "date" {
+		t.Errorf("Сортировка из запроса: получили %q", filter.Sort)
+	}
+}
+
+func TestPeriodRange(t *testing.T) {
+	now := time.Date(2026, 1, 20, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		period   string
+		from, to string
+	}{
+		{"this_month", "2026-01-01", "2026-01-31"},
+		{"last_month", "2025-12-01", "2025-12-31"},
+		{"this_quarter", "2026-01-01", "2026-03-31"},
+		{"last_quarter", "2025-10-01", "2025-12-31"},
+		{"this_year", "2026-01-01", "2026-12-31"},
+		{"last_30_days", "2025-12-22", "2026-01-20"},
+	}
+
+	for _, tt := range tests {
+		from, to, err := periodRange(tt.period, now)
+		if err != nil || from != tt.from || to != tt.to {
+			t.Errorf("%s: получили %s - %s (%v), ожидали %s - %s", tt.period, from, to, err, tt.from, tt.to)
+		}
+	}
+}
+
+func TestGetExpenses_WithView(t *testing.T) {
+	repo := NewMockRepository()
+	views := NewViewService(newMockViewRepository())
+	svc := NewExpenseService(repo, WithViews(views))
+	ctx := context.Background()
+
+	svc.CreateExpense(ctx, models.CreateExpenseRequest{Description: "Обед", Amount: 500, Category: "Еда", Date: "2024-01-15"})
+	svc.CreateExpense(ctx, models.CreateExpenseRequest{Description: "Такси", Amount: 300, Category: "Транспорт", Date: "2024-01-16"})
+
+	view, _ := views.CreateView(ctx, models.SavedViewRequest{Name: "Еда", Filter: models.ViewFilter{Category: "Еда"}})
+
+	page, err := svc.GetExpenses(ctx, models.ExpenseFilter{ViewID: view.ID})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Category != "Еда" {
+		t.Errorf("Ожидали только Еду, получили %v", page.Items)
+	}
+
+	if _, err := svc.GetStats(ctx, models.ExpenseFilter{ViewID: 999}); !errors.Is(err, ErrViewNotFound) {
+		t.Errorf("Ожидали ErrViewNotFound, получили %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+// Package tracing подключает OpenTelemetry: провайдер спанов, экспортёр и W3C traceparent
+//
+// Спаны создают сами слои приложения через otel.Tracer: middleware на каждый
+// HTTP-запрос, сервис на каждый метод, репозиторий на каждый SQL-запрос.
+// Setup только решает, куда эти спаны уходят
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Экспортёры
+const (
+	// ExporterNone - спаны не записываются, но traceparent всё равно
+	// передаётся дальше, а trace_id попадает в логи
+	ExporterNone = "none"
+	// ExporterStdout - спаны в stdout, для локальной отладки
+	ExporterStdout = "stdout"
+	// ExporterFile - спаны в файл, по JSON-объекту на спан
+	ExporterFile = "file"
+	// ExporterOTLP - спаны в коллектор по OTLP/HTTP (Jaeger, Tempo, OpenTelemetry Collector)
+	ExporterOTLP = "otlp"
+)
+
+// Config - куда и сколько спанов отправлять
+type Config struct {
+	Exporter string
+	// Endpoint - адрес коллектора для otlp: localhost:4318 или https://collector:4318/v1/traces
+	// Пусто - из OTEL_EXPORTER_OTLP_ENDPOINT или localhost:4318
+	Endpoint string
+	// Insecure - http вместо https, если в Endpoint нет схемы
+	Insecure bool
+	// File - файл для экспортёра file
+	File        string
+	ServiceName string
+	// SampleRatio - доля записываемых трассировок от 0 до 1
+	// Если вызывающий уже решил записывать (флаг в traceparent), решение сохраняется
+	SampleRatio float64
+}
+
+// Setup настраивает глобальный провайдер спанов и W3C-пропагатор
+// Возвращённую shutdown нужно вызвать при остановке: она дописывает
+// накопленные спаны и закрывает экспортёр
+func Setup(ctx context.Context, cfg Config) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if cfg.Exporter == ExporterNone || cfg.Exporter == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, closer, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(attribute.String("service.name", cfg.ServiceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка описания сервиса для трассировки: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			err = errors.Join(err, closer.Close())
+		}
+		return err
+	}, nil
+}
+
+// newExporter создаёт экспортёр; closer - что закрыть после него (файл)
+func newExporter(ctx context.Context, cfg Config) (sdktrace.SpanExporter, io.Closer, error) {
+	switch cfg.Exporter {
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		return exporter, nil, err
+
+	case ExporterFile:
+		file, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("не удалось открыть файл для спанов: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+		return exporter, file, nil
+
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		switch {
+		case strings.Contains(cfg.Endpoint, "://"):
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		case cfg.Endpoint != "":
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ошибка настройки OTLP-экспортёра: %w", err)
+		}
+		return exporter, nil, nil
+
+	default:
+		return nil, nil, fmt.Errorf("неизвестный экспортёр спанов %q", cfg.Exporter)
+	}
+}
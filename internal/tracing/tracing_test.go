@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+)
+
+func TestSetup_FileExporter(t *testing.T) {
+	prev := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	shutdown, err := Setup(context.Background(), Config{
+		Exporter:    ExporterFile,
+		File:        path,
+		ServiceName: "expense-tracker-test",
+		SampleRatio: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, span := otel.Tracer("test").Start(context.Background(), "ExpenseService.GetStats")
+	span.End()
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"ExpenseService.GetStats", "expense-tracker-test"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("В файле нет %q: %s", want, data)
+		}
+	}
+}
+
+func TestSetup_SampleRatioZero(t *testing.T) {
+	prev := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	shutdown, err := Setup(context.Background(), Config{Exporter: ExporterFile, File: path, ServiceName: "x", SampleRatio: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, span := otel.Tracer("test").Start(context.Background(), "dropped")
+	if span.SpanContext().IsSampled() {
+		t.Error("При sample_ratio 0 корневые спаны не записываются")
+	}
+	span.End()
+	shutdown(context.Background())
+
+	if data, _ := os.ReadFile(path); len(data) != 0 {
+		t.Errorf("Файл должен остаться пустым, получили %s", data)
+	}
+}
+
+func TestSetup_UnknownExporter(t *testing.T) {
+	if _, err := Setup(context.Background(), Config{Exporter: "zipkin"}); err == nil {
+		t.Error("Ожидали ошибку для неизвестного экспортёра")
+	}
+}
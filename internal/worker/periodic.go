@@ -0,0 +1,106 @@
+// Package worker - фоновые задачи, которые сервер запускает рядом с HTTP
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/dvoryadkinadv/expense-tracker/internal/logging"
+)
+
+// Periodic выполняет задачу сразу после старта и дальше с заданным интервалом
+// Прогоны не накладываются: следующий начинается после окончания предыдущего
+type Periodic struct {
+	name     string
+	interval time.Duration
+	task     func(ctx context.Context) error
+
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu      sync.Mutex
+	lastRun time.Time
+	lastErr error
+}
+
+// NewPeriodic создаёт задачу; запускается она отдельно через Start
+func NewPeriodic(name string, interval time.Duration, task func(ctx context.Context) error) *Periodic {
+	return &Periodic{name: name, interval: interval, task: task}
+}
+
+// Name возвращает имя задачи
+func (p *Periodic) Name() string {
+	return p.name
+}
+
+// Interval возвращает интервал между прогонами
+func (p *Periodic) Interval() time.Duration {
+	return p.interval
+}
+
+// Running - запущена и ещё не остановлена
+func (p *Periodic) Running() bool {
+	if p.done == nil {
+		return false
+	}
+	select {
+	case <-p.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Start запускает задачу в отдельной горутине
+// Остановить можно через Stop или отменой ctx
+func (p *Periodic) Start(ctx context.Context) {
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.done = make(chan struct{})
+
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			p.run(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop останавливает задачу и ждёт окончания текущего прогона
+func (p *Periodic) Stop() {
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+	<-p.done
+}
+
+// LastRun возвращает время и ошибку последнего прогона
+func (p *Periodic) LastRun() (time.Time, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lastRun, p.lastErr
+}
+
+func (p *Periodic) run(ctx context.Context) {
+	err := p.task(ctx)
+	if err != nil && ctx.Err() == nil {
+		slog.ErrorContext(ctx, "Фоновая задача завершилась с ошибкой", slog.String("worker", p.name), logging.Err(err))
+	}
+
+	p.mu.Lock()
+	p.lastRun = time.Now()
+	p.lastErr = err
+	p.mu.Unlock()
+}
@@ -0,0 +1,88 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPeriodic_RunsImmediatelyAndRepeats(t *testing.T) {
+	var runs atomic.Int32
+	p := NewPeriodic("test", 10*time.Millisecond, func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	})
+
+	p.Start(context.Background())
+	time.Sleep(35 * time.Millisecond)
+	p.Stop()
+
+	if n := runs.Load(); n < 2 {
+		t.Errorf("Ожидали несколько прогонов, получили %d", n)
+	}
+
+	// После Stop новых прогонов нет
+	n := runs.Load()
+	time.Sleep(25 * time.Millisecond)
+	if runs.Load() != n {
+		t.Error("Задача продолжила работать после Stop")
+	}
+}
+
+func TestPeriodic_StopWaitsForRun(t *testing.T) {
+	finished := make(chan struct{})
+	p := NewPeriodic("slow", time.Hour, func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		close(finished)
+		return ctx.Err()
+	})
+
+	p.Start(context.Background())
+	time.Sleep(5 * time.Millisecond)
+	p.Stop()
+
+	select {
+	case <-finished:
+	default:
+		t.Error("Stop вернулся раньше, чем закончился прогон")
+	}
+}
+
+func TestPeriodic_LastRun(t *testing.T) {
+	boom := errors.New("boom")
+	p := NewPeriodic("failing", time.Hour, func(ctx context.Context) error {
+		return boom
+	})
+
+	if at, _ := p.LastRun(); !at.IsZero() {
+		t.Error("До запуска LastRun должен быть пустым")
+	}
+
+	p.Start(context.Background())
+	time.Sleep(10 * time.Millisecond)
+	p.Stop()
+
+	if at, err := p.LastRun(); at.IsZero() || !errors.Is(err, boom) {
+		t.Errorf("Ожидали прогон с ошибкой boom, получили %v, %v", at, err)
+	}
+}
+
+func TestPeriodic_Running(t *testing.T) {
+	p := NewPeriodic("test", time.Hour, func(ctx context.Context) error { return nil })
+	if p.Running() {
+		t.Error("До Start задача не запущена")
+	}
+
+	p.Start(context.Background())
+	if !p.Running() {
+		t.Error("После Start задача запущена")
+	}
+
+	p.Stop()
+	if p.Running() {
+		t.Error("После Stop задача не запущена")
+	}
+}
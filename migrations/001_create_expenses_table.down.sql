@@ -0,0 +1,3 @@
+-- Откат: таблица расходов
+
+DROP TABLE IF EXISTS expenses;
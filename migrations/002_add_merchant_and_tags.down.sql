@@ -0,0 +1,5 @@
+-- Откат: продавец и теги
+
+DROP INDEX IF EXISTS idx_expenses_tags;
+ALTER TABLE expenses DROP COLUMN IF EXISTS tags;
+ALTER TABLE expenses DROP COLUMN IF EXISTS merchant;
@@ -0,0 +1,8 @@
+-- Миграция: продавец и теги у расходов
+-- Нужны для правил автокатегоризации (условие по продавцу, действие "добавить теги")
+
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS merchant VARCHAR(200) NOT NULL DEFAULT '';
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+-- GIN-индекс, чтобы искать по тегам через @> и &&
+CREATE INDEX IF NOT EXISTS idx_expenses_tags ON expenses USING GIN (tags);
@@ -0,0 +1,3 @@
+-- Откат: правила автокатегоризации
+
+DROP TABLE IF EXISTS rules;
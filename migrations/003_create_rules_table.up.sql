@@ -0,0 +1,17 @@
+-- Миграция: правила автокатегоризации
+-- Условия и действия храним в JSONB - набор полей будет расти,
+-- а менять схему ради каждого нового условия не хочется
+
+CREATE TABLE IF NOT EXISTS rules (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(200) NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 0,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    stop BOOLEAN NOT NULL DEFAULT FALSE,
+    conditions JSONB NOT NULL DEFAULT '{}',
+    actions JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Правила всегда читаем в порядке приоритета
+CREATE INDEX IF NOT EXISTS idx_rules_priority ON rules(priority DESC, id);
@@ -0,0 +1,4 @@
+-- Откат: полнотекстовый поиск
+
+DROP INDEX IF EXISTS idx_expenses_search;
+ALTER TABLE expenses DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,13 @@
+-- Миграция: полнотекстовый поиск по описанию
+-- Описание индексируем сразу в двух конфигурациях: русской и английской,
+-- продавца - в simple (названия магазинов стеммить бессмысленно).
+-- Колонка генерируется самой БД, приложению её обновлять не нужно
+
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('russian', coalesce(description, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(description, '')), 'B') ||
+        setweight(to_tsvector('simple', coalesce(merchant, '')), 'C')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_expenses_search ON expenses USING GIN (search_vector);
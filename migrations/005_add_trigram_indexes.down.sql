@@ -0,0 +1,6 @@
+-- Откат: триграммные индексы
+-- Расширение pg_trgm не удаляем: им могут пользоваться и другие
+
+DROP INDEX IF EXISTS idx_expenses_category_trgm;
+DROP INDEX IF EXISTS idx_expenses_merchant_trgm;
+DROP INDEX IF EXISTS idx_expenses_description_trgm;
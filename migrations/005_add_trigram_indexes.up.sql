@@ -0,0 +1,14 @@
+-- Миграция: триграммные индексы для автодополнения
+-- pg_trgm даёт нечёткий поиск (опечатки) и ускоряет LIKE 'префикс%'.
+-- Индексы строим по lower(...), поэтому и в запросах сравниваем lower(...)
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_expenses_description_trgm
+    ON expenses USING GIN (lower(description) gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_expenses_merchant_trgm
+    ON expenses USING GIN (lower(merchant) gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_expenses_category_trgm
+    ON expenses USING GIN (lower(category) gin_trgm_ops);
@@ -0,0 +1,3 @@
+-- Откат: индекс под keyset-пагинацию
+
+DROP INDEX IF EXISTS idx_expenses_date_id;
@@ -0,0 +1,5 @@
+-- Миграция: составной индекс под keyset-пагинацию
+-- Условие (date, id) < (...) и ORDER BY date DESC, id DESC
+-- читаются прямо из индекса, без сортировки всей таблицы
+
+CREATE INDEX IF NOT EXISTS idx_expenses_date_id ON expenses(date DESC, id DESC);
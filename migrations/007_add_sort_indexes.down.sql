@@ -0,0 +1,4 @@
+-- Откат: индексы под сортировку
+
+DROP INDEX IF EXISTS idx_expenses_created_at_id;
+DROP INDEX IF EXISTS idx_expenses_amount_id;
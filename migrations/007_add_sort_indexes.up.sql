@@ -0,0 +1,5 @@
+-- Миграция: индексы под сортировку списка по сумме и времени создания
+-- Вместе с id - для keyset-пагинации по этим полям
+
+CREATE INDEX IF NOT EXISTS idx_expenses_amount_id ON expenses(amount, id);
+CREATE INDEX IF NOT EXISTS idx_expenses_created_at_id ON expenses(created_at, id);
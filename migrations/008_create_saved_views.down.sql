@@ -0,0 +1,3 @@
+-- Откат: сохранённые представления
+
+DROP TABLE IF EXISTS saved_views;
@@ -0,0 +1,18 @@
+-- Миграция: сохранённые представления (фильтр + сортировка + колонки)
+-- owner - значение X-User-ID; пустая строка - запросы без заголовка
+
+CREATE TABLE IF NOT EXISTS saved_views (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    owner VARCHAR(100) NOT NULL DEFAULT '',
+    shared BOOLEAN NOT NULL DEFAULT FALSE,
+    filter JSONB NOT NULL DEFAULT '{}',
+    sort VARCHAR(50) NOT NULL DEFAULT '',
+    columns JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Список: свои представления плюс общие
+CREATE INDEX IF NOT EXISTS idx_saved_views_owner ON saved_views(owner);
+CREATE INDEX IF NOT EXISTS idx_saved_views_shared ON saved_views(shared) WHERE shared;
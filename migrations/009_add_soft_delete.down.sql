@@ -0,0 +1,6 @@
+-- Откат: мягкое удаление
+-- Расходы из корзины при этом удаляются насовсем, иначе они вернутся в списки
+
+DELETE FROM expenses WHERE deleted_at IS NOT NULL;
+DROP INDEX IF EXISTS idx_expenses_deleted_at;
+ALTER TABLE expenses DROP COLUMN IF EXISTS deleted_at;
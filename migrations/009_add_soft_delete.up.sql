@@ -0,0 +1,7 @@
+-- Миграция: мягкое удаление
+-- Удалённые расходы лежат в корзине с deleted_at, пока их не очистят
+
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- Корзина и очистка по сроку читают только удалённые строки
+CREATE INDEX IF NOT EXISTS idx_expenses_deleted_at ON expenses(deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,5 @@
+-- Откат: журнал изменений
+-- DROP TABLE триггеры не останавливают: они запрещают только UPDATE, DELETE и TRUNCATE
+
+DROP TABLE IF EXISTS audit_log;
+DROP FUNCTION IF EXISTS audit_log_immutable();
@@ -0,0 +1,4 @@
+-- Откат: версия расхода
+
+ALTER TABLE expenses DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE expenses DROP COLUMN IF EXISTS version;
@@ -0,0 +1,13 @@
+-- Миграция: версия расхода для оптимистичных блокировок
+-- version растёт на каждое изменение; клиент присылает её в If-Match,
+-- и если кто-то успел поменять расход раньше, изменение отклоняется
+
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+-- Колонку добавляем без DEFAULT: иначе старые строки сразу получили бы
+-- время миграции, и перенос из created_at ничего бы не нашёл
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE expenses SET updated_at = COALESCE(created_at, CURRENT_TIMESTAMP) WHERE updated_at IS NULL;
+
+ALTER TABLE expenses ALTER COLUMN updated_at SET DEFAULT CURRENT_TIMESTAMP;
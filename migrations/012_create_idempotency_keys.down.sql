@@ -0,0 +1,3 @@
+-- Откат: ключи идемпотентности
+
+DROP TABLE IF EXISTS idempotency_keys;
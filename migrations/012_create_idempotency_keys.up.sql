@@ -0,0 +1,18 @@
+-- Миграция: ключи идемпотентности (заголовок Idempotency-Key)
+-- Первый ответ на запрос с ключом сохраняется, повтор с тем же ключом получает его же.
+-- status_code = 0 - запрос ещё выполняется
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    owner VARCHAR(100) NOT NULL DEFAULT '',
+    key VARCHAR(255) NOT NULL,
+    fingerprint VARCHAR(64) NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    content_type VARCHAR(100) NOT NULL DEFAULT '',
+    body BYTEA,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (owner, key)
+);
+
+-- Для очистки просроченных ключей
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
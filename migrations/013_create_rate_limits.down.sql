@@ -0,0 +1,3 @@
+-- Откат: корзины ограничения частоты запросов
+
+DROP TABLE IF EXISTS rate_limits;
@@ -0,0 +1,15 @@
+-- Миграция: общие корзины ограничения частоты запросов (RATE_LIMIT_STORE=postgres)
+-- tokens - сколько запросов осталось на момент updated_at; пополняется со скоростью rate в секунду до burst.
+-- allowed - решение по последнему запросу
+
+CREATE TABLE IF NOT EXISTS rate_limits (
+    key VARCHAR(255) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    rate DOUBLE PRECISION NOT NULL,
+    burst INTEGER NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Для очистки давно не тронутых корзин
+CREATE INDEX IF NOT EXISTS idx_rate_limits_updated_at ON rate_limits(updated_at);
@@ -0,0 +1,13 @@
+// Package migrations - SQL-миграции схемы, встроенные в бинарник
+//
+// Файлы называются NNN_описание.up.sql и NNN_описание.down.sql:
+// NNN - версия, up применяет миграцию, down откатывает. Применённые
+// миграции менять нельзя - раннер сверяет контрольные суммы
+package migrations
+
+import "embed"
+
+// FS - все файлы миграций
+//
+//go:embed *.sql
+var FS embed.FS